@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"strings"
+	"unicode"
+)
+
+var (
+	errNotInterface    = errors.New("not an interface type")
+	errNotImplemented  = errors.New("type does not implement interface")
+	errNoImplementions = errors.New("no implementations of interface")
+)
+
+// factoryInfo describes an interface for which a wrapper.Factory is generated.
+type factoryInfo struct {
+	name  string
+	iface types.Type
+	impls []*implInfo
+}
+
+// implInfo describes a type that implements a factory interface.
+type implInfo struct {
+	name     string
+	typeName string // registered type name
+	fullName string // full type name as generated by go-type/reg
+	value    bool   // value type implements interface
+}
+
+// factoryInfo returns information about the interfaces for which factories are generated.
+func (g *generator) factoryInfo() ([]*factoryInfo, error) {
+	var factories []*factoryInfo
+	for _, name := range g.factories {
+		iface, err := g.lookupInterface(name)
+		if err != nil {
+			return nil, err
+		}
+		f := &factoryInfo{name: name[strings.LastIndex(name, ".")+1:], iface: iface}
+		if f.impls, err = g.implementations(iface); err != nil {
+			return nil, fmt.Errorf("interface %s: %w", name, err)
+		}
+		factories = append(factories, f)
+	}
+	return factories, nil
+}
+
+// lookupInterface finds an interface type by name in the package or,
+// if qualified with a package name, in one of its imports.
+func (g *generator) lookupInterface(name string) (types.Type, error) {
+	scope := g.pkg.Scope()
+	if pkgName, typeName, found := strings.Cut(name, "."); found {
+		scope = nil
+		for _, imported := range g.pkg.Imports() {
+			if imported.Name() == pkgName {
+				scope = imported.Scope()
+				break
+			}
+		}
+		if scope == nil {
+			return nil, fmt.Errorf("%w: %s (package not imported)", errNotInterface, name)
+		}
+		name = typeName
+	}
+	obj, ok := scope.Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errNotInterface, name)
+	} else if _, ok = obj.Type().Underlying().(*types.Interface); !ok {
+		return nil, fmt.Errorf("%w: %s", errNotInterface, name)
+	}
+	return obj.Type(), nil
+}
+
+// implementations returns the types in the package that implement the interface.
+// If implementation names were specified only those types are returned.
+func (g *generator) implementations(iface types.Type) ([]*implInfo, error) {
+	names := g.impls
+	if len(names) == 0 {
+		names = g.pkg.Scope().Names()
+	}
+
+	underlying := iface.Underlying().(*types.Interface)
+	var impls []*implInfo
+	for _, name := range names {
+		obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+		var named *types.Named
+		if ok && !obj.IsAlias() {
+			named, _ = obj.Type().(*types.Named)
+		}
+		if named == nil || named.TypeParams().Len() > 0 || types.IsInterface(named) {
+			if len(g.impls) > 0 {
+				return nil, fmt.Errorf("%w: %s", errNotImplemented, name)
+			}
+			continue
+		}
+
+		impl := &implInfo{
+			name:     name,
+			typeName: g.pkg.Path() + "/" + name,
+			fullName: g.pkg.Path() + "/" + name,
+		}
+		if g.alias != "" {
+			impl.typeName = "[" + g.alias + "]" + name
+		}
+		if types.Implements(named, underlying) {
+			impl.value = true
+		} else if !types.Implements(types.NewPointer(named), underlying) {
+			if len(g.impls) > 0 {
+				return nil, fmt.Errorf("%w: %s", errNotImplemented, name)
+			}
+			continue
+		}
+		impls = append(impls, impl)
+	}
+
+	if len(impls) < 1 {
+		return nil, errNoImplementions
+	}
+	return impls, nil
+}
+
+// factoryName returns the name of the generated factory type for an interface.
+func factoryName(f *factoryInfo) string {
+	runes := []rune(f.name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes) + "Factory"
+}
+
+func (g *generator) writeFactory(f *factoryInfo) {
+	name, iface := factoryName(f), g.typeString(f.iface)
+	fmtName, wrapperName := g.use("fmt", "fmt"), g.use(wrapperPath, "wrapper")
+
+	g.printf("\n// %s creates %s items by type name without reflection.\n", name, iface)
+	g.printf("type %s struct{}\n", name)
+
+	g.printf("\n// NameFor returns the type name for the specified item.\n")
+	g.printf("func (%s) NameFor(item %s) (string, error) {\n", name, iface)
+	g.printf("switch item.(type) {\n")
+	for _, impl := range f.impls {
+		if impl.value {
+			g.printf("case *%s, %s:\n", impl.name, impl.name)
+		} else {
+			g.printf("case *%s:\n", impl.name)
+		}
+		g.printf("return %q, nil\n", impl.typeName)
+	}
+	g.printf("}\n")
+	g.printf("return \"\", %s.Errorf(\"%%w: %%T\", %s.ErrUnknownItemType, item)\n", fmtName, wrapperName)
+	g.printf("}\n")
+
+	g.printf("\n// Make creates a new, empty item of the type with the specified name.\n")
+	g.printf("func (%s) Make(name string) (%s, error) {\n", name, iface)
+	g.printf("switch name {\n")
+	for _, impl := range f.impls {
+		if impl.typeName == impl.fullName {
+			g.printf("case %q:\n", impl.typeName)
+		} else {
+			g.printf("case %q, %q:\n", impl.typeName, impl.fullName)
+		}
+		g.printf("return &%s{}, nil\n", impl.name)
+	}
+	g.printf("}\n")
+	g.printf("return nil, %s.Errorf(\"%%w: %%s\", %s.ErrUnknownTypeName, name)\n", fmtName, wrapperName)
+	g.printf("}\n")
+
+	if !g.noInit {
+		g.printf("\nfunc init() {\n")
+		g.printf("%s.SetFactory[%s](%s{})\n", wrapperName, iface, name)
+		g.printf("}\n")
+	}
+}
@@ -0,0 +1,621 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	serialPath  = "github.com/madkins23/go-serial"
+	wrapperPath = serialPath + "/wrapper"
+	jsonPath    = "encoding/json"
+	yamlPath    = "gopkg.in/yaml.v3"
+)
+
+// codec describes the generated code for a serialization format.
+type codec struct {
+	name       string // format name for the -format flag
+	suffix     string // suffix for shadow struct and method names
+	wrapPath   string // package path for the go-serial wrapper package
+	wrapName   string // import name for the go-serial wrapper package
+	codecPath  string // package path for the serialization package
+	codecName  string // import name for the serialization package
+	writeFuncs func(g *generator, s *structInfo, c *codec)
+}
+
+var codecs = []*codec{
+	{
+		name: "json", suffix: "JSON",
+		wrapPath: serialPath + "/json", wrapName: "sjson",
+		codecPath: jsonPath, codecName: "json",
+		writeFuncs: (*generator).writeJSON,
+	},
+	{
+		name: "yaml", suffix: "YAML",
+		wrapPath: serialPath + "/yaml", wrapName: "syaml",
+		codecPath: yamlPath, codecName: "yaml",
+		writeFuncs: (*generator).writeYAML,
+	},
+}
+
+var (
+	errNoStructs      = errors.New("no structs with interface fields")
+	errNotStruct      = errors.New("not a struct type")
+	errUnknownFormat  = errors.New("unknown format")
+	errMethodConflict = errors.New("method already defined")
+	errErrorField     = errors.New("error field can't be serialized")
+)
+
+//////////////////////////////////////////////////////////////////////////
+
+// generator generates code for a single package directory.
+type generator struct {
+	dir       string
+	output    string
+	types     []string
+	formats   []string
+	factories []string
+	impls     []string
+	alias     string
+	noInit    bool
+
+	fset    *token.FileSet
+	pkg     *types.Package
+	imports map[string]string // path -> name
+	names   map[string]string // name -> path
+	buf     bytes.Buffer
+}
+
+// fieldKind specifies how a field is copied to and from the shadow structure.
+type fieldKind int
+
+const (
+	plainField fieldKind = iota
+	interfaceField
+	sliceField
+	arrayField
+	mapField
+)
+
+// fieldInfo describes a struct field.
+type fieldInfo struct {
+	name     string
+	embedded bool
+	kind     fieldKind
+	tag      string
+	typ      types.Type
+	item     types.Type // interface type for wrapped fields
+	key      types.Type // key type for map fields
+	length   int64      // length for array fields
+}
+
+// structInfo describes a struct for which code is generated.
+type structInfo struct {
+	name   string
+	named  *types.Named
+	fields []*fieldInfo
+}
+
+// generate returns the formatted source code for the package.
+func (g *generator) generate() ([]byte, error) {
+	var selected []*codec
+	for _, name := range g.formats {
+		var found bool
+		for _, c := range codecs {
+			if c.name == name {
+				selected = append(selected, c)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", errUnknownFormat, name)
+		}
+	}
+
+	if err := g.load(); err != nil {
+		return nil, fmt.Errorf("load package: %w", err)
+	}
+
+	g.imports = make(map[string]string)
+	g.names = make(map[string]string)
+	for _, c := range codecs {
+		// Reserve names used by generated code.
+		g.names[c.codecName] = c.codecPath
+		g.names[c.wrapName] = c.wrapPath
+	}
+	g.names["fmt"] = "fmt"
+	g.names["wrapper"] = wrapperPath
+
+	// Shadow structures are generated unless only factories were requested.
+	if len(g.factories) == 0 || len(g.types) > 0 {
+		structs, err := g.structs()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range structs {
+			for _, c := range selected {
+				if err = g.checkMethods(s, c); err != nil {
+					if len(g.types) > 0 {
+						return nil, err
+					}
+					// Structs found automatically may already handle their own serialization.
+					continue
+				} else if err = checkErrorFields(s, c); err != nil {
+					return nil, err
+				}
+				g.use(c.codecPath, c.codecName)
+				g.use(c.wrapPath, c.wrapName)
+				g.writeShadow(s, c)
+				c.writeFuncs(g, s, c)
+			}
+		}
+	}
+
+	if len(g.factories) > 0 {
+		factories, err := g.factoryInfo()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range factories {
+			g.writeFactory(f)
+		}
+	}
+
+	return g.finish()
+}
+
+// load parses and type-checks the package in the generator directory.
+// The output file is skipped so that it can be regenerated.
+func (g *generator) load() error {
+	bp, err := build.ImportDir(g.dir, 0)
+	if err != nil {
+		return fmt.Errorf("import directory %s: %w", g.dir, err)
+	}
+
+	g.fset = token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == g.output {
+			continue
+		}
+		file, err := parser.ParseFile(g.fset, filepath.Join(g.dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", name, err)
+		}
+		files = append(files, file)
+	}
+
+	config := &types.Config{Importer: importer.ForCompiler(g.fset, "source", nil)}
+	if g.pkg, err = config.Check(importPath(g.dir, bp.ImportPath), g.fset, files, nil); err != nil {
+		return fmt.Errorf("check package: %w", err)
+	}
+	return nil
+}
+
+// importPath returns the module-aware import path for the package in the directory.
+// Type names generated for factories depend on the import path.
+func importPath(dir, fallback string) string {
+	cmd := exec.Command("go", "list", "-f", "{{.ImportPath}}", ".")
+	cmd.Dir = dir
+	if output, err := cmd.Output(); err == nil {
+		return strings.TrimSpace(string(output))
+	}
+	return fallback
+}
+
+// structs returns information about the structs for which code will be generated.
+// If no struct names were specified all structs with interface fields are returned.
+func (g *generator) structs() ([]*structInfo, error) {
+	names := g.types
+	if len(names) == 0 {
+		names = g.pkg.Scope().Names()
+	}
+
+	var structs []*structInfo
+	for _, name := range names {
+		obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			if len(g.types) > 0 {
+				return nil, fmt.Errorf("%w: %s", errNotStruct, name)
+			}
+			continue
+		}
+		named, _ := obj.Type().(*types.Named)
+		st, ok := obj.Type().Underlying().(*types.Struct)
+		if !ok || named == nil || named.TypeParams().Len() > 0 {
+			if len(g.types) > 0 {
+				return nil, fmt.Errorf("%w: %s", errNotStruct, name)
+			}
+			continue
+		}
+
+		info := &structInfo{name: name, named: named}
+		var wrapped bool
+		for i := 0; i < st.NumFields(); i++ {
+			v := st.Field(i)
+			if !v.Exported() && !v.Embedded() {
+				continue
+			}
+			field := classify(v, st.Tag(i))
+			if field.kind != plainField {
+				wrapped = true
+			}
+			info.fields = append(info.fields, field)
+		}
+		if wrapped || len(g.types) > 0 {
+			structs = append(structs, info)
+		}
+	}
+
+	if len(structs) < 1 {
+		return nil, errNoStructs
+	}
+	return structs, nil
+}
+
+// classify determines how a struct field is copied to and from the shadow structure.
+func classify(v *types.Var, tag string) *fieldInfo {
+	field := &fieldInfo{name: v.Name(), embedded: v.Embedded(), tag: tag, typ: v.Type()}
+	if v.Embedded() {
+		// Embedded fields (including interfaces) are copied as is.
+		return field
+	}
+	switch t := v.Type().Underlying().(type) {
+	case *types.Interface:
+		if isWrappable(v.Type()) {
+			field.kind = interfaceField
+			field.item = v.Type()
+		}
+	case *types.Slice:
+		if isWrappable(t.Elem()) {
+			field.kind = sliceField
+			field.item = t.Elem()
+		}
+	case *types.Array:
+		if isWrappable(t.Elem()) {
+			field.kind = arrayField
+			field.item = t.Elem()
+			field.length = t.Len()
+		}
+	case *types.Map:
+		if isWrappable(t.Elem()) {
+			field.kind = mapField
+			field.item = t.Elem()
+			field.key = t.Key()
+		}
+	}
+	return field
+}
+
+// isWrappable returns true for interface types other than error and type parameters.
+// Items in error fields can't be created by type name so they are not wrapped
+// and such fields must be skipped via struct tags (see checkErrorFields).
+func isWrappable(t types.Type) bool {
+	if _, ok := t.(*types.TypeParam); ok {
+		return false
+	} else if types.Identical(t, types.Universe.Lookup("error").Type()) {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Interface)
+	return ok
+}
+
+// checkErrorFields returns an error if the struct has a field of type error
+// (or a slice, array or map of error) that is not skipped by a "-" struct tag for the codec.
+// Such fields would be copied as is and could not be decoded.
+func checkErrorFields(s *structInfo, c *codec) error {
+	for _, field := range s.fields {
+		if field.embedded || !hasError(field.typ) {
+			continue
+		}
+		if name, _, _ := strings.Cut(reflect.StructTag(field.tag).Get(c.name), ","); name != "-" {
+			return fmt.Errorf("%w: %s.%s (skip with tag %s:\"-\")", errErrorField, s.name, field.name, c.name)
+		}
+	}
+	return nil
+}
+
+// hasError returns true for the error type and slices, arrays and maps of it.
+func hasError(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		t = u.Elem()
+	case *types.Array:
+		t = u.Elem()
+	case *types.Map:
+		t = u.Elem()
+	}
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// checkMethods returns an error if the struct already has methods that would be generated.
+func (g *generator) checkMethods(s *structInfo, c *codec) error {
+	methods := types.NewMethodSet(types.NewPointer(s.named))
+	for _, prefix := range []string{"Marshal", "Unmarshal"} {
+		name := prefix + c.suffix
+		if sel := methods.Lookup(g.pkg, name); sel != nil && len(sel.Index()) == 1 {
+			return fmt.Errorf("%w: %s.%s", errMethodConflict, s.name, name)
+		}
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// use records an import with the specified preferred name and returns the actual name.
+func (g *generator) use(path, name string) string {
+	if current, found := g.imports[path]; found {
+		return current
+	}
+	base := name
+	for i := 2; ; i++ {
+		if other, found := g.names[name]; !found || other == path {
+			break
+		}
+		name = base + strconv.Itoa(i)
+	}
+	g.imports[path] = name
+	g.names[name] = path
+	return name
+}
+
+// qualifier provides import names for other packages when formatting types.
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+	return g.use(pkg.Path(), pkg.Name())
+}
+
+// typeString returns the source form of a type within the generated file.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// finish prepends the file header and imports and formats the result.
+func (g *generator) finish() ([]byte, error) {
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "// Code generated by serialgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&header, "package %s\n\n", g.pkg.Name())
+	var std, other []string
+	for path := range g.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	header.WriteString("import (\n")
+	for i, paths := range [][]string{std, other} {
+		if i > 0 && len(std) > 0 && len(other) > 0 {
+			header.WriteString("\n")
+		}
+		for _, path := range paths {
+			name := g.imports[path]
+			if name == defaultImportName(path) {
+				fmt.Fprintf(&header, "\t%q\n", path)
+			} else {
+				fmt.Fprintf(&header, "\t%s %q\n", name, path)
+			}
+		}
+	}
+	header.WriteString(")\n")
+	header.Write(g.buf.Bytes())
+
+	source, err := format.Source(header.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, header.String())
+	}
+	return source, nil
+}
+
+// defaultImportName returns the probable package name for an import path.
+func defaultImportName(path string) string {
+	switch path {
+	case yamlPath:
+		return "yaml"
+	}
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// shadowName returns the name of the shadow structure for a struct and codec.
+func shadowName(s *structInfo, c *codec) string {
+	runes := []rune(s.name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes) + c.suffix
+}
+
+// receiver returns the receiver name for the methods of a struct.
+func receiver(s *structInfo) string {
+	return strings.ToLower(s.name[:1])
+}
+
+// wrapperType returns the wrapper type for the specified interface type.
+func (g *generator) wrapperType(c *codec, item types.Type) string {
+	return "*" + c.wrapName + ".Wrapper[" + g.typeString(item) + "]"
+}
+
+// wrapFunc returns the wrap function call prefix for the specified interface type.
+func (g *generator) wrapFunc(c *codec, item types.Type) string {
+	return c.wrapName + ".Wrap[" + g.typeString(item) + "]"
+}
+
+func (g *generator) writeShadow(s *structInfo, c *codec) {
+	shadow := shadowName(s, c)
+	g.printf("\n// %s is the %s shadow structure for %s.\n", shadow, c.suffix, s.name)
+	g.printf("// Interface fields are replaced by %s.Wrapper fields.\n", c.name)
+	g.printf("type %s struct {\n", shadow)
+	for _, f := range s.fields {
+		var fieldType string
+		switch f.kind {
+		case plainField:
+			fieldType = g.typeString(f.typ)
+		case interfaceField:
+			fieldType = g.wrapperType(c, f.item)
+		case sliceField:
+			fieldType = "[]" + g.wrapperType(c, f.item)
+		case arrayField:
+			fieldType = fmt.Sprintf("[%d]%s", f.length, g.wrapperType(c, f.item))
+		case mapField:
+			fieldType = fmt.Sprintf("map[%s]%s", g.typeString(f.key), g.wrapperType(c, f.item))
+		}
+		if f.embedded {
+			g.printf("\t%s", fieldType)
+		} else {
+			g.printf("\t%s %s", f.name, fieldType)
+		}
+		if f.tag != "" {
+			if strings.Contains(f.tag, "`") {
+				g.printf(" %s", strconv.Quote(f.tag))
+			} else {
+				g.printf(" `%s`", f.tag)
+			}
+		}
+		g.printf("\n")
+	}
+	g.printf("}\n")
+}
+
+// writeCopyTo writes code to copy struct fields into the shadow structure.
+func (g *generator) writeCopyTo(s *structInfo, c *codec) {
+	r := receiver(s)
+	for _, f := range s.fields {
+		switch f.kind {
+		case plainField:
+			g.printf("shadow.%s = %s.%s\n", f.name, r, f.name)
+		case interfaceField:
+			g.printf("if %s.%s != nil {\n", r, f.name)
+			g.printf("shadow.%s = %s(%s.%s)\n", f.name, g.wrapFunc(c, f.item), r, f.name)
+			g.printf("}\n")
+		case sliceField, arrayField, mapField:
+			index := "index"
+			if f.kind == mapField {
+				index = "key"
+			}
+			if f.kind != arrayField {
+				g.printf("if %s.%s != nil {\n", r, f.name)
+				if f.kind == sliceField {
+					g.printf("shadow.%s = make([]%s, len(%s.%s))\n",
+						f.name, g.wrapperType(c, f.item), r, f.name)
+				} else {
+					g.printf("shadow.%s = make(map[%s]%s, len(%s.%s))\n",
+						f.name, g.typeString(f.key), g.wrapperType(c, f.item), r, f.name)
+				}
+			}
+			g.printf("for %s, item := range %s.%s {\n", index, r, f.name)
+			if f.kind == mapField {
+				g.printf("shadow.%s[key] = nil\n", f.name)
+			}
+			g.printf("if item != nil {\n")
+			g.printf("shadow.%s[%s] = %s(item)\n", f.name, index, g.wrapFunc(c, f.item))
+			g.printf("}\n}\n")
+			if f.kind != arrayField {
+				g.printf("}\n")
+			}
+		}
+	}
+}
+
+// writeCopyFrom writes code to copy shadow structure fields into the struct.
+func (g *generator) writeCopyFrom(s *structInfo) {
+	r := receiver(s)
+	for _, f := range s.fields {
+		switch f.kind {
+		case plainField:
+			g.printf("%s.%s = shadow.%s\n", r, f.name, f.name)
+		case interfaceField:
+			g.printf("%s.%s = nil\n", r, f.name)
+			g.printf("if shadow.%s != nil {\n", f.name)
+			g.printf("%s.%s = shadow.%s.Get()\n", r, f.name, f.name)
+			g.printf("}\n")
+		case sliceField, arrayField, mapField:
+			index := "index"
+			switch f.kind {
+			case sliceField:
+				g.printf("%s.%s = nil\n", r, f.name)
+				g.printf("if shadow.%s != nil {\n", f.name)
+				g.printf("%s.%s = make(%s, len(shadow.%s))\n", r, f.name, g.typeString(f.typ), f.name)
+			case arrayField:
+				g.printf("%s.%s = %s{}\n", r, f.name, g.typeString(f.typ))
+			case mapField:
+				index = "key"
+				g.printf("%s.%s = nil\n", r, f.name)
+				g.printf("if shadow.%s != nil {\n", f.name)
+				g.printf("%s.%s = make(%s, len(shadow.%s))\n", r, f.name, g.typeString(f.typ), f.name)
+			}
+			g.printf("for %s, item := range shadow.%s {\n", index, f.name)
+			if f.kind == mapField {
+				g.printf("%s.%s[key] = nil\n", r, f.name)
+			}
+			g.printf("if item != nil {\n")
+			g.printf("%s.%s[%s] = item.Get()\n", r, f.name, index)
+			g.printf("}\n}\n")
+			if f.kind != arrayField {
+				g.printf("}\n")
+			}
+		}
+	}
+}
+
+func (g *generator) writeJSON(s *structInfo, c *codec) {
+	r, shadow := receiver(s), shadowName(s, c)
+	g.printf("\n// MarshalJSON copies the %s into a %s shadow structure and marshals that.\n", s.name, shadow)
+	g.printf("func (%s *%s) MarshalJSON() ([]byte, error) {\n", r, s.name)
+	g.printf("shadow := new(%s)\n", shadow)
+	g.writeCopyTo(s, c)
+	g.printf("return %s.Marshal(shadow)\n", c.codecName)
+	g.printf("}\n")
+
+	g.printf("\n// UnmarshalJSON unmarshals a %s shadow structure and copies it into the %s.\n", shadow, s.name)
+	g.printf("func (%s *%s) UnmarshalJSON(marshaled []byte) error {\n", r, s.name)
+	g.printf("shadow := new(%s)\n", shadow)
+	g.printf("if err := %s.Unmarshal(marshaled, shadow); err != nil {\n", c.codecName)
+	g.printf("return err\n")
+	g.printf("}\n")
+	g.writeCopyFrom(s)
+	g.printf("return nil\n")
+	g.printf("}\n")
+}
+
+func (g *generator) writeYAML(s *structInfo, c *codec) {
+	r, shadow := receiver(s), shadowName(s, c)
+	g.printf("\n// MarshalYAML copies the %s into a %s shadow structure to be marshaled.\n", s.name, shadow)
+	g.printf("func (%s *%s) MarshalYAML() (interface{}, error) {\n", r, s.name)
+	g.printf("shadow := new(%s)\n", shadow)
+	g.writeCopyTo(s, c)
+	g.printf("return shadow, nil\n")
+	g.printf("}\n")
+
+	g.printf("\n// UnmarshalYAML decodes a %s shadow structure and copies it into the %s.\n", shadow, s.name)
+	g.printf("func (%s *%s) UnmarshalYAML(node *%s.Node) error {\n", r, s.name, c.codecName)
+	g.printf("shadow := new(%s)\n", shadow)
+	g.printf("if err := node.Decode(shadow); err != nil {\n")
+	g.printf("return err\n")
+	g.printf("}\n")
+	g.writeCopyFrom(s)
+	g.printf("return nil\n")
+	g.printf("}\n")
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+
+	"github.com/madkins23/go-type/reg"
+
+	"github.com/madkins23/go-serial/test"
+	"github.com/madkins23/go-serial/test/generated"
+)
+
+const (
+	testDir      = "testdata/portfolio"
+	failureDir   = "testdata/failure"
+	generatedDir = "../../test/generated"
+)
+
+type GenerateTestSuite struct {
+	suite.Suite
+}
+
+func TestGenerateSuite(t *testing.T) {
+	suite.Run(t, new(GenerateTestSuite))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+func (suite *GenerateTestSuite) TestGenerate() {
+	gen := &generator{
+		dir:     testDir,
+		output:  defaultOutput,
+		types:   []string{"Portfolio", "Bond"},
+		formats: []string{"json", "yaml"},
+	}
+	source, err := gen.generate()
+	suite.Require().NoError(err)
+	generated := string(source)
+	suite.Assert().Contains(generated, "// Code generated by serialgen. DO NOT EDIT.")
+	suite.Assert().Contains(generated, `sjson "github.com/madkins23/go-serial/json"`)
+	suite.Assert().Contains(generated, `syaml "github.com/madkins23/go-serial/yaml"`)
+	suite.Assert().Contains(generated, "type portfolioJSON struct {")
+	suite.Assert().Contains(generated, "type portfolioYAML struct {")
+	suite.Assert().Contains(generated, "type bondJSON struct {")
+	suite.Assert().Contains(generated, "type bondYAML struct {")
+	suite.Assert().Contains(generated,
+		"Favorite  *sjson.Wrapper[test.Investment] `json:\"favorite,omitempty\"`")
+	suite.Assert().Contains(generated, "Positions []*syaml.Wrapper[test.Investment]")
+	suite.Assert().Contains(generated, "Top       [2]*sjson.Wrapper[test.Investment]")
+	suite.Assert().Contains(generated, "Lookup    map[string]*syaml.Wrapper[test.Investment]")
+	suite.Assert().Contains(generated, "Opened    time.Time")
+	suite.Assert().Contains(generated, "Failure   error `json:\"-\" yaml:\"-\"`")
+	suite.Assert().NotContains(generated, "private")
+	suite.Assert().Contains(generated, "\ttest.BondData\n")
+	suite.Assert().Contains(generated, "func (p *Portfolio) MarshalJSON() ([]byte, error) {")
+	suite.Assert().Contains(generated, "func (p *Portfolio) UnmarshalJSON(marshaled []byte) error {")
+	suite.Assert().Contains(generated, "func (b *Bond) MarshalYAML() (interface{}, error) {")
+	suite.Assert().Contains(generated, "func (b *Bond) UnmarshalYAML(node *yaml.Node) error {")
+	suite.checkCompiles(source)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_Automatic() {
+	gen := &generator{
+		dir:     testDir,
+		output:  defaultOutput,
+		formats: []string{"json"},
+	}
+	source, err := gen.generate()
+	suite.Require().NoError(err)
+	generated := string(source)
+	suite.Assert().Contains(generated, "type portfolioJSON struct {")
+	suite.Assert().Contains(generated, "type bondJSON struct {")
+	suite.Assert().NotContains(generated, "YAML")
+	// Plain has no interface fields and Custom already has serialization methods.
+	suite.Assert().NotContains(generated, "plainJSON")
+	suite.Assert().NotContains(generated, "customJSON")
+	suite.checkCompiles(source)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_Errors() {
+	gen := &generator{dir: testDir, output: defaultOutput, formats: []string{"xml"}}
+	_, err := gen.generate()
+	suite.Assert().ErrorIs(err, errUnknownFormat)
+	gen = &generator{dir: testDir, output: defaultOutput, types: []string{"Missing"}, formats: []string{"json"}}
+	_, err = gen.generate()
+	suite.Assert().ErrorIs(err, errNotStruct)
+	gen = &generator{dir: testDir, output: defaultOutput, types: []string{"Custom"}, formats: []string{"json"}}
+	_, err = gen.generate()
+	suite.Assert().ErrorIs(err, errMethodConflict)
+}
+
+// TestGenerate_ErrorFields verifies that error fields must be skipped via struct tags.
+func (suite *GenerateTestSuite) TestGenerate_ErrorFields() {
+	gen := &generator{dir: failureDir, output: defaultOutput, types: []string{"Report"}, formats: []string{"json"}}
+	_, err := gen.generate()
+	suite.Assert().ErrorIs(err, errErrorField)
+	suite.Assert().ErrorContains(err, "Report.Err")
+	gen = &generator{dir: failureDir, output: defaultOutput, types: []string{"Listed"}, formats: []string{"json"}}
+	source, err := gen.generate()
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(source), "Errs   []error `json:\"-\"`")
+	gen = &generator{dir: failureDir, output: defaultOutput, types: []string{"Listed"}, formats: []string{"yaml"}}
+	_, err = gen.generate()
+	suite.Assert().ErrorIs(err, errErrorField)
+	suite.Assert().ErrorContains(err, "Listed.Errs")
+	gen = &generator{dir: failureDir, output: defaultOutput, formats: []string{"json"}}
+	_, err = gen.generate()
+	suite.Assert().ErrorIs(err, errErrorField)
+}
+
+// TestGenerate_RoundTrip verifies that the code generated for the test/generated package
+// is current and that the generated methods round trip through JSON and YAML.
+func (suite *GenerateTestSuite) TestGenerate_RoundTrip() {
+	gen := &generator{
+		dir:     generatedDir,
+		output:  defaultOutput,
+		types:   []string{"Portfolio", "Bond"},
+		formats: []string{"json", "yaml"},
+	}
+	source, err := gen.generate()
+	suite.Require().NoError(err)
+	current, err := os.ReadFile(filepath.Join(generatedDir, defaultOutput))
+	suite.Require().NoError(err)
+	suite.Require().Equal(string(source), string(current), "run go generate in "+generatedDir)
+
+	reg.Singleton().Clear()
+	suite.Require().NoError(test.Register())
+	suite.Require().NoError(reg.Register(&generated.Bond{}))
+	start := generated.MakePortfolio()
+	start.Failure = errors.New("not serialized")
+
+	marshaled, err := json.Marshal(start)
+	suite.Require().NoError(err)
+	finish := new(generated.Portfolio)
+	suite.Require().NoError(json.Unmarshal(marshaled, finish))
+	suite.Assert().Nil(finish.Failure)
+	finish.Failure = start.Failure
+	suite.Assert().Equal(start, finish)
+
+	marshaled, err = yaml.Marshal(start)
+	suite.Require().NoError(err)
+	finish = new(generated.Portfolio)
+	suite.Require().NoError(yaml.Unmarshal(marshaled, finish))
+	suite.Assert().Nil(finish.Failure)
+	finish.Failure = start.Failure
+	suite.Assert().Equal(start, finish)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_Factory() {
+	gen := &generator{
+		dir:       testDir,
+		output:    defaultOutput,
+		formats:   []string{"json", "yaml"},
+		factories: []string{"test.Investment", "Source"},
+		alias:     "port",
+	}
+	source, err := gen.generate()
+	suite.Require().NoError(err)
+	generated := string(source)
+	// Only factories are generated without the -type flag.
+	suite.Assert().NotContains(generated, "portfolioJSON")
+	suite.Assert().Contains(generated, `"github.com/madkins23/go-serial/wrapper"`)
+	suite.Assert().Contains(generated, "type investmentFactory struct{}")
+	suite.Assert().Contains(generated, "func (investmentFactory) NameFor(item test.Investment) (string, error) {")
+	suite.Assert().Contains(generated, "func (investmentFactory) Make(name string) (test.Investment, error) {")
+	suite.Assert().Contains(generated, "case *Bond:\n\t\treturn \"[port]Bond\", nil")
+	suite.Assert().Contains(generated,
+		`case "[port]Bond", "github.com/madkins23/go-serial/cmd/serialgen/testdata/portfolio/Bond":`)
+	suite.Assert().Contains(generated, "wrapper.SetFactory[test.Investment](investmentFactory{})")
+	// Named implements the local Source interface with a value receiver.
+	suite.Assert().Contains(generated, "type sourceFactory struct{}")
+	suite.Assert().Contains(generated, "case *Named, Named:")
+	suite.Assert().Contains(generated, "wrapper.SetFactory[Source](sourceFactory{})")
+	suite.checkCompiles(source)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_FactoryImpl() {
+	gen := &generator{
+		dir:       testDir,
+		output:    defaultOutput,
+		types:     []string{"Bond"},
+		formats:   []string{"json"},
+		factories: []string{"test.Borrower"},
+		impls:     []string{"Named"},
+		noInit:    true,
+	}
+	source, err := gen.generate()
+	suite.Require().NoError(err)
+	generated := string(source)
+	suite.Assert().Contains(generated, "type bondJSON struct {")
+	suite.Assert().Contains(generated, "type borrowerFactory struct{}")
+	suite.Assert().Contains(generated,
+		`case "github.com/madkins23/go-serial/cmd/serialgen/testdata/portfolio/Named":`)
+	suite.Assert().NotContains(generated, "case *Bond")
+	suite.Assert().NotContains(generated, "func init()")
+	suite.checkCompiles(source)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_FactoryErrors() {
+	gen := &generator{dir: testDir, output: defaultOutput, formats: []string{"json"},
+		factories: []string{"Portfolio"}}
+	_, err := gen.generate()
+	suite.Assert().ErrorIs(err, errNotInterface)
+	gen = &generator{dir: testDir, output: defaultOutput, formats: []string{"json"},
+		factories: []string{"fmt.Stringer"}}
+	_, err = gen.generate()
+	suite.Assert().ErrorIs(err, errNotInterface)
+	gen = &generator{dir: testDir, output: defaultOutput, formats: []string{"json"},
+		factories: []string{"test.Investment"}, impls: []string{"Plain"}}
+	_, err = gen.generate()
+	suite.Assert().ErrorIs(err, errNotImplemented)
+}
+
+func (suite *GenerateTestSuite) TestSplitList() {
+	suite.Assert().Nil(splitList(""))
+	suite.Assert().Equal([]string{"json", "yaml"}, splitList(" json,,yaml "))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// checkCompiles type checks the generated source along with the test package.
+func (suite *GenerateTestSuite) checkCompiles(source []byte) {
+	fset := token.NewFileSet()
+	matches, err := filepath.Glob(filepath.Join(testDir, "*.go"))
+	suite.Require().NoError(err)
+	var files []*ast.File
+	for _, name := range matches {
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		suite.Require().NoError(err)
+		files = append(files, file)
+	}
+	file, err := parser.ParseFile(fset, defaultOutput, source, 0)
+	suite.Require().NoError(err)
+	files = append(files, file)
+	config := &types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = config.Check("portfolio", fset, files, nil)
+	suite.Assert().NoError(err)
+}
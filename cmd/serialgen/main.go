@@ -0,0 +1,106 @@
+// Command serialgen generates shadow structures and serialization methods
+// for structs that have interface fields.
+//
+// The generated code follows the "Convert to Wrappers During Serialization"
+// pattern described in the go-serial README:
+// for each struct a shadow structure is generated with json.Wrapper or yaml.Wrapper
+// fields in place of interface fields, along with MarshalJSON/UnmarshalJSON and/or
+// MarshalYAML/UnmarshalYAML methods that copy data back and forth.
+//
+// The command can also generate reflection-free wrapper.Factory implementations
+// for interfaces, using type switches to provide type names and create new items.
+// A generated Factory registers itself during initialization and
+// is then used by json.Wrapper and yaml.Wrapper instead of go-type/reg.
+//
+// Usage:
+//
+//	serialgen [flags] [directory]
+//
+// The directory defaults to the current directory.
+// Flags:
+//
+//	-type     comma-separated list of struct type names,
+//	          defaults to all structs with interface fields
+//	-format   comma-separated list of formats (json, yaml), defaults to both
+//	-output   output file name, defaults to serial_gen.go in the directory
+//	-factory  comma-separated list of interface names for which to generate factories,
+//	          interfaces from imported packages are qualified (e.g. test.Investment)
+//	-impl     comma-separated list of implementation type names for factories,
+//	          defaults to all types in the package that implement the interface
+//	-alias    go-type/reg alias for the package used in factory type names
+//	-init     generate init() functions to register factories, defaults to true
+//
+// Fields of type error (or slices, arrays or maps of error) can't be decoded
+// so they must be skipped via struct tags (e.g. `json:"-" yaml:"-"`),
+// otherwise an error is returned.
+//
+// When the -factory flag is specified shadow structures are only generated
+// if the -type flag is also specified.
+//
+// Typical usage is via go:generate:
+//
+//	//go:generate go run github.com/madkins23/go-serial/cmd/serialgen -type Portfolio,Bond
+//	//go:generate go run github.com/madkins23/go-serial/cmd/serialgen -factory Investment -alias test
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultOutput = "serial_gen.go"
+
+func main() {
+	var typeNames, formats, output, factories, impls, alias string
+	var register bool
+	flag.StringVar(&typeNames, "type", "", "comma-separated list of struct type names")
+	flag.StringVar(&formats, "format", "json,yaml", "comma-separated list of formats (json, yaml)")
+	flag.StringVar(&output, "output", "", "output file name")
+	flag.StringVar(&factories, "factory", "", "comma-separated list of interface names for factories")
+	flag.StringVar(&impls, "impl", "", "comma-separated list of implementation type names for factories")
+	flag.StringVar(&alias, "alias", "", "go-type/reg alias for the package used in factory type names")
+	flag.BoolVar(&register, "init", true, "generate init() functions to register factories")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if output == "" {
+		output = filepath.Join(dir, defaultOutput)
+	}
+
+	gen := &generator{
+		dir:       dir,
+		output:    filepath.Base(output),
+		types:     splitList(typeNames),
+		formats:   splitList(formats),
+		factories: splitList(factories),
+		impls:     splitList(impls),
+		alias:     alias,
+		noInit:    !register,
+	}
+	source, err := gen.generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "serialgen: %v\n", err)
+		os.Exit(1)
+	}
+	if err = os.WriteFile(output, source, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "serialgen: write %s: %v\n", output, err)
+		os.Exit(1)
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
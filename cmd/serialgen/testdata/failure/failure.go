@@ -0,0 +1,18 @@
+// Package failure provides source with error fields for serialgen tests.
+package failure
+
+import (
+	"github.com/madkins23/go-serial/test"
+)
+
+// Report has an error field that is not skipped.
+type Report struct {
+	Source test.Borrower
+	Err    error
+}
+
+// Listed has an error slice that is only skipped for JSON.
+type Listed struct {
+	Source test.Borrower
+	Errs   []error `json:"-"`
+}
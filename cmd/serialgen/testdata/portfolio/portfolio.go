@@ -0,0 +1,52 @@
+// Package portfolio provides source for serialgen tests.
+package portfolio
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/madkins23/go-serial/test"
+)
+
+type Portfolio struct {
+	Favorite  test.Investment `json:"favorite,omitempty"`
+	Positions []test.Investment
+	Top       [2]test.Investment
+	Lookup    map[string]test.Investment
+	Opened    time.Time
+	Failure   error `json:"-" yaml:"-"`
+	private   test.Investment
+}
+
+type Bond struct {
+	test.BondData
+	Source test.Borrower
+}
+
+// Plain has no interface fields.
+type Plain struct {
+	Count int
+}
+
+// Custom already has JSON serialization methods.
+type Custom struct {
+	Source test.Borrower
+}
+
+func (c *Custom) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Source.Name())
+}
+
+// Source is implemented by Named.
+type Source interface {
+	Name() string
+}
+
+// Named implements Source with a value receiver.
+type Named struct {
+	Label string
+}
+
+func (n Named) Name() string {
+	return n.Label
+}
@@ -0,0 +1,13 @@
+// Package finder provides ready-made pointer.Finder functions.
+//
+// Map returns Target items from a fixed map and Chain tries several Finder functions in order.
+// Dir and FS load Target items from JSON or YAML files, one file per key,
+// decoded via the json and yaml packages so that interface fields are supported.
+// FS may be used with embed.FS for reference data built into a program.
+// SQL runs a query per group against a database/sql database
+// and SetSQL also configures a pointer.Storer that writes Target items through to the database.
+//
+// All Finder functions return pointer.ErrNoSuchTarget for keys that are not found
+// so that they may be combined via Chain and used with the pointer.DanglingPolicy values.
+
+package finder
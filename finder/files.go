@@ -0,0 +1,113 @@
+package finder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/madkins23/go-serial/json"
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/wrapper"
+	"github.com/madkins23/go-serial/yaml"
+)
+
+// ErrBadKey is returned from a file Finder if the key can't be used as a file name.
+var ErrBadKey = errors.New("key not usable as file name")
+
+// extensions are the file name extensions tried in order by file Finder functions.
+var extensions = []string{".json", ".yaml", ".yml"}
+
+// Option specifies optional behavior for file Finder functions.
+type Option func(*options)
+
+type options struct {
+	jsonOptions []json.Option
+	yamlOptions []yaml.Option
+}
+
+// WithRegistry specifies the Registry used to create Target items
+// and the items in their interface fields.
+func WithRegistry(registry wrapper.Registry) Option {
+	return func(o *options) {
+		o.jsonOptions = append(o.jsonOptions, json.WithRegistry(registry))
+		o.yamlOptions = append(o.yamlOptions, yaml.WithRegistry(registry))
+	}
+}
+
+// WithJSONOptions specifies options for decoding JSON files (e.g. json.WithCache).
+func WithJSONOptions(opts ...json.Option) Option {
+	return func(o *options) {
+		o.jsonOptions = append(o.jsonOptions, opts...)
+	}
+}
+
+// WithYAMLOptions specifies options for decoding YAML files (e.g. yaml.WithCache).
+func WithYAMLOptions(opts ...yaml.Option) Option {
+	return func(o *options) {
+		o.yamlOptions = append(o.yamlOptions, opts...)
+	}
+}
+
+// Dir returns a Finder that loads Target items from files in the specified directory.
+// See FS.
+func Dir(dir string, opts ...Option) pointer.Finder {
+	return FS(os.DirFS(dir), ".", opts...)
+}
+
+// FS returns a Finder that loads Target items from files in the specified directory of the file system.
+// Each Target is in a file named by its key with the extension .json, .yaml or .yml
+// (tried in that order), for example pets/Noah.json for key "Noah" in directory "pets".
+//
+// The file contains a wrapped Target as serialized by json.Wrapper or yaml.Wrapper:
+//
+//	{"type": "[test]Pet", "data": {"Name": "Noah", "Type": "cat"}}
+//
+// The type name is resolved by the Registry specified via WithRegistry
+// (or the Registry returned by wrapper.Lookup for pointer.Target).
+// Keys that can't be used as file names (e.g. keys containing a slash) return ErrBadKey.
+// If there is no file for the key pointer.ErrNoSuchTarget is returned.
+func FS(fsys fs.FS, dir string, opts ...Option) pointer.Finder {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(key string) (pointer.Target, error) {
+		if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+			return nil, fmt.Errorf("%w: '%s'", ErrBadKey, key)
+		}
+		for _, ext := range extensions {
+			name := path.Join(dir, key+ext)
+			data, err := fs.ReadFile(fsys, name)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("read %s: %w", name, err)
+			}
+			target, err := o.decode(ext, data)
+			if err != nil {
+				return nil, fmt.Errorf("decode %s: %w", name, err)
+			}
+			return target, nil
+		}
+		return nil, pointer.ErrNoSuchTarget
+	}
+}
+
+// decode returns the wrapped Target in the file data with the specified extension.
+func (o *options) decode(ext string, data []byte) (pointer.Target, error) {
+	if ext == ".json" {
+		w := new(json.Wrapper[pointer.Target])
+		if err := json.UnmarshalWith(data, w, o.jsonOptions...); err != nil {
+			return nil, err
+		}
+		return w.Get(), nil
+	}
+	w := new(yaml.Wrapper[pointer.Target])
+	if err := yaml.UnmarshalWith(data, w, o.yamlOptions...); err != nil {
+		return nil, err
+	}
+	return w.Get(), nil
+}
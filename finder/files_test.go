@@ -0,0 +1,65 @@
+package finder
+
+import (
+	"embed"
+	"testing"
+
+	"github.com/madkins23/go-type/reg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/test"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+func testRegistry(t *testing.T) reg.Registry {
+	registry := reg.NewRegistry()
+	require.NoError(t, registry.AddAlias("test", &test.Pet{}))
+	require.NoError(t, registry.Register(&test.Pet{}))
+	return registry
+}
+
+func TestFS(t *testing.T) {
+	checkFiles(t, FS(testdata, "testdata/pets", WithRegistry(testRegistry(t))))
+}
+
+func TestDir(t *testing.T) {
+	checkFiles(t, Dir("testdata/pets", WithRegistry(testRegistry(t))))
+}
+
+func checkFiles(t *testing.T, finder pointer.Finder) {
+	for _, pet := range []*test.Pet{test.Noah, test.Lacey, test.Orca} {
+		target, err := finder(pet.Name)
+		require.NoError(t, err, pet.Name)
+		assert.Equal(t, pet, target)
+	}
+	_, err := finder("Knight")
+	assert.ErrorIs(t, err, pointer.ErrNoSuchTarget)
+	_, err = finder("Broken")
+	assert.ErrorContains(t, err, "Broken.json: envelope")
+	for _, key := range []string{"", ".", "..", "../pets/Noah", `a\b`} {
+		_, err = finder(key)
+		assert.ErrorIs(t, err, ErrBadKey, key)
+	}
+}
+
+func TestFS_Cache(t *testing.T) {
+	cache := pointer.NewCache()
+	require.NoError(t, cache.SetFinder("cat", Chain(
+		Map(map[string]pointer.Target{"Tom": &test.Pet{Name: "Tom", Type: "cat"}}),
+		FS(testdata, "testdata/pets", WithRegistry(testRegistry(t))),
+	), false))
+	target, err := cache.GetTarget("cat", "Noah", nil)
+	require.NoError(t, err)
+	assert.Equal(t, test.Noah, target)
+	target, err = cache.GetTarget("cat", "Tom", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Tom", target.Key())
+
+	// The registry is required to decode the files.
+	_, err = FS(testdata, "testdata/pets")("Noah")
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package finder
+
+import (
+	"errors"
+
+	"github.com/madkins23/go-serial/pointer"
+)
+
+// Map returns a Finder that returns the Target items in the map by key.
+// The map is copied so later changes to it do not affect the Finder.
+func Map(targets map[string]pointer.Target) pointer.Finder {
+	copied := make(map[string]pointer.Target, len(targets))
+	for key, target := range targets {
+		copied[key] = target
+	}
+	return func(key string) (pointer.Target, error) {
+		if target, found := copied[key]; found && target != nil {
+			return target, nil
+		}
+		return nil, pointer.ErrNoSuchTarget
+	}
+}
+
+// Chain returns a Finder that calls the specified Finder functions in order
+// and returns the first Target found.
+// A Finder that returns pointer.ErrNoSuchTarget or a nil Target is skipped,
+// any other error is returned immediately.
+// Nil Finder functions are ignored.
+func Chain(finders ...pointer.Finder) pointer.Finder {
+	chain := make([]pointer.Finder, 0, len(finders))
+	for _, finder := range finders {
+		if finder != nil {
+			chain = append(chain, finder)
+		}
+	}
+	return func(key string) (pointer.Target, error) {
+		for _, finder := range chain {
+			if target, err := finder(key); errors.Is(err, pointer.ErrNoSuchTarget) {
+				continue
+			} else if err != nil {
+				return nil, err
+			} else if target != nil {
+				return target, nil
+			}
+		}
+		return nil, pointer.ErrNoSuchTarget
+	}
+}
@@ -0,0 +1,59 @@
+package finder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/test"
+)
+
+func TestMap(t *testing.T) {
+	targets := map[string]pointer.Target{"Noah": test.Noah, "Nil": nil}
+	finder := Map(targets)
+	delete(targets, "Noah")
+	target, err := finder("Noah")
+	require.NoError(t, err)
+	assert.Equal(t, test.Noah, target)
+	_, err = finder("Nil")
+	assert.ErrorIs(t, err, pointer.ErrNoSuchTarget)
+	_, err = finder("Lacey")
+	assert.ErrorIs(t, err, pointer.ErrNoSuchTarget)
+}
+
+func TestChain(t *testing.T) {
+	errDown := errors.New("database down")
+	var calls []string
+	finder := Chain(
+		Map(map[string]pointer.Target{"Noah": test.Noah}),
+		nil,
+		func(key string) (pointer.Target, error) {
+			calls = append(calls, key)
+			switch key {
+			case "Lacey":
+				return nil, nil
+			case "Down":
+				return nil, errDown
+			}
+			return nil, pointer.ErrNoSuchTarget
+		},
+		Map(map[string]pointer.Target{"Noah": test.Orca, "Lacey": test.Lacey}),
+	)
+	target, err := finder("Noah")
+	require.NoError(t, err)
+	assert.Equal(t, test.Noah, target)
+	target, err = finder("Lacey")
+	require.NoError(t, err)
+	assert.Equal(t, test.Lacey, target)
+	_, err = finder("Down")
+	assert.ErrorIs(t, err, errDown)
+	_, err = finder("Orca")
+	assert.ErrorIs(t, err, pointer.ErrNoSuchTarget)
+	assert.Equal(t, []string{"Lacey", "Down", "Orca"}, calls)
+
+	_, err = Chain()("Noah")
+	assert.ErrorIs(t, err, pointer.ErrNoSuchTarget)
+}
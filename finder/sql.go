@@ -0,0 +1,122 @@
+package finder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/madkins23/go-serial/pointer"
+)
+
+// ErrBadSQLGroup is returned from SetSQL if an SQLGroup has no Query or Mapper,
+// has a Store statement without Values or has an empty group name.
+var ErrBadSQLGroup = errors.New("sql group incomplete")
+
+// RowScanner scans the columns of a row returned by a query.
+// It is implemented by *sql.Row and *sql.Rows.
+type RowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// RowMapper returns the Target for a row returned by the query for a group.
+type RowMapper func(row RowScanner) (pointer.Target, error)
+
+// SQLValues returns the arguments for the statement that persists a Target.
+type SQLValues func(target pointer.Target) ([]interface{}, error)
+
+// SQLGroup specifies how Target items for a group are read from and written to a database.
+type SQLGroup struct {
+	// Query selects the row for a Target with the key as its only argument.
+	Query string
+
+	// Mapper returns the Target for the row selected by Query.
+	Mapper RowMapper
+
+	// Store is an optional statement that persists a Target passed to SetTarget
+	// with the arguments returned by Values (e.g. an INSERT or upsert).
+	Store string
+
+	// Values returns the arguments for Store.
+	Values SQLValues
+}
+
+// SQL returns a Finder that runs the query with the key as its only argument
+// and returns the Target returned by the RowMapper for the first row.
+// If the query returns no rows pointer.ErrNoSuchTarget is returned.
+func SQL(db *sql.DB, query string, mapper RowMapper) pointer.Finder {
+	finder := SQLContext(db, query, mapper)
+	return func(key string) (pointer.Target, error) {
+		return finder(context.Background(), key)
+	}
+}
+
+// SQLContext returns a ContextFinder that runs the query with the specified context.
+// See SQL.
+func SQLContext(db *sql.DB, query string, mapper RowMapper) pointer.ContextFinder {
+	return func(ctx context.Context, key string) (pointer.Target, error) {
+		target, err := mapper(db.QueryRowContext(ctx, query, key))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pointer.ErrNoSuchTarget
+		} else if err != nil {
+			return nil, fmt.Errorf("query %s: %w", key, err)
+		}
+		return target, nil
+	}
+}
+
+// SQLStorer returns a Storer that executes the statement with the arguments returned by values.
+func SQLStorer(db *sql.DB, statement string, values SQLValues) pointer.Storer {
+	return func(target pointer.Target) error {
+		args, err := values(target)
+		if err != nil {
+			return fmt.Errorf("values for %s/%s: %w", target.Group(), target.Key(), err)
+		}
+		if _, err = db.Exec(statement, args...); err != nil {
+			return fmt.Errorf("store %s/%s: %w", target.Group(), target.Key(), err)
+		}
+		return nil
+	}
+}
+
+// SetSQL configures a ContextFinder in the Cache for each group using the database.
+// Groups with a Store statement also get a Storer so that Target items passed to
+// SetTarget are written through to the database.
+// A nil Cache means the default Cache.
+//
+// All groups are checked before any are configured, so if an error is returned
+// for an incomplete group or, when replace is false, a group that already has
+// a Finder or Storer, the Cache is not changed.
+func SetSQL(cache *pointer.Cache, db *sql.DB, groups map[string]SQLGroup, replace bool) error {
+	if cache == nil {
+		cache = pointer.DefaultCache()
+	}
+	names := make([]string, 0, len(groups))
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	for _, group := range names {
+		g := groups[group]
+		if group == "" || g.Query == "" || g.Mapper == nil || (g.Store != "" && g.Values == nil) {
+			return fmt.Errorf("%w: '%s'", ErrBadSQLGroup, group)
+		} else if !replace && cache.HasFinder(group) {
+			return fmt.Errorf("set finder %s: %w", group, pointer.ErrFinderAlreadyExists)
+		} else if !replace && g.Store != "" && cache.HasStorer(group) {
+			return fmt.Errorf("set storer %s: %w", group, pointer.ErrStorerAlreadyExists)
+		}
+	}
+	for _, group := range names {
+		g := groups[group]
+		if err := cache.SetContextFinder(group, SQLContext(db, g.Query, g.Mapper), replace); err != nil {
+			return fmt.Errorf("set finder %s: %w", group, err)
+		}
+		if g.Store != "" {
+			if err := cache.SetStorer(group, SQLStorer(db, g.Store, g.Values), replace); err != nil {
+				return fmt.Errorf("set storer %s: %w", group, err)
+			}
+		}
+	}
+	return nil
+}
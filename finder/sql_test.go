@@ -0,0 +1,245 @@
+package finder
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/test"
+)
+
+const (
+	selectPet = "SELECT name, type FROM pets WHERE name = ?"
+	insertPet = "INSERT INTO pets (name, type) VALUES (?, ?)"
+	failPet   = "SELECT broken"
+)
+
+// fakeDriver is a database/sql driver for a table of pets held in memory.
+type fakeDriver struct {
+	sync.Mutex
+	pets map[string]string
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	switch query {
+	case selectPet, insertPet, failPet:
+		return &fakeStmt{driver: c.driver, query: query}, nil
+	}
+	return nil, fmt.Errorf("unknown query '%s'", query)
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+type fakeStmt struct {
+	driver *fakeDriver
+	query  string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.query != insertPet || len(args) != 2 {
+		return nil, fmt.Errorf("bad exec '%s'", s.query)
+	}
+	s.driver.Lock()
+	defer s.driver.Unlock()
+	s.driver.pets[args[0].(string)] = args[1].(string)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.query != selectPet || len(args) != 1 {
+		return nil, fmt.Errorf("bad query '%s'", s.query)
+	}
+	s.driver.Lock()
+	defer s.driver.Unlock()
+	rows := &fakeRows{}
+	if petType, found := s.driver.pets[args[0].(string)]; found {
+		rows.values = [][]driver.Value{{args[0], petType}}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"name", "type"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) < 1 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var (
+	fakeOnce sync.Once
+	fake     = &fakeDriver{}
+)
+
+// openFake returns a database holding the specified pets.
+func openFake(t *testing.T, pets ...*test.Pet) *sql.DB {
+	fakeOnce.Do(func() {
+		sql.Register("finder-fake", fake)
+	})
+	fake.Lock()
+	fake.pets = make(map[string]string)
+	for _, pet := range pets {
+		fake.pets[pet.Name] = pet.Type
+	}
+	fake.Unlock()
+	db, err := sql.Open("finder-fake", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func mapPet(row RowScanner) (pointer.Target, error) {
+	pet := new(test.Pet)
+	if err := row.Scan(&pet.Name, &pet.Type); err != nil {
+		return nil, err
+	}
+	return pet, nil
+}
+
+func petValues(target pointer.Target) ([]interface{}, error) {
+	pet, ok := target.(*test.Pet)
+	if !ok {
+		return nil, pointer.ErrNotTarget
+	}
+	return []interface{}{pet.Name, pet.Type}, nil
+}
+
+func TestSQL(t *testing.T) {
+	db := openFake(t, test.Noah, test.Knight)
+	finder := SQL(db, selectPet, mapPet)
+	target, err := finder("Noah")
+	require.NoError(t, err)
+	assert.Equal(t, test.Noah, target)
+	_, err = finder("Garfield")
+	assert.ErrorIs(t, err, pointer.ErrNoSuchTarget)
+	_, err = SQL(db, failPet, mapPet)("Noah")
+	assert.ErrorContains(t, err, "query Noah")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = SQLContext(db, selectPet, mapPet)(ctx, "Noah")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSetSQL(t *testing.T) {
+	db := openFake(t, test.Noah, test.Knight)
+	cache := pointer.NewCache()
+	require.NoError(t, SetSQL(cache, db, map[string]SQLGroup{
+		"cat": {Query: selectPet, Mapper: mapPet, Store: insertPet, Values: petValues},
+		"dog": {Query: selectPet, Mapper: mapPet},
+	}, false))
+	assert.True(t, cache.HasStorer("cat"))
+	assert.False(t, cache.HasStorer("dog"))
+
+	target, err := cache.GetTarget("cat", "Noah", nil)
+	require.NoError(t, err)
+	assert.Equal(t, test.Noah, target)
+	target, err = cache.GetTarget("dog", "Knight", nil)
+	require.NoError(t, err)
+	assert.Equal(t, test.Knight, target)
+
+	// Target items are written through to the database.
+	require.NoError(t, cache.SetTarget(test.Lacey, false))
+	fake.Lock()
+	assert.Equal(t, "cat", fake.pets["Lacey"])
+	fake.Unlock()
+	cache.ClearTargets()
+	target, err = cache.GetTarget("cat", "Lacey", nil)
+	require.NoError(t, err)
+	assert.Equal(t, test.Lacey, target)
+	assert.NotSame(t, test.Lacey, target)
+
+	require.NoError(t, cache.SetTarget(&test.Pet{Name: "Rex", Type: "dog"}, false))
+	fake.Lock()
+	assert.NotContains(t, fake.pets, "Rex")
+	fake.Unlock()
+
+	assert.ErrorIs(t, SetSQL(cache, db, map[string]SQLGroup{
+		"cat": {Query: selectPet, Mapper: mapPet},
+	}, false), pointer.ErrFinderAlreadyExists)
+	assert.ErrorIs(t, SetSQL(cache, db, map[string]SQLGroup{
+		"bird": {Query: selectPet, Mapper: mapPet, Store: insertPet},
+	}, false), ErrBadSQLGroup)
+}
+
+// TestSetSQL_Unchanged verifies that the Cache is not changed if any group can't be configured.
+func TestSetSQL_Unchanged(t *testing.T) {
+	db := openFake(t, test.Noah, test.Knight)
+	groups := map[string]SQLGroup{
+		"bird":  {Query: selectPet, Mapper: mapPet},
+		"cat":   {Query: selectPet, Mapper: mapPet, Store: insertPet, Values: petValues},
+		"dog":   {Query: selectPet, Mapper: mapPet},
+		"fish":  {Query: selectPet, Mapper: mapPet, Store: insertPet},
+		"horse": {Query: selectPet, Mapper: mapPet},
+	}
+	cache := pointer.NewCache()
+	assert.ErrorIs(t, SetSQL(cache, db, groups, false), ErrBadSQLGroup)
+	for group := range groups {
+		assert.False(t, cache.HasFinder(group), group)
+		assert.False(t, cache.HasStorer(group), group)
+	}
+
+	// Groups that already have a Finder or Storer are detected before any are configured.
+	delete(groups, "fish")
+	require.NoError(t, cache.SetStorer("cat", func(pointer.Target) error { return nil }, false))
+	assert.ErrorIs(t, SetSQL(cache, db, groups, false), pointer.ErrStorerAlreadyExists)
+	for _, group := range []string{"bird", "cat", "dog", "horse"} {
+		assert.False(t, cache.HasFinder(group), group)
+	}
+	finderCache := pointer.NewCache()
+	require.NoError(t, finderCache.SetFinder("horse", func(string) (pointer.Target, error) { return nil, nil }, false))
+	assert.ErrorIs(t, SetSQL(finderCache, db, groups, false), pointer.ErrFinderAlreadyExists)
+	for _, group := range []string{"bird", "cat", "dog"} {
+		assert.False(t, finderCache.HasFinder(group), group)
+	}
+
+	// Once the problem is fixed a retry succeeds.
+	require.NoError(t, SetSQL(cache, db, groups, true))
+	for group := range groups {
+		assert.True(t, cache.HasFinder(group), group)
+	}
+}
@@ -0,0 +1,61 @@
+package targets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/madkins23/go-serial/pointer"
+)
+
+var (
+	// ErrEmptyGroupField is returned when a serialized pointer has no group.
+	ErrEmptyGroupField = errors.New("empty group field")
+
+	// ErrEmptyKeyField is returned when a serialized pointer has no key.
+	ErrEmptyKeyField = errors.New("empty key field")
+)
+
+// FmtWrongTargetType formats the error for a Target of the wrong type for a pointer.
+const FmtWrongTargetType = "object '%v' not Target"
+
+// RememberKeyed adds the Target of a KeyedPointer being marshaled to the KeyedCache
+// if it isn't already there, without passing it to any pointer.Storer.
+func RememberKeyed[K comparable](cache *pointer.KeyedCache[K], target pointer.KeyedTarget[K]) error {
+	if cache.HasTarget(target.Group(), target.Key()) {
+		return nil
+	} else if err := cache.Remember(target, false); err != nil && !errors.Is(err, pointer.ErrTargetAlreadyExists) {
+		return fmt.Errorf("setting target in cache: %w", err)
+	}
+	return nil
+}
+
+// ResolveKeyed acquires the Target for the group and key of a decoded KeyedPointer
+// from the KeyedCache and passes it to the set function.
+// If the Batch is not nil the zero value is set and the Target is added to the Batch instead.
+func ResolveKeyed[K comparable, T pointer.KeyedTarget[K]](
+	ctx context.Context, cache *pointer.KeyedCache[K], batch *pointer.Batch, group string, key *K, set func(T),
+) error {
+	setTarget := func(target pointer.KeyedTarget[K]) error {
+		item, ok := target.(T)
+		if !ok {
+			return fmt.Errorf(FmtWrongTargetType, target)
+		}
+		set(item)
+		return nil
+	}
+
+	var zero T
+	if group == "" {
+		return ErrEmptyGroupField
+	} else if key == nil {
+		return ErrEmptyKeyField
+	} else if batch != nil {
+		set(zero)
+		return cache.AddToBatch(batch, group, *key, setTarget)
+	} else if target, err := cache.GetTarget(ctx, group, *key); err != nil {
+		return fmt.Errorf("get target: %w", err)
+	} else {
+		return setTarget(target)
+	}
+}
@@ -0,0 +1,105 @@
+// Package targets searches decoded values for pointer.Target items.
+// It is shared by the json and yaml packages, which provide access to the items
+// held in unexported fields of their Wrapper, Slice and Map types.
+package targets
+
+import (
+	"reflect"
+
+	"github.com/madkins23/go-serial/pointer"
+)
+
+// Holder returns the items held in unexported fields of the item (e.g. by a Wrapper)
+// and true if the item holds items, otherwise false.
+type Holder func(item interface{}) ([]interface{}, bool)
+
+var targetType = reflect.TypeOf((*pointer.Target)(nil)).Elem()
+
+// Collect calls the visit function for each pointer.Target found in the value.
+// Pointers, interfaces, exported struct fields, arrays, slices, map values,
+// and the items returned by the Holder are searched.
+// Items for which the Holder returns true are not otherwise searched.
+// Pointer objects are references to Target items rather than definitions
+// and hold their Target items in unexported fields so they are not searched.
+func Collect(v reflect.Value, visit func(pointer.Target), held Holder) {
+	c := &collector{visit: visit, held: held, seen: make(map[visited]bool)}
+	c.collect(v)
+}
+
+// visited identifies a value by type and address to avoid searching it more than once.
+type visited struct {
+	typ  reflect.Type
+	addr uintptr
+}
+
+type collector struct {
+	visit func(pointer.Target)
+	held  Holder
+	seen  map[visited]bool
+}
+
+func (c *collector) collect(v reflect.Value) {
+	if !v.IsValid() {
+		return
+	} else if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		key := visited{typ: v.Type(), addr: v.Pointer()}
+		if c.seen[key] {
+			return
+		}
+		c.seen[key] = true
+		if !c.inspect(v) {
+			c.collectElements(v.Elem())
+		}
+	} else if v.CanAddr() && v.Addr().CanInterface() {
+		// Search via the address so that methods with pointer receivers are found.
+		c.collect(v.Addr())
+	} else if !c.inspect(v) {
+		c.collectElements(v)
+	}
+}
+
+// inspect calls the visit function if the value is a pointer.Target
+// and searches the held items if the Holder returns any, in which case true is returned.
+func (c *collector) inspect(v reflect.Value) bool {
+	if !v.CanInterface() || v.Kind() == reflect.Interface {
+		// Interface values are inspected via their contents.
+		return false
+	}
+	if v.Type().Implements(targetType) {
+		c.visit(v.Interface().(pointer.Target))
+	}
+	if items, ok := c.held(v.Interface()); ok {
+		for _, item := range items {
+			c.collect(reflect.ValueOf(item))
+		}
+		return true
+	}
+	return false
+}
+
+// collectElements searches the elements of the value.
+func (c *collector) collectElements(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		c.collect(v)
+	case reflect.Interface:
+		c.collect(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				c.collect(v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			c.collect(v.Index(i))
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			c.collect(iter.Value())
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package targets
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/test"
+)
+
+// box holds an item in an unexported field as do the json and yaml wrappers.
+type box struct {
+	item interface{}
+}
+
+func boxed(item interface{}) ([]interface{}, bool) {
+	if b, ok := item.(*box); ok {
+		return []interface{}{b.item}, true
+	}
+	return nil, false
+}
+
+func TestCollect(t *testing.T) {
+	type node struct {
+		Pet      test.Pet
+		Pets     map[string]*test.Pet
+		Any      interface{}
+		Nil      *test.Pet
+		Box      *box
+		Array    [1]*test.Pet
+		Next     *node
+		unlisted *test.Pet
+	}
+	cat := &test.Pet{Name: "Tom", Type: "cat"}
+	root := &node{
+		Pet:      test.Pet{Name: "Rex", Type: "dog"},
+		Pets:     map[string]*test.Pet{"cat": cat, "nil": nil},
+		Any:      []interface{}{test.Orca, (*test.Pet)(nil)},
+		Box:      &box{item: test.Noah},
+		Array:    [1]*test.Pet{test.Knight},
+		unlisted: test.Lacey,
+	}
+	root.Next = &node{Any: root, Pets: map[string]*test.Pet{"cat": cat}}
+
+	var found []pointer.Target
+	Collect(reflect.ValueOf(root).Elem(), func(target pointer.Target) {
+		found = append(found, target)
+	}, boxed)
+	assert.ElementsMatch(t, []pointer.Target{
+		&root.Pet, cat, test.Orca, test.Noah, test.Knight, &root.Next.Pet,
+	}, found)
+
+	// Without the Holder the boxed item is not found.
+	found = nil
+	Collect(reflect.ValueOf(root.Box), func(target pointer.Target) {
+		found = append(found, target)
+	}, func(interface{}) ([]interface{}, bool) { return nil, false })
+	assert.Empty(t, found)
+}
@@ -0,0 +1,191 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/madkins23/go-serial/pointer"
+)
+
+// CheckReferences scans a JSON document for Pointer references and reports,
+// for each one in document order, its location and whether it can be resolved.
+// The document is not decoded into any Go value and the pointer.Cache is not changed
+// (see pointer.Cache.Check), so this may be used before importing a document
+// to find references to Target items that are missing.
+//
+// References are objects with the group and key fields (and the type and data fields
+// of embedded Target items, see WithEmbeddedTargets) generated for Pointer objects
+// and JSON References (see WithRefs).
+// Keys of KeyedPointer objects that aren't strings are checked in the string form
+// used by pointer.KeyedCache (see pointer.KeyString).
+// Local JSON References to values in the document are resolved in the document,
+// other JSON References are checked by the last two reference tokens as group and key.
+//
+// The pointer.Cache specified via WithCache (or the default Cache) is checked for Target items
+// and its Finder functions are called with the context specified via WithContext.
+func CheckReferences(data []byte, opts ...Option) (*pointer.CheckReport, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	s := &refScanner{document: data}
+	leading := len(data) - len(bytes.TrimLeft(data, " \t\r\n"))
+	if err := s.scan(raw, int64(leading), ""); err != nil {
+		return nil, err
+	}
+	return o.getCache().Check(o.getContext(), s.refs)
+}
+
+// refScanner collects references from a JSON document.
+type refScanner struct {
+	document []byte
+	lines    []int64
+	refs     []pointer.CheckedReference
+}
+
+// refFields are the fields of a serialized Pointer.
+var refFields = map[string]bool{"group": true, "key": true, "type": true, "data": true}
+
+// scanField is a field of a JSON object or an element of a JSON array
+// with the offset of its value within the document.
+type scanField struct {
+	name   string
+	value  json.RawMessage
+	offset int64
+}
+
+// scan collects references from the JSON value at the specified offset and path in the document.
+func (s *refScanner) scan(raw json.RawMessage, offset int64, path string) error {
+	if len(raw) < 1 {
+		return nil
+	}
+	var fields []scanField
+	var err error
+	switch raw[0] {
+	case '{':
+		if fields, err = s.fields(raw, offset, false); err != nil {
+			return err
+		} else if err = s.reference(fields, offset, path); err != nil {
+			return err
+		}
+	case '[':
+		if fields, err = s.fields(raw, offset, true); err != nil {
+			return err
+		}
+	}
+	for _, field := range fields {
+		if err = s.scan(field.value, field.offset, path+"/"+escapeToken(field.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fields returns the fields of the JSON object or elements of the JSON array in document order.
+func (s *refScanner) fields(raw json.RawMessage, offset int64, array bool) ([]scanField, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	var fields []scanField
+	for decoder.More() {
+		field := scanField{name: fmt.Sprint(len(fields))}
+		if !array {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			field.name = token.(string)
+		}
+		start := decoder.InputOffset()
+		if err := decoder.Decode(&field.value); err != nil {
+			return nil, err
+		}
+		// Skip the separator and white space before the value.
+		start += int64(len(raw[start:]) - len(bytes.TrimLeft(raw[start:], " \t\r\n,:")))
+		field.offset = offset + start
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// reference adds a reference for the JSON object with the specified fields if it is one.
+func (s *refScanner) reference(fields []scanField, offset int64, path string) error {
+	values := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if !refFields[field.name] && !(field.name == refField && len(fields) == 1) {
+			return nil
+		}
+		values[field.name] = field.value
+	}
+
+	ref := pointer.CheckedReference{Path: path}
+	ref.Line, ref.Column = s.position(offset)
+	if raw, found := values[refField]; found {
+		return s.jsonReference(ref, raw)
+	}
+	var group, typeName string
+	key := keyString(values["key"])
+	if json.Unmarshal(values["group"], &group) != nil || group == "" || key == "" {
+		return nil
+	}
+	ref.Group, ref.Key = group, key
+	ref.Embedded = json.Unmarshal(values["type"], &typeName) == nil && typeName != "" && values["data"] != nil
+	s.refs = append(s.refs, ref)
+	return nil
+}
+
+// keyString returns the string form of the key field of a reference or
+// the empty string if there is no key.
+// Keys of KeyedPointer objects that aren't strings (e.g. numbers or composite keys)
+// are returned in compact JSON form as by pointer.KeyString.
+func keyString(raw json.RawMessage) string {
+	var key string
+	if json.Unmarshal(raw, &key) == nil {
+		return key
+	}
+	var compact bytes.Buffer
+	if len(raw) == 0 || bytes.Equal(raw, jsonNull) || json.Compact(&compact, raw) != nil {
+		return ""
+	}
+	return compact.String()
+}
+
+// jsonReference adds a reference for a JSON Reference.
+func (s *refScanner) jsonReference(ref pointer.CheckedReference, raw json.RawMessage) error {
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return nil
+	}
+	tokens, local, err := parseRef(str)
+	if err != nil {
+		return fmt.Errorf("reference at %s: %w", ref.Path, err)
+	}
+	if _, found := evaluateRef(s.document, tokens); local && found {
+		ref.Resolution = pointer.InDocument
+	} else if len(tokens) < 2 {
+		return fmt.Errorf("reference at %s: %w: '%s'", ref.Path, errShortRef, str)
+	}
+	if len(tokens) > 1 {
+		ref.Group, ref.Key = tokens[len(tokens)-2], tokens[len(tokens)-1]
+	}
+	s.refs = append(s.refs, ref)
+	return nil
+}
+
+// position returns the line and column in the document for the offset, starting from 1.
+func (s *refScanner) position(offset int64) (int, int) {
+	if s.lines == nil {
+		s.lines = []int64{0}
+		for i, b := range s.document {
+			if b == '\n' {
+				s.lines = append(s.lines, int64(i+1))
+			}
+		}
+	}
+	line := sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset })
+	return line, int(offset-s.lines[line-1]) + 1
+}
@@ -0,0 +1,98 @@
+package json
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/test"
+)
+
+func TestCheckReferences(t *testing.T) {
+	document := []byte(`{
+  "Cats": [
+    {"group": "cat", "key": "Noah"},
+    {"group": "cat", "key": "Garfield"},
+    {"group": "cat", "key": "Tom", "type": "[test]Pet", "data": {"Name": "Tom", "Type": "cat"}},
+    {"group": "cat", "key": "Tom"},
+    {"$ref": "#/Pets/dog/Knight"},
+    {"$ref": "#/Pets/dog/Odie"}
+  ],
+  "Wrapped": {"type": "[test]Pet", "data": {"Name": "Rex", "Type": "dog"}},
+  "Other": {"group": "cat", "key": "Lacey", "Name": "not a pointer"},
+  "Pets": {"dog": {"Knight": {"Name": "Knight", "Type": "dog"}}},
+  "a/b": [[{"group": "cat", "key": "Lacey"}]]
+}`)
+	cache := pointer.NewCache()
+	require.NoError(t, cache.SetTarget(test.Noah, false))
+	require.NoError(t, cache.SetFinder("cat", func(key string) (pointer.Target, error) {
+		if key == "Lacey" {
+			return test.Lacey, nil
+		}
+		return nil, pointer.ErrNoSuchTarget
+	}, false))
+
+	report, err := CheckReferences(document, WithCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, []pointer.CheckedReference{
+		{Group: "cat", Key: "Noah", Path: "/Cats/0", Line: 3, Column: 5, Resolution: pointer.InCache},
+		{Group: "cat", Key: "Garfield", Path: "/Cats/1", Line: 4, Column: 5, Resolution: pointer.Dangling},
+		{Group: "cat", Key: "Tom", Path: "/Cats/2", Line: 5, Column: 5, Embedded: true, Resolution: pointer.InDocument},
+		{Group: "cat", Key: "Tom", Path: "/Cats/3", Line: 6, Column: 5, Resolution: pointer.InDocument},
+		{Group: "dog", Key: "Knight", Path: "/Cats/4", Line: 7, Column: 5, Resolution: pointer.InDocument},
+		{Group: "dog", Key: "Odie", Path: "/Cats/5", Line: 8, Column: 5, Resolution: pointer.Dangling},
+		{Group: "cat", Key: "Lacey", Path: "/a~1b/0/0", Line: 13, Column: 12, Resolution: pointer.ByFinder},
+	}, report.References)
+	assert.Len(t, report.Dangling(), 2)
+
+	// The Cache is not changed.
+	assert.Equal(t, 1, cache.Len())
+	assert.False(t, cache.HasTarget("cat", "Tom"))
+
+	// Documents generated by MarshalWith are checked.
+	marshaled, err := MarshalWith(makeAnimals(), WithCache(pointer.NewCache()))
+	require.NoError(t, err)
+	report, err = CheckReferences(marshaled, WithCache(cache))
+	require.NoError(t, err)
+	assert.Len(t, report.References, 4)
+	assert.Equal(t, "/Cats/0", report.References[0].Path)
+	assert.Equal(t, 1, report.References[0].Line)
+	assert.Equal(t, 10, report.References[0].Column)
+	assert.Equal(t, "/Dog", report.References[3].Path)
+
+	_, err = CheckReferences([]byte(`{"Cats": [`))
+	assert.Error(t, err)
+	_, err = CheckReferences([]byte(`{"$ref": "#/x"}`))
+	assert.ErrorIs(t, err, errShortRef)
+}
+
+// TestCheckReferences_Keyed verifies that references with keys that aren't strings
+// are checked using the string form of the keys.
+func TestCheckReferences_Keyed(t *testing.T) {
+	window := &test.Seat{Number: test.SeatKey{Row: 3, Letter: "A"}, Window: true}
+	marshaled, err := MarshalWith(&boarding{
+		Passenger: PointKeyed[int](&test.Passenger{ID: 42, Name: "Lacey"}),
+		Seat:      *PointKeyed[test.SeatKey](window),
+	}, WithCache(pointer.NewCache()))
+	require.NoError(t, err)
+
+	cache := pointer.NewCache()
+	require.NoError(t, pointer.NewKeyedCache[test.SeatKey](cache).SetTarget(window, false))
+	report, err := CheckReferences(marshaled, WithCache(cache))
+	require.NoError(t, err)
+	require.Len(t, report.References, 2)
+	assert.Equal(t, pointer.CheckedReference{
+		Group: "passenger", Key: "42", Path: "/Passenger", Line: 1, Column: 14, Resolution: pointer.Dangling,
+	}, report.References[0])
+	assert.Equal(t, `{"Row":3,"Letter":"A"}`, report.References[1].Key)
+	assert.Equal(t, pointer.InCache, report.References[1].Resolution)
+
+	// White space in composite keys is ignored.
+	report, err = CheckReferences([]byte(`[{"group": "seat", "key": {"Row": 3, "Letter": "A"}}]`), WithCache(cache))
+	require.NoError(t, err)
+	require.Len(t, report.References, 1)
+	assert.Equal(t, pointer.InCache, report.References[0].Resolution)
+	assert.Empty(t, report.Dangling())
+}
@@ -0,0 +1,283 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+// WrapSlice wraps a slice of items in a JSON wrapper that can handle serialization.
+func WrapSlice[T any](items []T) *Slice[T] {
+	s := new(Slice[T])
+	s.Set(items)
+	return s
+}
+
+// Slice is used to serialize a slice of interface items.
+// Each item is serialized within its own type/data envelope as with Wrapper:
+//
+//	[{"type": "[test]Stock", "data": {...}}, {"type": "[test]Bond", "data": {...}}]
+//
+// Items may be in either the packed or the inline form when deserialized.
+// A nil slice is serialized as JSON null and a nil item as a JSON null element.
+// Type names are provided and items created as with Wrapper.
+type Slice[T any] struct {
+	items    []T
+	inline   bool
+	registry wrapper.Registry
+}
+
+// Get the wrapped items.
+func (s *Slice[T]) Get() []T {
+	return s.items
+}
+
+// Set the wrapped items.
+func (s *Slice[T]) Set(items []T) {
+	s.items = items
+}
+
+// IsZero returns true if the slice wrapper is nil or has no items.
+// This supports the omitzero field tag option and
+// the omitempty field tag option when using Marshal.
+func (s *Slice[T]) IsZero() bool {
+	return s == nil || len(s.items) == 0
+}
+
+// Inline returns true if the slice wrapper serializes items in inline mode.
+func (s *Slice[T]) Inline() bool {
+	return s.inline
+}
+
+// SetInline configures whether the slice wrapper serializes items in inline mode.
+func (s *Slice[T]) SetInline(inline bool) {
+	s.inline = inline
+}
+
+// Registry returns the Registry specific to the slice wrapper, if any.
+func (s *Slice[T]) Registry() wrapper.Registry {
+	return s.registry
+}
+
+// SetRegistry specifies a Registry for all items in the slice wrapper.
+// See Wrapper.SetRegistry.
+func (s *Slice[T]) SetRegistry(registry wrapper.Registry) {
+	s.registry = registry
+}
+
+func (s *Slice[T]) MarshalJSON() ([]byte, error) {
+	return s.marshalWith(nil)
+}
+
+func (s *Slice[T]) marshalWith(opts *options) ([]byte, error) {
+	if s == nil || s.items == nil {
+		return jsonNull, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, item := range s.items {
+		w := &Wrapper[T]{item: item, inline: s.inline, registry: s.registry}
+		marshaled, err := w.marshalWith(opts)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(marshaled)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+func (s *Slice[T]) UnmarshalJSON(marshaled []byte) error {
+	return s.unmarshalWith(nil, marshaled)
+}
+
+func (s *Slice[T]) unmarshalWith(opts *options, marshaled []byte) error {
+	if bytes.Equal(bytes.TrimSpace(marshaled), jsonNull) {
+		s.items = nil
+		return nil
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(marshaled, &raw); err != nil {
+		return fmt.Errorf("unmarshal slice: %w", err)
+	}
+	items := make([]T, len(raw))
+	for i, data := range raw {
+		w := &Wrapper[T]{registry: s.registry}
+		if err := w.unmarshalWith(opts, data); err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+		items[i] = w.item
+	}
+	s.items = items
+	return nil
+}
+
+// heldItems returns the wrapped items for use by collectTargets.
+func (s *Slice[T]) heldItems() []interface{} {
+	items := make([]interface{}, len(s.items))
+	for i, item := range s.items {
+		items[i] = item
+	}
+	return items
+}
+
+// -----------------------------------------------------------------------
+
+// WrapMap wraps a map of items in a JSON wrapper that can handle serialization.
+func WrapMap[K comparable, T any](items map[K]T) *Map[K, T] {
+	m := new(Map[K, T])
+	m.Set(items)
+	return m
+}
+
+// Map is used to serialize a map of interface items.
+// Each item is serialized within its own type/data envelope as with Wrapper:
+//
+//	{"alpha": {"type": "[test]Stock", "data": {...}}, "bravo": {"type": "[test]Bond", "data": {...}}}
+//
+// Items may be in either the packed or the inline form when deserialized.
+// Keys follow the encoding/json rules for map keys:
+// strings, integers, and types that implement encoding.TextMarshaler.
+// Entries are serialized in key order.
+// A nil map is serialized as JSON null and a nil item as a JSON null value.
+// Type names are provided and items created as with Wrapper.
+type Map[K comparable, T any] struct {
+	items    map[K]T
+	inline   bool
+	registry wrapper.Registry
+}
+
+// Get the wrapped items.
+func (m *Map[K, T]) Get() map[K]T {
+	return m.items
+}
+
+// Set the wrapped items.
+func (m *Map[K, T]) Set(items map[K]T) {
+	m.items = items
+}
+
+// IsZero returns true if the map wrapper is nil or has no items.
+// This supports the omitzero field tag option and
+// the omitempty field tag option when using Marshal.
+func (m *Map[K, T]) IsZero() bool {
+	return m == nil || len(m.items) == 0
+}
+
+// Inline returns true if the map wrapper serializes items in inline mode.
+func (m *Map[K, T]) Inline() bool {
+	return m.inline
+}
+
+// SetInline configures whether the map wrapper serializes items in inline mode.
+func (m *Map[K, T]) SetInline(inline bool) {
+	m.inline = inline
+}
+
+// Registry returns the Registry specific to the map wrapper, if any.
+func (m *Map[K, T]) Registry() wrapper.Registry {
+	return m.registry
+}
+
+// SetRegistry specifies a Registry for all items in the map wrapper.
+// See Wrapper.SetRegistry.
+func (m *Map[K, T]) SetRegistry(registry wrapper.Registry) {
+	m.registry = registry
+}
+
+func (m *Map[K, T]) MarshalJSON() ([]byte, error) {
+	return m.marshalWith(nil)
+}
+
+func (m *Map[K, T]) marshalWith(opts *options) ([]byte, error) {
+	if m == nil || m.items == nil {
+		return jsonNull, nil
+	}
+
+	type entry struct {
+		key  string
+		item T
+	}
+	entries := make([]entry, 0, len(m.items))
+	for key, item := range m.items {
+		keyString, err := mapKeyString(reflect.ValueOf(key))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: keyString, item: item})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, ent := range entries {
+		w := &Wrapper[T]{item: ent.item, inline: m.inline, registry: m.registry}
+		marshaled, err := w.marshalWith(opts)
+		if err != nil {
+			return nil, fmt.Errorf("map key %s: %w", ent.key, err)
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeKey(&buf, ent.key)
+		buf.Write(marshaled)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (m *Map[K, T]) UnmarshalJSON(marshaled []byte) error {
+	return m.unmarshalWith(nil, marshaled)
+}
+
+func (m *Map[K, T]) unmarshalWith(opts *options, marshaled []byte) error {
+	if bytes.Equal(bytes.TrimSpace(marshaled), jsonNull) {
+		m.items = nil
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(marshaled, &raw); err != nil {
+		return fmt.Errorf("unmarshal map: %w", err)
+	}
+	// Decode in the same order as marshalWith so that embedded Target items precede references.
+	keyStrings := make([]string, 0, len(raw))
+	for keyString := range raw {
+		keyStrings = append(keyStrings, keyString)
+	}
+	sort.Strings(keyStrings)
+	keyType := reflect.TypeOf((*K)(nil)).Elem()
+	items := make(map[K]T, len(raw))
+	for _, keyString := range keyStrings {
+		data := raw[keyString]
+		key, err := mapKeyValue(keyString, keyType)
+		if err != nil {
+			return err
+		}
+		w := &Wrapper[T]{registry: m.registry}
+		if err = w.unmarshalWith(opts, data); err != nil {
+			return fmt.Errorf("map key %s: %w", keyString, err)
+		}
+		items[key.Interface().(K)] = w.item
+	}
+	m.items = items
+	return nil
+}
+
+// heldItems returns the wrapped items for use by collectTargets.
+func (m *Map[K, T]) heldItems() []interface{} {
+	items := make([]interface{}, 0, len(m.items))
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,144 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/madkins23/go-type/reg"
+
+	"github.com/madkins23/go-serial/test"
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+type JsonCollectionTestSuite struct {
+	suite.Suite
+	showSerialized bool
+}
+
+func (suite *JsonCollectionTestSuite) SetupSuite() {
+	if showSerialized, found := os.LookupEnv("GO-TYPE-SHOW-SERIALIZED"); found {
+		var err error
+		suite.showSerialized, err = strconv.ParseBool(showSerialized)
+		suite.Require().NoError(err)
+	}
+	reg.Singleton().Clear()
+	suite.Require().NoError(reg.AddAlias("json", Bond{}), "creating json test alias")
+	suite.Require().NoError(test.Register())
+	suite.Require().NoError(reg.Register(Bond{}))
+}
+
+func TestJsonCollectionSuite(t *testing.T) {
+	suite.Run(t, new(JsonCollectionTestSuite))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+func (suite *JsonCollectionTestSuite) TestCollections() {
+	holdings := MakeHoldings()
+	marshaled, err := json.Marshal(holdings)
+	suite.Require().NoError(err)
+	if suite.showSerialized {
+		fmt.Println(string(marshaled))
+	}
+	suite.Assert().Contains(string(marshaled), `"Positions":[{"type":"[test]Stock","data":{"Market":"NASDAQ"`)
+	suite.Assert().Contains(string(marshaled), `{"type":"[json]Bond","data":{`)
+	suite.Assert().Contains(string(marshaled), `"Lenders":{"federal":{"type":"[test]Federal","data":{}},`)
+	suite.Assert().Contains(string(marshaled), `"Ranked":{"1":{"type":"[test]Stock"`)
+
+	newHoldings := new(Holdings)
+	suite.Require().NoError(json.Unmarshal(marshaled, newHoldings))
+	suite.Assert().Equal(holdings, newHoldings)
+	suite.Assert().Equal(test.StockWalmartName, newHoldings.Ranked.Get()[2].Name())
+}
+
+func (suite *JsonCollectionTestSuite) TestCollections_Inline() {
+	positions := WrapSlice([]test.Investment{test.MakeCostco(), nil})
+	positions.SetInline(true)
+	marshaled, err := json.Marshal(positions)
+	suite.Require().NoError(err)
+	suite.Assert().Regexp(`^\[{"type":"\[test\]Stock","Market":"NASDAQ",.*},null\]$`, string(marshaled))
+	newPositions := new(Slice[test.Investment])
+	suite.Require().NoError(json.Unmarshal(marshaled, newPositions))
+	suite.Assert().Equal(positions.Get(), newPositions.Get())
+
+	lenders := WrapMap(map[string]test.Borrower{"state": test.StateBondSource()})
+	lenders.SetInline(true)
+	marshaled, err = json.Marshal(lenders)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`{"state":{"type":"[test]State","State":"Confusion"}}`, string(marshaled))
+	newLenders := new(Map[string, test.Borrower])
+	suite.Require().NoError(json.Unmarshal(marshaled, newLenders))
+	suite.Assert().Equal(lenders.Get(), newLenders.Get())
+}
+
+func (suite *JsonCollectionTestSuite) TestCollections_Nil() {
+	holdings := &Holdings{}
+	marshaled, err := Marshal(holdings)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`{"Positions":null,"Lenders":null}`, string(marshaled))
+	newHoldings := MakeHoldings()
+	suite.Require().NoError(Unmarshal(marshaled, newHoldings))
+	suite.Assert().Nil(newHoldings.Positions)
+	suite.Assert().Nil(newHoldings.Lenders)
+
+	holdings = &Holdings{
+		Positions: WrapSlice[test.Investment](nil),
+		Lenders:   WrapMap[string, test.Borrower](nil),
+		Ranked:    WrapMap(map[int]test.Investment{}),
+	}
+	marshaled, err = Marshal(holdings)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`{"Positions":null,"Lenders":null}`, string(marshaled))
+}
+
+func (suite *JsonCollectionTestSuite) TestCollections_Registry() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("solo", &test.Stock{}))
+	suite.Require().NoError(registry.Register(&test.Stock{}))
+	positions := WrapSlice([]test.Investment{test.MakeWalmart()})
+	marshaled, err := MarshalWith(&struct{ Positions *Slice[test.Investment] }{positions}, WithRegistry(registry))
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "[solo]Stock")
+	positions.SetRegistry(wrapper.DefaultRegistry())
+	marshaled, err = json.Marshal(positions)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "[test]Stock")
+}
+
+func (suite *JsonCollectionTestSuite) TestCollections_Errors() {
+	var decodeErr *wrapper.DecodeError
+	suite.Assert().ErrorAs(json.Unmarshal([]byte(`[{"data":{}}]`), new(Slice[test.Investment])), &decodeErr)
+	suite.Assert().ErrorAs(json.Unmarshal([]byte(`{"x":{"type":"[test]Federal","data":{}}}`),
+		new(Map[string, test.Investment])), &decodeErr)
+	suite.Assert().Equal(wrapper.PhaseTypeAssert, decodeErr.Phase)
+	suite.Assert().ErrorIs(json.Unmarshal([]byte(`{"x":null}`), new(Map[int, test.Investment])), errMapKey)
+	suite.Assert().Error(json.Unmarshal([]byte(`{}`), new(Slice[test.Investment])))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// Holdings has collections of interface items without custom serialization code.
+type Holdings struct {
+	Positions *Slice[test.Investment]
+	Lenders   *Map[string, test.Borrower]
+	Ranked    *Map[int, test.Investment] `json:",omitempty"`
+}
+
+func MakeHoldings() *Holdings {
+	return &Holdings{
+		Positions: WrapSlice([]test.Investment{test.MakeCostco(), test.MakeWalmart(), MakeTBill()}),
+		Lenders: WrapMap(map[string]test.Borrower{
+			"federal": test.TBillSource(),
+			"state":   test.StateBondSource(),
+		}),
+		Ranked: WrapMap(map[int]test.Investment{
+			1: test.MakeCostco(),
+			2: test.MakeWalmart(),
+		}),
+	}
+}
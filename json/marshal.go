@@ -0,0 +1,1203 @@
+package json
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+// Marshal returns the JSON encoding of v.
+//
+// Interface values (struct fields, slice and array elements, map values)
+// are automatically serialized within the same type/data envelope used by Wrapper.
+// This removes the need for shadow structures using Wrapper fields and
+// custom MarshalJSON methods to copy data into them.
+// The types of all interface values must be known to the Registry
+// returned by wrapper.Lookup for the interface type,
+// normally a Factory for the interface or the go-type/reg singleton.
+//
+// Plain data held in empty interfaces (nil, booleans, numbers, strings,
+// map[string]interface{} and []interface{}) is serialized without the envelope
+// as it would be by encoding/json.
+// A map[string]interface{} with only type and data entries would be read as the envelope
+// so it is rejected.
+//
+// Types that implement json.Marshaler or encoding.TextMarshaler
+// and types that contain no interface values are serialized using encoding/json.
+// Struct fields follow the encoding/json field naming and tag conventions.
+func Marshal(v interface{}) ([]byte, error) {
+	return MarshalWith(v)
+}
+
+// MarshalWith returns the JSON encoding of v as does Marshal,
+// configured by the specified Option values (e.g. WithRegistry).
+// With no Option values this is the same as calling Marshal.
+func MarshalWith(v interface{}, opts ...Option) ([]byte, error) {
+	e := &encoder{opts: newOptions(opts)}
+	return e.encode(reflect.ValueOf(v))
+}
+
+// Unmarshal parses the JSON-encoded data and stores the result in the value pointed to by v.
+//
+// Interface values are read from the type/data envelope generated by Marshal,
+// instantiated via the Registry returned by wrapper.Lookup
+// and then filled from the envelope data.
+// Other values for empty interfaces are read as by encoding/json
+// with any envelopes within them read as above.
+// The inline form generated by Wrapper in inline mode is also accepted.
+// Failures to unwrap interface values are returned as *wrapper.DecodeError.
+//
+// Types that implement json.Unmarshaler or encoding.TextUnmarshaler
+// and types that contain no interface values are deserialized using encoding/json.
+func Unmarshal(data []byte, v interface{}) error {
+	return UnmarshalWith(data, v)
+}
+
+// UnmarshalWith parses the JSON-encoded data as does Unmarshal,
+// configured by the specified Option values (e.g. WithRegistry).
+// With no Option values this is the same as calling Unmarshal.
+func UnmarshalWith(data []byte, v interface{}, opts ...Option) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return fmt.Errorf("%w: %T", errNotPointer, v)
+	}
+	d := &decoder{opts: newOptions(opts)}
+	d.opts.document = data
+	if err := d.decode(data, value.Elem()); err != nil {
+		return err
+	}
+	return d.opts.resolve(value.Elem())
+}
+
+var (
+	errNotPointer         = errors.New("unmarshal target not a non-nil pointer")
+	errMapKey             = errors.New("unsupported map key")
+	errUnexportedEmbedded = errors.New("cannot set embedded pointer to unexported struct")
+	errQuoted             = errors.New("invalid use of ,string struct tag")
+	errAmbiguousMap       = errors.New("map with only type and data entries in empty interface")
+)
+
+//////////////////////////////////////////////////////////////////////////
+
+// Option configures a single MarshalWith or UnmarshalWith call.
+type Option func(*options)
+
+// WithRegistry specifies a Registry for all interface values and Wrapper objects
+// that don't have their own Registry.
+// A nil Registry is the same as not specifying one.
+func WithRegistry(registry wrapper.Registry) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithCache specifies a pointer.Cache for all Pointer objects
+// that don't have their own Cache.
+// A nil Cache is the same as not specifying one.
+func WithCache(cache *pointer.Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithContext specifies a context to be passed to any pointer.ContextFinder
+// called while unmarshaling Pointer objects.
+// When the context is done, unmarshaling Pointer objects with Target items
+// not already in the Cache will fail with the context error.
+// A nil context is the same as not specifying one.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithBatch defers acquiring the Target items for Pointer objects while unmarshaling
+// until the entire document has been decoded.
+// The Target items are then acquired with a single pointer.Cache.GetTargets call per group,
+// using the pointer.BatchFinder for the group if there is one.
+// This avoids calling the Finder for the group once for each Pointer.
+//
+// Pointer objects must not be copied during decoding (e.g. by Factory methods
+// that return value types) or the copies will not receive their Target items.
+// Pointer objects that are map values are handled.
+func WithBatch() Option {
+	return func(o *options) {
+		if o.batch == nil {
+			o.batch = new(pointer.Batch)
+		}
+	}
+}
+
+// WithEmbeddedTargets causes the first Pointer to each Target item during a MarshalWith call
+// to be serialized with the Target item embedded in the type/data envelope used by Wrapper:
+//
+//	{"group": "dog", "key": "Knight", "type": "[test]Pet", "data": {"Name": "Knight", ...}}
+//
+// Later Pointer objects to the same Target item are serialized as references.
+// The result can be unmarshaled where the pointer.Cache doesn't have the Target items,
+// as each embedded Target item is added to the Cache before any references to it are decoded.
+// The types of embedded Target items must be known to the Registry (see WithRegistry).
+func WithEmbeddedTargets() Option {
+	return func(o *options) {
+		o.embedded = make(map[string]map[string]bool)
+	}
+}
+
+// WithRefs causes Pointer objects to be serialized during a MarshalWith call
+// as JSON References understood by JSON Schema and OpenAPI tools:
+//
+//	{"$ref": "#/pets/dog/Knight"}
+//
+// The reference is the base followed by the group and key of the Target item
+// as RFC 6901 reference tokens.
+// A base containing a '#' (e.g. "#/pets" or "https://example.com/pets.json#")
+// generates URI fragment references, other bases (e.g. "/pets") generate JSON pointers.
+// An empty base is the same as "#".
+// Pointer objects with Target items embedded via WithEmbeddedTargets are not affected.
+//
+// JSON References are always accepted when unmarshaling Pointer objects.
+// When using UnmarshalWith, a reference local to the document (e.g. "#/pets/dog/Knight")
+// is first evaluated against the document being decoded.
+// If there is a value at that location it is decoded as the Target item,
+// using the type/data envelope if present.
+// Otherwise, and for references to other documents, the last two reference tokens
+// are used as the group and key of the Target item.
+//
+// References are never resolved to copies: all Pointer objects with the same
+// group and key share a single Target item after unmarshaling, as they would
+// with the packed form. A local reference is decoded once per UnmarshalWith call
+// and the Target item is added to the pointer.Cache (or replaced by the one already there),
+// so it is not the same instance as the value decoded into the referenced location
+// unless WithForwardReferences is also used.
+func WithRefs(base string) Option {
+	return func(o *options) {
+		if base == "" {
+			base = "#"
+		}
+		o.refBase = base
+	}
+}
+
+// WithForwardReferences allows Pointer objects to refer to Target items
+// that are defined later in the document being unmarshaled.
+// Acquiring Target items is deferred as with WithBatch until the entire document is decoded.
+// All Target items found in the decoded document are then added to the pointer.Cache
+// and used in preference to any other Target items with the same group and key.
+// Only Pointer objects referring to Target items neither in the document nor
+// available from the Cache return errors.
+//
+// Target items are found in exported struct fields, slices, arrays, map values,
+// interface values, and the items of Wrapper, Slice and Map objects.
+func WithForwardReferences() Option {
+	return func(o *options) {
+		if o.batch == nil {
+			o.batch = new(pointer.Batch)
+		}
+		o.forward = true
+	}
+}
+
+// WithDangling specifies how Pointer objects referring to Target items that can't be found
+// (neither in the pointer.Cache nor via a Finder) are handled while unmarshaling.
+// By default (pointer.DanglingFail) unmarshaling fails at the first such Pointer.
+// With pointer.DanglingKeep each such Pointer is left without a Target item
+// but remembers the group and key so that it is marshaled as it was read.
+// With pointer.DanglingPlaceholder each such Pointer is set to the Target item
+// returned by the pointer.Placeholder for the group (see pointer.Cache.SetPlaceholder).
+// With pointer.DanglingReport each such Pointer is handled as with pointer.DanglingKeep
+// and after the entire document is decoded a *pointer.DanglingError listing
+// all such Pointer references is returned.
+func WithDangling(policy pointer.DanglingPolicy) Option {
+	return func(o *options) {
+		o.dangling = policy
+	}
+}
+
+// WithLazy causes all Pointer objects to be unmarshaled lazily,
+// recording the group and key of each Target item without acquiring it.
+// The Target item is acquired when first requested via Pointer.Get or Pointer.Resolve.
+// This takes precedence over WithBatch.
+func WithLazy() Option {
+	return func(o *options) {
+		o.lazy = true
+	}
+}
+
+// options holds configuration for a single Marshal or Unmarshal call.
+type options struct {
+	registry wrapper.Registry
+	cache    *pointer.Cache
+	ctx      context.Context
+	batch    *pointer.Batch
+	lazy     bool
+	forward  bool
+	embedded map[string]map[string]bool
+	dangling pointer.DanglingPolicy
+	dangled  []pointer.DanglingReference
+	refBase  string
+
+	// document is the data being unmarshaled, for evaluating local JSON References.
+	document   []byte
+	localRefs  map[string]pointer.Target
+	refTargets []pointer.Target
+}
+
+func newOptions(opts []Option) *options {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// getRegistry returns the configured Registry, if any.
+// A nil options pointer is acceptable.
+func (o *options) getRegistry() wrapper.Registry {
+	if o == nil {
+		return nil
+	}
+	return o.registry
+}
+
+// getCache returns the configured pointer.Cache or the default Cache.
+// A nil options pointer is acceptable.
+func (o *options) getCache() *pointer.Cache {
+	if o == nil || o.cache == nil {
+		return pointer.DefaultCache()
+	}
+	return o.cache
+}
+
+// getContext returns the configured context or context.Background().
+// A nil options pointer is acceptable.
+func (o *options) getContext() context.Context {
+	if o == nil || o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// getBatch returns the configured pointer.Batch, if any.
+// A nil options pointer is acceptable.
+func (o *options) getBatch() *pointer.Batch {
+	if o == nil {
+		return nil
+	}
+	return o.batch
+}
+
+// embed returns true if the Target item with the specified group and key is to be embedded,
+// which is the first time it is requested when using WithEmbeddedTargets.
+// A nil options pointer is acceptable.
+func (o *options) embed(group, key string) bool {
+	if o == nil || o.embedded == nil || o.embedded[group][key] {
+		return false
+	}
+	if o.embedded[group] == nil {
+		o.embedded[group] = make(map[string]bool)
+	}
+	o.embedded[group][key] = true
+	return true
+}
+
+// ref returns a JSON Reference for the Target item with the specified group and key
+// or the empty string if Pointer objects are not to be serialized as references.
+// A nil options pointer is acceptable.
+func (o *options) ref(group, key string) string {
+	if o == nil || o.refBase == "" {
+		return ""
+	}
+	return formatRef(o.refBase, group, key)
+}
+
+// localRef returns the value in the document being unmarshaled referred to by
+// the reference tokens, if any.
+// A nil options pointer is acceptable.
+func (o *options) localRef(tokens []string) (json.RawMessage, bool) {
+	if o == nil || o.document == nil {
+		return nil, false
+	}
+	return evaluateRef(o.document, tokens)
+}
+
+// getLocalRef returns the Target item previously decoded for the local JSON Reference, if any.
+// A nil options pointer is acceptable.
+func (o *options) getLocalRef(ref string) pointer.Target {
+	if o == nil {
+		return nil
+	}
+	return o.localRefs[ref]
+}
+
+// setLocalRef records the Target item decoded for the local JSON Reference.
+// A nil options pointer is acceptable.
+func (o *options) setLocalRef(ref string, target pointer.Target) {
+	if o == nil {
+		return
+	}
+	if o.localRefs == nil {
+		o.localRefs = make(map[string]pointer.Target)
+	}
+	o.localRefs[ref] = target
+	o.refTargets = append(o.refTargets, target)
+}
+
+// getForward returns true if Pointer objects may refer to Target items defined later in the document.
+// A nil options pointer is acceptable.
+func (o *options) getForward() bool {
+	return o != nil && o.forward
+}
+
+// getDangling returns the configured pointer.DanglingPolicy.
+// A nil options pointer is acceptable.
+func (o *options) getDangling() pointer.DanglingPolicy {
+	if o == nil {
+		return pointer.DanglingFail
+	}
+	return o.dangling
+}
+
+// dangle handles a reference to a Target item that can't be found
+// according to the configured pointer.DanglingPolicy.
+// The result is a placeholder Target item, nil if the reference is to be left unresolved,
+// or pointer.ErrNoSuchTarget if unmarshaling is to fail.
+// A nil options pointer is acceptable.
+func (o *options) dangle(cache *pointer.Cache, group, key string) (pointer.Target, error) {
+	switch o.getDangling() {
+	case pointer.DanglingKeep:
+		return nil, nil
+	case pointer.DanglingReport:
+		o.dangled = append(o.dangled, pointer.DanglingReference{Group: group, Key: key})
+		return nil, nil
+	case pointer.DanglingPlaceholder:
+		if placeholder := cache.GetPlaceholder(group); placeholder != nil {
+			if target := placeholder(key); target != nil {
+				return target, nil
+			}
+		}
+	}
+	return nil, pointer.ErrNoSuchTarget
+}
+
+// getLazy returns true if Pointer objects are to be unmarshaled lazily.
+// A nil options pointer is acceptable.
+func (o *options) getLazy() bool {
+	return o != nil && o.lazy
+}
+
+// resolve acquires the Target items for any Pointer objects in the configured pointer.Batch.
+// With forward references the Target items in the decoded value are defined first.
+func (o *options) resolve(decoded reflect.Value) error {
+	if batch := o.getBatch(); batch != nil {
+		if err := o.resolveBatch(batch, decoded); err != nil {
+			return err
+		}
+	}
+	if len(o.dangled) > 0 {
+		return &pointer.DanglingError{References: o.dangled}
+	}
+	return nil
+}
+
+// resolveBatch acquires the Target items for the Pointer objects in the pointer.Batch.
+func (o *options) resolveBatch(batch *pointer.Batch, decoded reflect.Value) error {
+	if o.dangling != pointer.DanglingFail {
+		batch.SetMissing(o.dangle)
+	}
+	if o.forward {
+		collectTargets(decoded, func(target pointer.Target) {
+			batch.Define(o.getCache(), target)
+		})
+		// Target items decoded for local JSON References are only used
+		// if not found in the decoded value.
+		for _, target := range o.refTargets {
+			batch.Define(o.getCache(), target)
+		}
+	}
+	return batch.Resolve(o.getContext())
+}
+
+// optionsUser is implemented by types (e.g. Wrapper and Pointer) that make use of
+// the options passed down through Marshal and Unmarshal.
+type optionsUser interface {
+	marshalWith(opts *options) ([]byte, error)
+	unmarshalWith(opts *options, marshaled []byte) error
+}
+
+var (
+	jsonNull = []byte("null")
+
+	anyMapType   = reflect.TypeOf(map[string]interface{}{})
+	anySliceType = reflect.TypeOf([]interface{}{})
+
+	optionsUserType     = reflect.TypeOf((*optionsUser)(nil)).Elem()
+	marshalerType       = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// customized returns true if the type or a pointer to the type
+// provides its own JSON serialization.
+func customized(t reflect.Type) bool {
+	for _, custom := range []reflect.Type{
+		marshalerType, unmarshalerType, textMarshalerType, textUnmarshalerType,
+	} {
+		if t.Implements(custom) || reflect.PointerTo(t).Implements(custom) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesOptions returns true if a pointer to the type implements optionsUser.
+func usesOptions(t reflect.Type) bool {
+	return t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(optionsUserType)
+}
+
+// walkCache holds types known to require walking.
+// Types not requiring walking are only stored after a complete search.
+var walkCache sync.Map
+
+// needsWalk returns true if the type contains interface values
+// that can't be serialized by encoding/json without help
+// or values that make use of the options.
+func needsWalk(t reflect.Type) bool {
+	result, _ := walkable(t, make(map[reflect.Type]bool))
+	return result
+}
+
+// walkable returns true if the type requires the walker.
+// The seen map holds the types currently being checked.
+// The second result is true if a recursive type was found so that a false result is not definitive.
+func walkable(t reflect.Type, seen map[reflect.Type]bool) (bool, bool) {
+	if cached, found := walkCache.Load(t); found {
+		return cached.(bool), false
+	} else if seen[t] {
+		// Recursive type, the rest of the type will determine the result.
+		return false, true
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var result, recursive bool
+	if usesOptions(t) || (t.Kind() == reflect.Pointer && usesOptions(t.Elem())) {
+		// Checked before customized as these types also implement the standard interfaces.
+		result = true
+	} else if !customized(t) {
+		switch t.Kind() {
+		case reflect.Interface:
+			result = true
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			result, recursive = walkable(t.Elem(), seen)
+		case reflect.Struct:
+			for _, field := range fieldsOf(t) {
+				var r bool
+				if result, r = walkable(field.typ, seen); result {
+					break
+				}
+				recursive = recursive || r
+			}
+		}
+	}
+
+	// A false result that depends on a recursive type is only definitive at the top of the search.
+	if result || !recursive || len(seen) == 1 {
+		walkCache.Store(t, result)
+		recursive = false
+	}
+	return result, recursive
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// field describes a serializable struct field.
+type field struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	quoted    bool
+	tagged    bool
+}
+
+var fieldCache sync.Map
+
+// fieldsOf returns the serializable fields for the specified struct type
+// following the encoding/json rules for names, tags and embedded structs.
+func fieldsOf(t reflect.Type) []field {
+	if cached, found := fieldCache.Load(t); found {
+		return cached.([]field)
+	}
+
+	var fields []field
+	byName := make(map[string]int)
+	depthOf := make(map[string]int)
+	var collect func(t reflect.Type, index []int, depth int)
+	collect = func(t reflect.Type, index []int, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			fieldIndex := append(append([]int{}, index...), i)
+			if sf.Anonymous && name == "" {
+				embedded := sf.Type
+				if embedded.Kind() == reflect.Pointer {
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct {
+					collect(embedded, fieldIndex, depth+1)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			f := field{
+				name:      name,
+				index:     fieldIndex,
+				typ:       sf.Type,
+				omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+				quoted:    strings.Contains(","+options+",", ",string,") && quotable(sf.Type),
+				tagged:    name != "",
+			}
+			if f.name == "" {
+				f.name = sf.Name
+			}
+			if at, found := byName[f.name]; !found {
+				byName[f.name] = len(fields)
+				depthOf[f.name] = depth
+				fields = append(fields, f)
+			} else if depth < depthOf[f.name] || (depth == depthOf[f.name] && f.tagged && !fields[at].tagged) {
+				fields[at] = f
+				depthOf[f.name] = depth
+			} else if depth == depthOf[f.name] && f.tagged == fields[at].tagged {
+				// Ambiguous fields at the same depth are dropped.
+				fields[at].name = ""
+			}
+		}
+	}
+	collect(t, nil, 0)
+
+	result := make([]field, 0, len(fields))
+	for _, f := range fields {
+		if f.name != "" {
+			result = append(result, f)
+		}
+	}
+	fieldCache.Store(t, result)
+	return result
+}
+
+// quotable returns true if the string tag option applies to fields of the type.
+// Following encoding/json this is the case for booleans, numbers and strings
+// or unnamed pointers to them that don't provide their own JSON serialization.
+func quotable(t reflect.Type) bool {
+	if t.Name() == "" && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if customized(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// fieldByIndex returns the field at the specified index path.
+// If alloc is true nil embedded struct pointers are allocated,
+// otherwise an invalid value is returned when one is found.
+// An invalid value is also returned for a nil pointer to an unexported embedded struct
+// which can't be allocated.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// isEmptyValue follows the encoding/json definition of empty for omitempty.
+// In addition, Wrapper and Pointer objects with nil items are empty.
+func isEmptyValue(v reflect.Value) bool {
+	if usesOptions(v.Type()) || (v.Kind() == reflect.Pointer && usesOptions(v.Type().Elem())) {
+		if zeroer, ok := addressable(v).Interface().(interface{ IsZero() bool }); ok {
+			return zeroer.IsZero()
+		}
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isNil returns true if the item is nil or a nil pointer, map, slice, etc.
+func isNil(item interface{}) bool {
+	if item == nil {
+		return true
+	}
+	v := reflect.ValueOf(item)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// encoder walks a value generating JSON.
+type encoder struct {
+	opts *options
+}
+
+func (e *encoder) encode(v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return jsonNull, nil
+	} else if !needsWalk(v.Type()) {
+		return json.Marshal(addressable(v).Interface())
+	} else if usesOptions(v.Type()) {
+		return addressable(v).Interface().(optionsUser).marshalWith(e.opts)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		return e.encodeInterface(v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return jsonNull, nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			return jsonNull, nil
+		}
+		return e.encodeArray(v)
+	case reflect.Array:
+		return e.encodeArray(v)
+	default:
+		return json.Marshal(v.Interface())
+	}
+}
+
+// addressable returns a pointer to the value so that
+// methods with pointer receivers are available.
+func addressable(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		return v
+	} else if v.CanAddr() {
+		return v.Addr()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr
+}
+
+func (e *encoder) encodeInterface(v reflect.Value) ([]byte, error) {
+	if v.IsNil() {
+		return jsonNull, nil
+	}
+
+	var err error
+	var pack packed
+	item := v.Elem()
+	if v.NumMethod() == 0 && isPlain(item.Type()) {
+		if m, ok := item.Interface().(map[string]interface{}); ok && isPackedMap(m) {
+			return nil, errAmbiguousMap
+		}
+		return e.encode(item)
+	}
+	registry := wrapper.Lookup(v.Type(), e.opts.getRegistry())
+	if pack.TypeName, err = registry.NameFor(item.Interface()); err != nil {
+		return nil, fmt.Errorf("get type name for %#v: %w", item.Interface(), err)
+	} else if pack.RawForm, err = e.encode(item); err != nil {
+		return nil, fmt.Errorf("marshal packed area: %w", err)
+	}
+	return json.Marshal(pack)
+}
+
+// isPlain returns true if values of the type are serialized in an empty interface
+// without the type/data envelope.
+// These are the unnamed types generated by encoding/json for empty interfaces
+// and the predeclared boolean, numeric and string types.
+func isPlain(t reflect.Type) bool {
+	if t == anyMapType || t == anySliceType {
+		return true
+	} else if t.PkgPath() != "" {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// isPackedMap returns true if the map would be read as the type/data envelope.
+func isPackedMap(m map[string]interface{}) bool {
+	if len(m) != 2 {
+		return false
+	} else if _, found := m[dataField]; !found {
+		return false
+	}
+	_, ok := m[typeField].(string)
+	return ok
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for _, f := range fieldsOf(v.Type()) {
+		fv := fieldByIndex(v, f.index, false)
+		if !fv.IsValid() || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		var encoded []byte
+		var err error
+		if f.quoted {
+			encoded, err = encodeQuoted(fv)
+		} else {
+			encoded, err = e.encode(fv)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		writeKey(&buf, f.name)
+		buf.Write(encoded)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeQuoted encodes a field with the string tag option as a JSON string
+// containing the JSON for the value.
+func encodeQuoted(v reflect.Value) ([]byte, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return jsonNull, nil
+		}
+		v = v.Elem()
+	}
+	encoded, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(encoded))
+}
+
+func (e *encoder) encodeMap(v reflect.Value) ([]byte, error) {
+	if v.IsNil() {
+		return jsonNull, nil
+	}
+
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKeyString(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, ent := range entries {
+		encoded, err := e.encode(ent.value)
+		if err != nil {
+			return nil, fmt.Errorf("map key %s: %w", ent.key, err)
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeKey(&buf, ent.key)
+		buf.Write(encoded)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (e *encoder) encodeArray(v reflect.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		encoded, err := e.encode(v.Index(i))
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(encoded)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+func writeKey(buf *bytes.Buffer, key string) {
+	encoded, _ := json.Marshal(key)
+	buf.Write(encoded)
+	buf.WriteByte(':')
+}
+
+// mapKeyString converts a map key to a string following encoding/json rules.
+func mapKeyString(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	} else if tm, ok := key.Interface().(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return "", fmt.Errorf("marshal map key: %w", err)
+		}
+		return string(text), nil
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("%w: %s", errMapKey, key.Type())
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// decoder walks a value filling it from JSON.
+type decoder struct {
+	opts *options
+}
+
+func (d *decoder) decode(data []byte, v reflect.Value) error {
+	if !needsWalk(v.Type()) {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+
+	if usesOptions(v.Type()) {
+		// Checked first so that these types can handle null themselves.
+		return v.Addr().Interface().(optionsUser).unmarshalWith(d.opts, data)
+	} else if bytes.Equal(bytes.TrimSpace(data), jsonNull) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			return d.decodeAny(data, v)
+		}
+		return d.decodeInterface(data, v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(data, v.Elem())
+	case reflect.Struct:
+		return d.decodeStruct(data, v)
+	case reflect.Map:
+		return d.decodeMap(data, v)
+	case reflect.Slice, reflect.Array:
+		return d.decodeArray(data, v)
+	default:
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+}
+
+func (d *decoder) decodeInterface(data []byte, v reflect.Value) error {
+	pack, _, err := unpack(data)
+	if err != nil {
+		return decodeError(v, wrapper.PhaseEnvelope, pack.TypeName, err)
+	}
+	temp, err := wrapper.Lookup(v.Type(), d.opts.getRegistry()).Make(pack.TypeName)
+	if err != nil {
+		return decodeError(v, wrapper.PhaseInstantiate, pack.TypeName, err)
+	} else if temp == nil {
+		return decodeError(v, wrapper.PhaseInstantiate, pack.TypeName, wrapper.ErrNilInstance)
+	}
+	// Factories may return values for types implementing interfaces by value.
+	instance := addressable(reflect.ValueOf(temp))
+	if err = d.decode(pack.RawForm, instance.Elem()); err != nil {
+		return decodeError(v, wrapper.PhaseDecode, pack.TypeName, err)
+	}
+	return assign(v, instance, pack.TypeName)
+}
+
+// decodeAny decodes a value for an empty interface.
+// The type/data envelope is decoded as for other interfaces,
+// other values are decoded as by encoding/json with any envelopes within them
+// decoded as well.
+func (d *decoder) decodeAny(data []byte, v reflect.Value) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	switch data[0] {
+	case '{':
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("unmarshal %s: %w", v.Type(), err)
+		} else if isPackedRaw(raw) {
+			return d.decodeInterface(data, v)
+		}
+		// Decode in the same order as encodeMap so that embedded Target items precede references.
+		keys := make([]string, 0, len(raw))
+		for key := range raw {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		m := make(map[string]interface{}, len(raw))
+		for _, key := range keys {
+			value := reflect.New(v.Type()).Elem()
+			if err := d.decodeAny(raw[key], value); err != nil {
+				return fmt.Errorf("map key %s: %w", key, err)
+			}
+			m[key] = value.Interface()
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	case '[':
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("unmarshal %s: %w", v.Type(), err)
+		}
+		items := make([]interface{}, len(raw))
+		for i := range raw {
+			value := reflect.New(v.Type()).Elem()
+			if err := d.decodeAny(raw[i], value); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+			items[i] = value.Interface()
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	default:
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+}
+
+// isPackedRaw returns true if the object is the type/data envelope.
+func isPackedRaw(raw map[string]json.RawMessage) bool {
+	if len(raw) != 2 {
+		return false
+	} else if _, found := raw[dataField]; !found {
+		return false
+	}
+	rawType := bytes.TrimSpace(raw[typeField])
+	return len(rawType) > 0 && rawType[0] == '"'
+}
+
+// assign sets the interface value to the instance or,
+// if only the value type implements the interface, the value pointed to.
+func assign(v reflect.Value, instance reflect.Value, typeName string) error {
+	if instance.Type().AssignableTo(v.Type()) {
+		v.Set(instance)
+	} else if instance.Elem().Type().AssignableTo(v.Type()) {
+		v.Set(instance.Elem())
+	} else {
+		return decodeError(v, wrapper.PhaseTypeAssert, typeName, wrapper.ErrTypeMismatch)
+	}
+	return nil
+}
+
+// decodeError returns a wrapper.DecodeError for the specified interface value.
+func decodeError(v reflect.Value, phase wrapper.Phase, typeName string, err error) error {
+	return &wrapper.DecodeError{
+		TypeName: typeName,
+		Expected: v.Type(),
+		Phase:    phase,
+		Err:      err,
+	}
+}
+
+func (d *decoder) decodeStruct(data []byte, v reflect.Value) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("unmarshal %s: %w", v.Type(), err)
+	}
+	for _, f := range fieldsOf(v.Type()) {
+		fieldData, found := raw[f.name]
+		if !found {
+			// Follow encoding/json in preferring exact matches
+			// but accepting case-insensitive ones.
+			for name, rawData := range raw {
+				if strings.EqualFold(name, f.name) {
+					fieldData, found = rawData, true
+					break
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		fv := fieldByIndex(v, f.index, true)
+		if !fv.IsValid() {
+			return fmt.Errorf("field %s: %w", f.name, errUnexportedEmbedded)
+		} else if f.quoted {
+			if err := decodeQuoted(fieldData, fv); err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+		} else if err := d.decode(fieldData, fv); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// decodeQuoted decodes a field with the string tag option from a JSON string
+// containing the JSON for the value.
+func decodeQuoted(data []byte, v reflect.Value) error {
+	if bytes.Equal(bytes.TrimSpace(data), jsonNull) {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	var quoted string
+	if err := json.Unmarshal(data, &quoted); err != nil {
+		return fmt.Errorf("%w: unmarshal %s into %s", errQuoted, data, v.Type())
+	} else if err = json.Unmarshal([]byte(quoted), v.Addr().Interface()); err != nil {
+		return fmt.Errorf("%w: unmarshal %q into %s: %v", errQuoted, quoted, v.Type(), err)
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(data []byte, v reflect.Value) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("unmarshal %s: %w", v.Type(), err)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(raw)))
+	}
+	// Decode in the same order as encodeMap so that embedded Target items precede references.
+	keys := make([]string, 0, len(raw))
+	for keyString := range raw {
+		keys = append(keys, keyString)
+	}
+	sort.Strings(keys)
+	for _, keyString := range keys {
+		valueData := raw[keyString]
+		key, err := mapKeyValue(keyString, v.Type().Key())
+		if err != nil {
+			return err
+		}
+		value := reflect.New(v.Type().Elem()).Elem()
+		pending := d.opts.getBatch().Len()
+		if err = d.decode(valueData, value); err != nil {
+			return fmt.Errorf("map key %s: %w", keyString, err)
+		}
+		v.SetMapIndex(key, value)
+		d.afterBatch(pending, v, key, value)
+	}
+	return nil
+}
+
+// afterBatch copies the map value into the map again after the pointer.Batch is resolved
+// if decoding the value added Pointer objects to the Batch,
+// since the map holds a copy of the value made before the Target items were set.
+func (d *decoder) afterBatch(pending int, m, key, value reflect.Value) {
+	if batch := d.opts.getBatch(); batch.Len() > pending && value.Kind() != reflect.Pointer {
+		batch.After(func() {
+			m.SetMapIndex(key, value)
+		})
+	}
+}
+
+func (d *decoder) decodeArray(data []byte, v reflect.Value) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("unmarshal %s: %w", v.Type(), err)
+	}
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), len(raw), len(raw)))
+	}
+	for i := 0; i < len(raw) && i < v.Len(); i++ {
+		if err := d.decode(raw[i], v.Index(i)); err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// mapKeyValue converts a string to a map key following encoding/json rules.
+func mapKeyValue(key string, keyType reflect.Type) (reflect.Value, error) {
+	if reflect.PointerTo(keyType).Implements(textUnmarshalerType) {
+		ptr := reflect.New(keyType)
+		if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return reflect.Value{}, fmt.Errorf("unmarshal map key: %w", err)
+		}
+		return ptr.Elem(), nil
+	}
+	value := reflect.New(keyType).Elem()
+	switch keyType.Kind() {
+	case reflect.String:
+		value.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, keyType.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: %s", errMapKey, key)
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 10, keyType.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: %s", errMapKey, key)
+		}
+		value.SetUint(n)
+	default:
+		return reflect.Value{}, fmt.Errorf("%w: %s", errMapKey, keyType)
+	}
+	return value, nil
+}
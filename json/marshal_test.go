@@ -0,0 +1,399 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/madkins23/go-type/reg"
+
+	"github.com/madkins23/go-serial/test"
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+type JsonMarshalTestSuite struct {
+	suite.Suite
+	showSerialized bool
+}
+
+func (suite *JsonMarshalTestSuite) SetupSuite() {
+	if showSerialized, found := os.LookupEnv("GO-TYPE-SHOW-SERIALIZED"); found {
+		var err error
+		suite.showSerialized, err = strconv.ParseBool(showSerialized)
+		suite.Require().NoError(err)
+	}
+	reg.Singleton().Clear()
+	suite.Require().NoError(reg.AddAlias("json", Bond{}), "creating json test alias")
+	suite.Require().NoError(test.Register())
+	suite.Require().NoError(reg.Register(Bond{}))
+	suite.Require().NoError(reg.Register(PlainBond{}))
+}
+
+func TestJsonMarshalSuite(t *testing.T) {
+	suite.Run(t, new(JsonMarshalTestSuite))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// TestPlain tests automatic wrapping of interface fields in structs
+// that have no custom serialization code.
+func (suite *JsonMarshalTestSuite) TestPlain() {
+	portfolio := MakePlainPortfolio()
+	marshaled, err := Marshal(portfolio)
+	suite.Require().NoError(err)
+	if suite.showSerialized {
+		var buf bytes.Buffer
+		suite.Require().NoError(json.Indent(&buf, marshaled, "", "  "))
+		fmt.Println(buf.String())
+	}
+	suite.Assert().Contains(string(marshaled), `"Favorite":{"type":"[test]Stock","data":{"Market":"NASDAQ",`)
+	suite.Assert().Contains(string(marshaled), `"type":"[json]PlainBond"`)
+	suite.Assert().Contains(string(marshaled), `"Source":{"type":"[test]Federal","data":{}}`)
+	suite.Assert().Contains(string(marshaled), `"Nickname":"Fred"`)
+	suite.Assert().NotContains(string(marshaled), `"Spare"`)
+	suite.Assert().NotContains(string(marshaled), `"Ignored"`)
+
+	newPortfolio := new(PlainPortfolio)
+	suite.Require().NoError(Unmarshal(marshaled, newPortfolio))
+	if suite.showSerialized {
+		fmt.Println("---------------------------")
+		spew.Dump(newPortfolio)
+	}
+	portfolio.Ignored = ""
+	suite.Assert().Equal(portfolio, newPortfolio)
+	suite.Assert().Equal(test.StockWalmartName, newPortfolio.Lookup[test.StockWalmartSymbol].Name())
+}
+
+// TestCustom verifies that types with custom serialization
+// (in this case the Bond type from the wrapper tests) are left alone.
+func (suite *JsonMarshalTestSuite) TestCustom() {
+	holder := &PlainPortfolio{Favorite: MakeStateBond()}
+	marshaled, err := Marshal(holder)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), `"Source":{"type":"[test]State","data":{"State":"Confusion"}}`)
+	newHolder := new(PlainPortfolio)
+	suite.Require().NoError(Unmarshal(marshaled, newHolder))
+	suite.Assert().Equal(holder, newHolder)
+}
+
+func (suite *JsonMarshalTestSuite) TestNil() {
+	marshaled, err := Marshal(&PlainPortfolio{})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`{"Favorite":null,"Positions":null,"Lookup":null,"Nickname":""}`, string(marshaled))
+	newPortfolio := &PlainPortfolio{Favorite: test.MakeCostco()}
+	suite.Require().NoError(Unmarshal(marshaled, newPortfolio))
+	suite.Assert().Equal(&PlainPortfolio{}, newPortfolio)
+}
+
+// TestRegistry verifies that separate registries can be used concurrently.
+func (suite *JsonMarshalTestSuite) TestRegistry() {
+	var wg sync.WaitGroup
+	for _, alias := range []string{"one", "two", "three"} {
+		registry := reg.NewRegistry()
+		suite.Require().NoError(registry.AddAlias(alias, &test.Stock{}))
+		suite.Require().NoError(registry.Register(&test.Stock{}))
+		wg.Add(1)
+		go func(alias string, registry reg.Registry) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				portfolio := &PlainPortfolio{Favorite: test.MakeCostco()}
+				marshaled, err := MarshalWith(portfolio, WithRegistry(registry))
+				if !suite.Assert().NoError(err) {
+					return
+				}
+				suite.Assert().Contains(string(marshaled), "["+alias+"]Stock")
+				newPortfolio := new(PlainPortfolio)
+				suite.Assert().NoError(UnmarshalWith(marshaled, newPortfolio, WithRegistry(registry)))
+				suite.Assert().Equal(portfolio, newPortfolio)
+				// The go-type/reg singleton doesn't know about the alias.
+				suite.Assert().Error(Unmarshal(marshaled, new(PlainPortfolio)))
+			}
+		}(alias, registry)
+	}
+	wg.Wait()
+}
+
+// TestRegistry_Wrapper verifies that the Registry is passed to Wrapper fields
+// unless the Wrapper has its own Registry.
+func (suite *JsonMarshalTestSuite) TestRegistry_Wrapper() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("solo", &test.Stock{}))
+	suite.Require().NoError(registry.Register(&test.Stock{}))
+	holder := &struct {
+		Item *Wrapper[test.Investment]
+	}{Item: Wrap[test.Investment](test.MakeWalmart())}
+	marshaled, err := MarshalWith(holder, WithRegistry(registry))
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), `{"Item":{"type":"[solo]Stock"`)
+	suite.Require().NoError(UnmarshalWith(marshaled, holder, WithRegistry(registry)))
+	suite.Assert().Equal(test.MakeWalmart(), holder.Item.Get())
+
+	holder.Item.SetRegistry(reg.Singleton())
+	marshaled, err = MarshalWith(holder, WithRegistry(registry))
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "[test]Stock")
+}
+
+// TestOmitEmpty verifies that the omitempty field tag option
+// applies to Wrapper and Pointer objects with nil items.
+func (suite *JsonMarshalTestSuite) TestOmitEmpty() {
+	holder := &Holder{
+		Item:    Wrap[test.Investment](nil),
+		Value:   *Wrap[test.Investment](nil),
+		Pet:     Point[*test.Pet](nil),
+		Another: Wrap[test.Investment](nil),
+	}
+	marshaled, err := Marshal(holder)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`{"Another":null}`, string(marshaled))
+
+	holder.Item.Set(test.MakeCostco())
+	holder.Pet.Set(test.Knight)
+	marshaled, err = Marshal(holder)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), `"Item":{"type":"[test]Stock"`)
+	suite.Assert().Contains(string(marshaled), `"Pet":{"group":"dog","key":"Knight"}`)
+	suite.Assert().NotContains(string(marshaled), `"Value"`)
+
+	newHolder := new(Holder)
+	suite.Require().NoError(Unmarshal(marshaled, newHolder))
+	suite.Assert().Equal(test.MakeCostco(), newHolder.Item.Get())
+	suite.Assert().Equal(test.Knight, newHolder.Pet.Get())
+	suite.Assert().True(newHolder.Value.IsZero())
+	suite.Assert().True(newHolder.Another.IsZero())
+}
+
+func (suite *JsonMarshalTestSuite) TestErrors() {
+	_, err := Marshal(&PlainPortfolio{Favorite: &Unregistered{}})
+	suite.Assert().Error(err)
+	suite.Assert().ErrorIs(Unmarshal([]byte("{}"), PlainPortfolio{}), errNotPointer)
+	suite.Assert().ErrorIs(
+		Unmarshal([]byte(`{"Favorite":{"data":{}}}`), new(PlainPortfolio)),
+		wrapper.ErrEmptyTypeField)
+	err = Unmarshal([]byte(`{"Favorite":{"type":"[test]Federal","data":{}}}`), new(PlainPortfolio))
+	suite.Assert().ErrorIs(err, wrapper.ErrTypeMismatch)
+	var decodeErr *wrapper.DecodeError
+	suite.Require().ErrorAs(err, &decodeErr)
+	suite.Assert().Equal(wrapper.PhaseTypeAssert, decodeErr.Phase)
+	suite.Assert().Equal("[test]Federal", decodeErr.TypeName)
+	suite.Assert().Equal(reflect.TypeOf((*test.Investment)(nil)).Elem(), decodeErr.Expected)
+}
+
+// TestUnexportedEmbedded verifies that a nil pointer to an unexported embedded struct
+// is reported as an error as by encoding/json instead of causing a panic.
+func (suite *JsonMarshalTestSuite) TestUnexportedEmbedded() {
+	err := Unmarshal([]byte(`{"X":1,"I":null}`), &UnexportedOuter{})
+	suite.Assert().ErrorIs(err, errUnexportedEmbedded)
+
+	// An allocated embedded struct is filled.
+	outer := &UnexportedOuter{unexportedInner: &unexportedInner{}}
+	suite.Require().NoError(Unmarshal([]byte(`{"X":1,"I":null}`), outer))
+	suite.Assert().Equal(1, outer.X)
+	suite.Assert().Nil(outer.I)
+}
+
+// TestWalkable verifies that walkable caches results for types without interface values,
+// including types nested within recursive types.
+func (suite *JsonMarshalTestSuite) TestWalkable() {
+	suite.Assert().False(needsWalk(reflect.TypeOf(walkList{})))
+	for _, t := range []reflect.Type{
+		reflect.TypeOf(walkList{}), reflect.TypeOf(walkLeaf{}), reflect.TypeOf([]walkLeaf{}),
+	} {
+		cached, found := walkCache.Load(t)
+		suite.Assert().True(found, t.String())
+		suite.Assert().Equal(false, cached, t.String())
+	}
+
+	// The result for a type within a recursive type depends on the rest of the recursive type.
+	suite.Assert().True(needsWalk(reflect.TypeOf(walkTree{})))
+	suite.Assert().True(needsWalk(reflect.TypeOf(walkBranch{})))
+}
+
+// TestQuoted verifies that the string field tag option is handled as by encoding/json.
+func (suite *JsonMarshalTestSuite) TestQuoted() {
+	rate := 2.5
+	holder := &QuotedHolder{ID: 5, Rate: &rate, Flag: true, Label: "five", Counts: []int{5}}
+	expected, err := json.Marshal(holder)
+	suite.Require().NoError(err)
+	marshaled, err := Marshal(holder)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(string(expected), string(marshaled))
+	suite.Assert().Contains(string(marshaled), `"ID":"5","rate":"2.5","Flag":"true","Label":"\"five\""`)
+
+	holder.Favorite = test.MakeCostco()
+	marshaled, err = Marshal(holder)
+	suite.Require().NoError(err)
+	newHolder := new(QuotedHolder)
+	suite.Require().NoError(Unmarshal(marshaled, newHolder))
+	suite.Assert().Equal(holder, newHolder)
+
+	newHolder = new(QuotedHolder)
+	suite.Require().NoError(Unmarshal([]byte(`{"ID":"7","rate":null}`), newHolder))
+	suite.Assert().Equal(int64(7), newHolder.ID)
+	suite.Assert().Nil(newHolder.Rate)
+	suite.Assert().ErrorIs(Unmarshal([]byte(`{"ID":7}`), new(QuotedHolder)), errQuoted)
+	suite.Assert().ErrorIs(Unmarshal([]byte(`{"ID":"seven"}`), new(QuotedHolder)), errQuoted)
+}
+
+// TestAny verifies that plain data in empty interfaces is serialized as by encoding/json
+// and that only other types are serialized within the type/data envelope.
+func (suite *JsonMarshalTestSuite) TestAny() {
+	plain := map[string]interface{}{
+		"a": 1, "b": true, "c": "see", "d": nil, "e": 2.5,
+		"list": []interface{}{1, "two", map[string]interface{}{"three": 3}},
+	}
+	expected, err := json.Marshal(plain)
+	suite.Require().NoError(err)
+	marshaled, err := Marshal(plain)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(string(expected), string(marshaled))
+	var fromJSON, fromWalker map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(marshaled, &fromJSON))
+	suite.Require().NoError(Unmarshal(marshaled, &fromWalker))
+	suite.Assert().Equal(fromJSON, fromWalker)
+
+	// Other types within plain data are wrapped.
+	holder := &AnyHolder{
+		Item:  test.MakeCostco(),
+		Extra: map[string]interface{}{"stock": test.MakeWalmart(), "count": 2.0},
+		List:  []interface{}{"one", test.MakeCostco(), []interface{}{test.MakeWalmart()}},
+	}
+	marshaled, err = Marshal(holder)
+	suite.Require().NoError(err)
+	if suite.showSerialized {
+		fmt.Println(string(marshaled))
+	}
+	suite.Assert().Contains(string(marshaled), `"Item":{"type":"[test]Stock"`)
+	suite.Assert().Contains(string(marshaled), `"count":2`)
+	newHolder := new(AnyHolder)
+	suite.Require().NoError(Unmarshal(marshaled, newHolder))
+	suite.Assert().Equal(holder, newHolder)
+
+	// Named types must still be registered.
+	_, err = Marshal(&AnyHolder{Item: celsius(20)})
+	suite.Assert().Error(err)
+
+	// A map which would be read as the envelope is rejected.
+	_, err = Marshal(&AnyHolder{Item: map[string]interface{}{"type": "x", "data": 1}})
+	suite.Assert().ErrorIs(err, errAmbiguousMap)
+	_, err = Marshal(&AnyHolder{Item: map[string]interface{}{"type": 1, "data": 1}})
+	suite.Assert().NoError(err)
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// PlainPortfolio has interface fields but no custom serialization code.
+type PlainPortfolio struct {
+	Favorite  test.Investment
+	Positions []test.Investment
+	Lookup    map[string]test.Investment
+	Nickname  string
+	Spare     test.Investment `json:",omitempty"`
+	Ignored   string          `json:"-"`
+}
+
+func MakePlainPortfolio() *PlainPortfolio {
+	portfolio := &PlainPortfolio{
+		Positions: []test.Investment{
+			test.MakeCostco(), test.MakeWalmart(),
+			&PlainBond{BondData: test.StateBondData(), Source: test.StateBondSource()},
+			&PlainBond{BondData: test.TBillData(), Source: test.TBillSource()},
+		},
+		Lookup:   make(map[string]test.Investment),
+		Nickname: "Fred",
+		Ignored:  "ignored",
+	}
+	portfolio.Favorite = portfolio.Positions[0]
+	for _, position := range portfolio.Positions {
+		if stock, ok := position.(*test.Stock); ok {
+			portfolio.Lookup[stock.Symbol] = stock
+		}
+	}
+	return portfolio
+}
+
+//------------------------------------------------------------------------
+
+var _ test.Investment = &PlainBond{}
+
+// PlainBond has an interface field but no custom serialization code.
+type PlainBond struct {
+	test.BondData
+	Source test.Borrower
+}
+
+//------------------------------------------------------------------------
+
+// Holder has Wrapper and Pointer fields with the omitempty field tag option.
+type Holder struct {
+	Item    *Wrapper[test.Investment] `json:",omitempty"`
+	Value   Wrapper[test.Investment]  `json:",omitempty"`
+	Pet     *Pointer[*test.Pet]       `json:",omitempty"`
+	Another *Wrapper[test.Investment]
+}
+
+//------------------------------------------------------------------------
+
+var _ test.Investment = &Unregistered{}
+
+// Unregistered is not registered with go-type/reg.
+type Unregistered struct {
+	test.BondData
+}
+
+// QuotedHolder has fields with the string field tag option.
+// The option doesn't apply to slices.
+type QuotedHolder struct {
+	Favorite test.Investment
+	ID       int64    `json:",string"`
+	Rate     *float64 `json:"rate,string"`
+	Flag     bool     `json:",string"`
+	Label    string   `json:",string"`
+	Counts   []int    `json:",string"`
+}
+
+// AnyHolder has empty interface fields.
+type AnyHolder struct {
+	Item  interface{}
+	Extra map[string]interface{}
+	List  []interface{}
+}
+
+// celsius is not registered with go-type/reg.
+type celsius float64
+
+type UnexportedOuter struct {
+	*unexportedInner
+	I test.Investment
+}
+
+type unexportedInner struct {
+	X int
+}
+
+type walkList struct {
+	Next   *walkList
+	Leaves []walkLeaf
+}
+
+type walkLeaf struct {
+	Name string
+}
+
+type walkTree struct {
+	Branch *walkBranch
+	Item   interface{}
+}
+
+type walkBranch struct {
+	Tree *walkTree
+	Leaf walkLeaf
+}
@@ -1,21 +1,47 @@
 package json
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 
+	"github.com/madkins23/go-serial/internal/targets"
 	"github.com/madkins23/go-serial/pointer"
-)
-
-const (
-	tgtGroup = "group"
-	tgtKey   = "key"
+	"github.com/madkins23/go-serial/wrapper"
 )
 
 // Pointer is used to specify an object that may be found in a cache or DB.
+//
+// A Pointer with a nil Target item is serialized as JSON null
+// and JSON null is deserialized as a nil Target item.
+//
+// Target items are stored in and acquired from a pointer.Cache.
+// The Cache specified via SetCache is used if present,
+// otherwise the Cache specified via WithCache when using MarshalWith or UnmarshalWith,
+// otherwise the default pointer.Cache.
+// The context specified via WithContext when using UnmarshalWith
+// is passed to any pointer.ContextFinder used to acquire the Target item.
+// When using UnmarshalWith and WithBatch the Target item is acquired after
+// the entire document has been decoded, see WithBatch.
+//
+// When using MarshalWith and WithEmbeddedTargets the first Pointer to each Target item
+// is serialized with the Target item embedded, see WithEmbeddedTargets.
+// When using MarshalWith and WithRefs the Pointer is serialized as a JSON Reference.
+// JSON References are always accepted during deserialization, see WithRefs.
+//
+// When using UnmarshalWith and WithDangling a Pointer to a Target item that can't be found
+// may be left unresolved or set to a placeholder instead of failing, see WithDangling.
+//
+// A lazy Pointer (see SetLazy and WithLazy) only records the group and key
+// when deserialized and acquires the Target item when first requested via Get or Resolve.
 type Pointer[T pointer.Target] struct {
-	item T
+	item  T
+	cache *pointer.Cache
+	lazy  bool
+	ref   *pointer.Reference
 }
 
 func Point[T pointer.Target](target T) *Pointer[T] {
@@ -25,63 +51,352 @@ func Point[T pointer.Target](target T) *Pointer[T] {
 }
 
 // Get the Target item from the Pointer.
+// If the Pointer was deserialized lazily the Target item is acquired as by Resolve,
+// with the zero value returned if it can't be acquired.
 func (p *Pointer[T]) Get() T {
-	return p.item
+	item, _ := p.Resolve()
+	return item
 }
 
 // Set the Target item for the Pointer.
 func (p *Pointer[T]) Set(t T) {
 	p.item = t
+	p.ref = nil
+}
+
+// Resolve returns the Target item from the Pointer.
+// If the Pointer was deserialized lazily the Target item is acquired from
+// the pointer.Cache (and if necessary its Finder) the first time it is requested.
+// Failures are returned as errors and may be retried.
+func (p *Pointer[T]) Resolve() (T, error) {
+	return p.ResolveContext(context.Background())
+}
+
+// ResolveContext returns the Target item from the Pointer as does Resolve,
+// passing the specified context to any pointer.ContextFinder.
+func (p *Pointer[T]) ResolveContext(ctx context.Context) (T, error) {
+	if p.ref == nil {
+		return p.item, nil
+	}
+	var zero T
+	target, err := p.ref.Resolve(ctx)
+	if err != nil {
+		return zero, fmt.Errorf("get target: %w", err)
+	} else if item, ok := target.(T); !ok {
+		return zero, fmt.Errorf(fmtWrongTargetType, target)
+	} else {
+		return item, nil
+	}
+}
+
+// Resolved returns false if the Pointer was deserialized lazily
+// and its Target item has not yet been acquired.
+func (p *Pointer[T]) Resolved() bool {
+	return p.ref == nil || p.ref.Resolved()
+}
+
+// Lazy returns true if the Pointer is deserialized lazily.
+func (p *Pointer[T]) Lazy() bool {
+	return p.lazy
+}
+
+// SetLazy configures whether the Pointer is deserialized lazily,
+// recording the group and key of the Target item without acquiring it.
+// See also WithLazy.
+func (p *Pointer[T]) SetLazy(lazy bool) {
+	p.lazy = lazy
+}
+
+// IsZero returns true if the Pointer is nil or has a nil Target item
+// and no lazily deserialized reference.
+// This supports the omitzero field tag option and
+// the omitempty field tag option when using Marshal.
+func (p *Pointer[T]) IsZero() bool {
+	return p == nil || (isNil(p.item) && p.ref == nil)
+}
+
+// Cache returns the pointer.Cache specific to the Pointer, if any.
+func (p *Pointer[T]) Cache() *pointer.Cache {
+	return p.cache
+}
+
+// SetCache specifies a pointer.Cache to be used by the Pointer for storing and
+// acquiring its Target item instead of the Cache from the options or the default Cache.
+// A nil Cache reverts to the default behavior.
+func (p *Pointer[T]) SetCache(cache *pointer.Cache) {
+	p.cache = cache
+}
+
+// cacheFor returns the pointer.Cache to be used for the specified options.
+func (p *Pointer[T]) cacheFor(opts *options) *pointer.Cache {
+	if p.cache != nil {
+		return p.cache
+	}
+	return opts.getCache()
 }
 
 // -----------------------------------------------------------------------
 
 func (p *Pointer[T]) MarshalJSON() ([]byte, error) {
+	return p.marshalWith(nil)
+}
+
+func (p *Pointer[T]) marshalWith(opts *options) ([]byte, error) {
+	if p.IsZero() {
+		return jsonNull, nil
+	}
+
 	var err error
-	var group = p.item.Group()
-	var key = p.item.Key()
-	var pack = map[string]string{
-		tgtGroup: group,
-		tgtKey:   key,
+	var pack pointerPack
+	if p.ref != nil {
+		// Serialize a lazy reference without acquiring the Target item.
+		pack.Group, pack.Key = p.ref.Group(), p.ref.Key()
+	} else {
+		pack.Group, pack.Key = p.item.Group(), p.item.Key()
 	}
 
-	if !pointer.HasTarget(group, key) {
-		if err = pointer.SetTarget(p.item, false); err == nil {
+	if cache := p.cacheFor(opts); p.ref == nil && !cache.HasTarget(pack.Group, pack.Key) {
+		if err = cache.Remember(p.item, false); err == nil {
 		} else if !errors.Is(err, pointer.ErrTargetAlreadyExists) {
 			return nil, fmt.Errorf("setting target in cache: %w", err)
 		}
 	}
 
+	if p.ref == nil && opts.embed(pack.Group, pack.Key) {
+		if pack.TypeName, err = wrapper.NameFor(p.item, opts.getRegistry()); err != nil {
+			return nil, fmt.Errorf("get type name for %#v: %w", p.item, err)
+		} else if pack.RawForm, err = json.Marshal(p.item); err != nil {
+			return nil, fmt.Errorf("marshal embedded target: %w", err)
+		}
+	} else if ref := opts.ref(pack.Group, pack.Key); ref != "" {
+		return json.Marshal(map[string]string{refField: ref})
+	}
+
 	var marshaled []byte
 	marshaled, err = json.Marshal(pack)
 	if err != nil {
-		return []byte(""), fmt.Errorf("marshal packed form: %p", err)
+		return []byte(""), fmt.Errorf("marshal packed form: %w", err)
 	}
 	return marshaled, nil
 }
 
+// pointerPack is the serialized form of a Pointer.
+// The type name and data are only present for an embedded Target item.
+// The JSON Reference is only present (without any other fields) in references.
+type pointerPack struct {
+	Group    string          `json:"group"`
+	Key      string          `json:"key"`
+	TypeName string          `json:"type,omitempty"`
+	RawForm  json.RawMessage `json:"data,omitempty"`
+	Ref      string          `json:"$ref,omitempty"`
+}
+
 var (
-	errEmptyGroupField = errors.New("empty group field")
-	errEmptyKeyField   = errors.New("empty key field")
-	fmtWrongTargetType = "object '%v' not Target"
+	errEmptyGroupField = targets.ErrEmptyGroupField
+	errEmptyKeyField   = targets.ErrEmptyKeyField
 )
 
+const fmtWrongTargetType = targets.FmtWrongTargetType
+
 func (p *Pointer[T]) UnmarshalJSON(marshaled []byte) error {
-	var pack map[string]string
+	return p.unmarshalWith(nil, marshaled)
+}
+
+func (p *Pointer[T]) unmarshalWith(opts *options, marshaled []byte) error {
+	if bytes.Equal(bytes.TrimSpace(marshaled), jsonNull) {
+		p.clear()
+		return nil
+	}
+
+	var pack pointerPack
 	if err := json.Unmarshal(marshaled, &pack); err != nil {
-		return fmt.Errorf("unmarshal packed area: %p", err)
+		return fmt.Errorf("unmarshal packed area: %w", err)
 	}
 
-	var ok bool
-	if group, found := pack[tgtGroup]; !found {
+	if pack.Ref != "" {
+		return p.unmarshalRef(opts, pack.Ref)
+	} else if pack.Group == "" {
 		return errEmptyGroupField
-	} else if key, found := pack[tgtKey]; !found {
+	} else if pack.Key == "" {
 		return errEmptyKeyField
-	} else if target, err := pointer.GetTarget(group, key, nil); err != nil {
+	} else if pack.TypeName != "" {
+		return p.unmarshalEmbedded(opts, pack)
+	}
+	return p.unmarshalReference(opts, pack.Group, pack.Key)
+}
+
+// unmarshalReference sets the Pointer to the Target item with the specified group and key.
+func (p *Pointer[T]) unmarshalReference(opts *options, group, key string) error {
+	if p.lazy || opts.getLazy() {
+		p.clear()
+		p.ref = pointer.NewReference(p.cacheFor(opts), group, key)
+		return nil
+	}
+
+	cache := p.cacheFor(opts)
+	if batch := opts.getBatch(); batch != nil {
+		p.clear()
+		if opts.getDangling() != pointer.DanglingFail {
+			// Remains if the Target item can't be found.
+			p.ref = pointer.NewReference(cache, group, key)
+		}
+		batch.Add(cache, group, key, p.setTarget)
+		return nil
+	} else if target, err := cache.GetTargetContext(opts.getContext(), group, key, nil); err == nil {
+		return p.setTarget(target)
+	} else if !errors.Is(err, pointer.ErrNoSuchTarget) {
+		return fmt.Errorf("get target: %w", err)
+	} else if target, err = opts.dangle(cache, group, key); err != nil {
 		return fmt.Errorf("get target: %w", err)
-	} else if p.item, ok = target.(T); !ok {
+	} else if target != nil {
+		return p.setTarget(target)
+	}
+	// Left unresolved with the group and key so that it is marshaled as it was read.
+	p.clear()
+	p.ref = pointer.NewReference(cache, group, key)
+	return nil
+}
+
+// unmarshalRef sets the Pointer from a JSON Reference.
+// A local reference to a value in the document being decoded is resolved to that value,
+// any other reference ends with the group and key of the Target item.
+func (p *Pointer[T]) unmarshalRef(opts *options, ref string) error {
+	tokens, local, err := parseRef(ref)
+	if err != nil {
+		return err
+	}
+	if local {
+		target, err := p.localTarget(opts, ref, tokens)
+		if err != nil {
+			return err
+		} else if target != nil {
+			if batch := opts.getBatch(); batch != nil && opts.getForward() {
+				// Target items found in the decoded value take precedence.
+				p.clear()
+				batch.Add(p.cacheFor(opts), target.Group(), target.Key(), p.setTarget)
+				return nil
+			}
+			return p.setTarget(target)
+		}
+	}
+	if len(tokens) < 2 {
+		return fmt.Errorf("%w: '%s'", errShortRef, ref)
+	}
+	return p.unmarshalReference(opts, tokens[len(tokens)-2], tokens[len(tokens)-1])
+}
+
+// localTarget returns the Target item decoded from the value in the document
+// referred to by a local JSON Reference or nil if there is no such value.
+// Each value is only decoded once so all Pointer objects with the same reference
+// have the same Target item.
+// Unless using forward references the Target item is added to the pointer.Cache,
+// if the Cache already has a Target with the same group and key that Target is used instead.
+func (p *Pointer[T]) localTarget(opts *options, ref string, tokens []string) (pointer.Target, error) {
+	if target := opts.getLocalRef(ref); target != nil {
+		return target, nil
+	}
+	raw, found := opts.localRef(tokens)
+	if !found {
+		return nil, nil
+	}
+	var target pointer.Target
+	item, err := decodeTarget[T](opts, raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", ref, err)
+	} else if opts.getForward() {
+		target = item
+	} else if target, err = adopt(p.cacheFor(opts), item); err != nil {
+		return nil, fmt.Errorf("set target for %s: %w", ref, err)
+	}
+	opts.setLocalRef(ref, target)
+	return target, nil
+}
+
+// unmarshalEmbedded creates the Target item embedded in the packed form
+// and adds it to the pointer.Cache.
+// If the Cache already has a Target with the same group and key that Target is used instead,
+// so that all Pointer objects with the same group and key have the same Target item.
+func (p *Pointer[T]) unmarshalEmbedded(opts *options, pack pointerPack) error {
+	item, err := wrapper.Make[T](pack.TypeName, opts.getRegistry())
+	if err != nil {
+		return err
+	} else if len(pack.RawForm) == 0 {
+		return wrapper.NewDecodeError[T](wrapper.PhaseEnvelope, pack.TypeName, wrapper.ErrNotEnvelope)
+	} else if err = json.Unmarshal(pack.RawForm, item); err != nil {
+		return wrapper.NewDecodeError[T](wrapper.PhaseDecode, pack.TypeName, err)
+	} else if item.Group() != pack.Group {
+		return pointer.ErrBadTargetGroup
+	} else if item.Key() != pack.Key {
+		return pointer.ErrBadTargetKey
+	}
+	target, err := adopt(p.cacheFor(opts), item)
+	if err != nil {
+		return fmt.Errorf("set embedded target: %w", err)
+	}
+	return p.setTarget(target)
+}
+
+// decodeTarget decodes a Target item from a value that may be in the type/data envelope.
+// Without the envelope the Target type must not be an interface.
+func decodeTarget[T pointer.Target](opts *options, raw json.RawMessage) (T, error) {
+	var zero T
+	var envelope struct {
+		TypeName string          `json:"type"`
+		RawForm  json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal(raw, &envelope) == nil && envelope.TypeName != "" && len(envelope.RawForm) > 0 {
+		item, err := wrapper.Make[T](envelope.TypeName, opts.getRegistry())
+		if err != nil {
+			return zero, err
+		} else if err = json.Unmarshal(envelope.RawForm, item); err != nil {
+			return zero, wrapper.NewDecodeError[T](wrapper.PhaseDecode, envelope.TypeName, err)
+		}
+		return item, nil
+	}
+
+	var value reflect.Value
+	switch typ := reflect.TypeOf(&zero).Elem(); typ.Kind() {
+	case reflect.Interface:
+		return zero, wrapper.NewDecodeError[T](wrapper.PhaseEnvelope, "", wrapper.ErrNotEnvelope)
+	case reflect.Pointer:
+		value = reflect.New(typ.Elem())
+	default:
+		value = reflect.New(typ)
+	}
+	if err := json.Unmarshal(raw, value.Interface()); err != nil {
+		return zero, wrapper.NewDecodeError[T](wrapper.PhaseDecode, "", err)
+	} else if value.Type().AssignableTo(reflect.TypeOf(&zero).Elem()) {
+		return value.Interface().(T), nil
+	}
+	return value.Elem().Interface().(T), nil
+}
+
+// adopt adds the Target item to the pointer.Cache and returns it.
+// If the Cache already has a Target with the same group and key that Target is returned instead,
+// so that all Pointer objects with the same group and key have the same Target item.
+func adopt(cache *pointer.Cache, item pointer.Target) (pointer.Target, error) {
+	if err := cache.Remember(item, false); errors.Is(err, pointer.ErrTargetAlreadyExists) {
+		if existing, err := cache.GetTarget(item.Group(), item.Key(), nil); err == nil {
+			return existing, nil
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// clear sets the Pointer to a nil Target item.
+func (p *Pointer[T]) clear() {
+	var zero T
+	p.Set(zero)
+}
+
+// setTarget sets the Target item for the Pointer if it is of the correct type.
+func (p *Pointer[T]) setTarget(target pointer.Target) error {
+	item, ok := target.(T)
+	if !ok {
 		return fmt.Errorf(fmtWrongTargetType, target)
-	} else {
-		return nil
 	}
+	p.Set(item)
+	return nil
 }
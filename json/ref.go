@@ -0,0 +1,114 @@
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// refField is the name of the JSON Reference field used by JSON Schema and OpenAPI.
+const refField = "$ref"
+
+var (
+	errBadRef   = errors.New("bad JSON pointer")
+	errShortRef = errors.New("JSON pointer without group and key")
+)
+
+// formatRef returns a JSON Reference built from the base and the group and key tokens.
+// If the base contains a '#' the result is a URI fragment and
+// the tokens are percent-encoded as well as escaped as specified by RFC 6901.
+func formatRef(base, group, key string) string {
+	fragment := strings.Contains(base, "#")
+	var builder strings.Builder
+	builder.WriteString(strings.TrimSuffix(base, "/"))
+	for _, token := range []string{group, key} {
+		token = escapeToken(token)
+		if fragment {
+			token = escapeFragment(token)
+		}
+		builder.WriteByte('/')
+		builder.WriteString(token)
+	}
+	return builder.String()
+}
+
+// parseRef returns the reference tokens of a JSON Reference and
+// whether the reference is local to the current document.
+// The reference may be a URI with a JSON pointer fragment (e.g. "other.json#/pets/cat/Lacey"),
+// a local URI fragment (e.g. "#/pets/cat/Lacey") or a JSON pointer (e.g. "/pets/cat/Lacey").
+func parseRef(ref string) ([]string, bool, error) {
+	local := true
+	ptr := ref
+	if uri, fragment, found := strings.Cut(ref, "#"); found {
+		unescaped, err := url.PathUnescape(fragment)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w '%s': %v", errBadRef, ref, err)
+		}
+		local, ptr = uri == "", unescaped
+	}
+	if ptr == "" {
+		return []string{}, local, nil
+	} else if ptr[0] != '/' {
+		return nil, false, fmt.Errorf("%w '%s': no leading slash", errBadRef, ref)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = unescapeToken(token)
+	}
+	return tokens, local, nil
+}
+
+// evaluateRef returns the value in the JSON document referred to by the reference tokens.
+// If there is no such value the result is false.
+func evaluateRef(document []byte, tokens []string) (json.RawMessage, bool) {
+	current := json.RawMessage(document)
+	for _, token := range tokens {
+		var object map[string]json.RawMessage
+		var array []json.RawMessage
+		if json.Unmarshal(current, &object) == nil && object != nil {
+			if current = object[token]; current == nil {
+				return nil, false
+			}
+		} else if json.Unmarshal(current, &array) == nil && array != nil {
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(array) || (len(token) > 1 && token[0] == '0') {
+				return nil, false
+			}
+			current = array[index]
+		} else {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+var tokenEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+var tokenUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// escapeToken escapes a reference token as specified by RFC 6901.
+func escapeToken(token string) string {
+	return tokenEscaper.Replace(token)
+}
+
+// unescapeToken reverses escapeToken.
+func unescapeToken(token string) string {
+	return tokenUnescaper.Replace(token)
+}
+
+// escapeFragment percent-encodes the characters not allowed in a URI fragment (RFC 3986).
+func escapeFragment(s string) string {
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+			strings.IndexByte("-._~!$&'()*+,;=:@/?", c) >= 0 {
+			builder.WriteByte(c)
+		} else {
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+	return builder.String()
+}
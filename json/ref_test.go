@@ -0,0 +1,61 @@
+package json
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatRef(t *testing.T) {
+	assert.Equal(t, "#/cat/Lacey", formatRef("#", "cat", "Lacey"))
+	assert.Equal(t, "#/pets/cat/Lacey", formatRef("#/pets/", "cat", "Lacey"))
+	assert.Equal(t, "/pets/a~1b/c~0d", formatRef("/pets", "a/b", "c~d"))
+	assert.Equal(t, "pets.json#/a~1b/Big%20Noah%25", formatRef("pets.json#", "a/b", "Big Noah%"))
+}
+
+func TestParseRef(t *testing.T) {
+	for ref, expected := range map[string][]string{
+		"#":                         {},
+		"#/pets/cat/Lacey":          {"pets", "cat", "Lacey"},
+		"/pets/a~1b/c~0d/~01":       {"pets", "a/b", "c~d", "~1"},
+		"#/a~1b/Big%20Noah%25":      {"a/b", "Big Noah%"},
+		"pets.json#/pets/cat/Lacey": {"pets", "cat", "Lacey"},
+	} {
+		tokens, local, err := parseRef(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, expected, tokens, ref)
+		assert.Equal(t, ref[0] == '#' || ref[0] == '/', local, ref)
+	}
+	for _, ref := range []string{"pets/cat", "#pets", "#/%zz"} {
+		_, _, err := parseRef(ref)
+		assert.ErrorIs(t, err, errBadRef, ref)
+	}
+	for _, name := range []string{"a/b", "c~d", "~1", "Big Noah%", "ünïcode"} {
+		tokens, _, err := parseRef(formatRef("#", "group", name))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"group", name}, tokens)
+	}
+}
+
+func TestEvaluateRef(t *testing.T) {
+	document := []byte(`{"pets": {"cat": [{"name": "Lacey"}, {"name": "Noah"}]}, "a/b": 1, "": 2}`)
+	for ptr, expected := range map[string]string{
+		"":                  string(document),
+		"/pets/cat/1":       `{"name": "Noah"}`,
+		"/pets/cat/0/name":  `"Lacey"`,
+		"/a~1b":             `1`,
+		"/":                 `2`,
+		"/pets/cat/2":       "",
+		"/pets/cat/01":      "",
+		"/pets/cat/-":       "",
+		"/pets/dog":         "",
+		"/pets/cat/0/name/": "",
+	} {
+		tokens, _, err := parseRef(ptr)
+		require.NoError(t, err)
+		value, found := evaluateRef(document, tokens)
+		assert.Equal(t, expected != "", found, ptr)
+		assert.Equal(t, expected, string(value), ptr)
+	}
+}
@@ -0,0 +1,27 @@
+package json
+
+import (
+	"reflect"
+
+	"github.com/madkins23/go-serial/internal/targets"
+	"github.com/madkins23/go-serial/pointer"
+)
+
+// itemHolder is implemented by types (e.g. Wrapper) that hold items in unexported fields.
+type itemHolder interface {
+	heldItems() []interface{}
+}
+
+// collectTargets calls the visit function for each pointer.Target found in the value,
+// including the items held by Wrapper, Slice and Map objects (see targets.Collect).
+func collectTargets(v reflect.Value, visit func(pointer.Target)) {
+	targets.Collect(v, visit, heldItems)
+}
+
+// heldItems returns the items held by the item if it is an itemHolder.
+func heldItems(item interface{}) ([]interface{}, bool) {
+	if holder, ok := item.(itemHolder); ok {
+		return holder.heldItems(), true
+	}
+	return nil, false
+}
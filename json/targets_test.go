@@ -0,0 +1,46 @@
+package json
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/test"
+)
+
+func TestCollectTargets(t *testing.T) {
+	type node struct {
+		Pet      test.Pet
+		Pets     map[string]*test.Pet
+		Any      interface{}
+		Nil      *test.Pet
+		Wrapped  *Wrapper[pointer.Target]
+		Pointer  *Pointer[*test.Pet]
+		Next     *node
+		unlisted *test.Pet
+	}
+	cat := &test.Pet{Name: "Tom", Type: "cat"}
+	root := &node{
+		Pet:      test.Pet{Name: "Rex", Type: "dog"},
+		Pets:     map[string]*test.Pet{"cat": cat, "nil": nil},
+		Any:      []interface{}{test.Orca, (*test.Pet)(nil)},
+		Wrapped:  Wrap[pointer.Target](test.Noah),
+		Pointer:  Point[*test.Pet](test.Knight),
+		unlisted: test.Lacey,
+	}
+	root.Next = &node{Any: root, Pets: map[string]*test.Pet{"cat": cat}}
+
+	var found []pointer.Target
+	collectTargets(reflect.ValueOf(root).Elem(), func(target pointer.Target) {
+		found = append(found, target)
+	})
+	// The zero Pet in the second node is found but will be ignored by pointer.Batch.
+	assert.ElementsMatch(t, []pointer.Target{&root.Pet, cat, test.Orca, test.Noah, &root.Next.Pet}, found)
+	for _, target := range found {
+		if target.Key() == "Rex" {
+			assert.Same(t, &root.Pet, target)
+		}
+	}
+}
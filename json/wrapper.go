@@ -1,12 +1,13 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 
-	"github.com/madkins23/go-type/reg"
+	"github.com/madkins23/go-serial/wrapper"
 )
 
 // Wrap an item in a JSON wrapper that can handle serialization.
@@ -16,10 +17,41 @@ func Wrap[W any](item W) *Wrapper[W] {
 	return w
 }
 
+// WrapInline wraps an item in a JSON wrapper that serializes in inline mode.
+func WrapInline[W any](item W) *Wrapper[W] {
+	w := Wrap(item)
+	w.SetInline(true)
+	return w
+}
+
 // Wrapper is used to attach a type name to an item to be serialized.
 // This supports re-creating the correct type for filling an interface field.
+//
+// By default the item is serialized within an envelope:
+//
+//	{"type": "[test]Stock", "data": {"Market": "NYSE", ...}}
+//
+// In inline mode the type name is merged into the item's own fields:
+//
+//	{"type": "[test]Stock", "Market": "NYSE", ...}
+//
+// Inline mode requires the item to serialize as a JSON object
+// that does not have its own "type" field
+// and does not have a "data" field as its only field,
+// which would be read as the envelope.
+// Either form is accepted during unmarshaling,
+// the form that was read is retained for subsequent marshaling.
+//
+// A Wrapper with a nil item is serialized as JSON null
+// and JSON null is deserialized as a nil item.
+//
+// Type names are provided and items created by the Registry
+// returned by wrapper.Lookup for interface type T,
+// unless a specific Registry is set for the wrapper.
 type Wrapper[T any] struct {
-	item T
+	item     T
+	inline   bool
+	registry wrapper.Registry
 }
 
 // Get the wrapped item.
@@ -32,17 +64,61 @@ func (w *Wrapper[T]) Set(t T) {
 	w.item = t
 }
 
+// IsZero returns true if the wrapper is nil or has a nil item.
+// This supports the omitzero field tag option and
+// the omitempty field tag option when using Marshal.
+func (w *Wrapper[T]) IsZero() bool {
+	return w == nil || isNil(w.item)
+}
+
+// Inline returns true if the wrapper serializes in inline mode.
+func (w *Wrapper[T]) Inline() bool {
+	return w.inline
+}
+
+// SetInline configures whether the wrapper serializes in inline mode.
+func (w *Wrapper[T]) SetInline(inline bool) {
+	w.inline = inline
+}
+
+// Registry returns the Registry specific to the wrapper, if any.
+func (w *Wrapper[T]) Registry() wrapper.Registry {
+	return w.registry
+}
+
+// SetRegistry specifies a Registry for the wrapper.
+// This overrides any other Registry or wrapper.Factory that would otherwise be used.
+// In order to unmarshal using a specific Registry the wrapper must exist
+// before unmarshaling (e.g. a non-nil *Wrapper field) or
+// the Registry must be provided via UnmarshalWith.
+func (w *Wrapper[T]) SetRegistry(registry wrapper.Registry) {
+	w.registry = registry
+}
+
 // -----------------------------------------------------------------------
 
+const (
+	typeField = "type"
+	dataField = "data"
+)
+
 type packed struct {
 	TypeName string          `json:"type"`
 	RawForm  json.RawMessage `json:"data"`
 }
 
 func (w *Wrapper[T]) MarshalJSON() ([]byte, error) {
+	return w.marshalWith(nil)
+}
+
+func (w *Wrapper[T]) marshalWith(opts *options) ([]byte, error) {
+	if w.IsZero() {
+		return jsonNull, nil
+	}
+
 	var err error
 	var pack packed
-	if pack.TypeName, err = reg.NameFor(w.item); err != nil {
+	if pack.TypeName, err = wrapper.NameFor(w.item, w.registry, opts.getRegistry()); err != nil {
 		return nil, fmt.Errorf("get type name for %#v: %w", w.item, err)
 	}
 
@@ -55,6 +131,10 @@ func (w *Wrapper[T]) MarshalJSON() ([]byte, error) {
 	// Must get rid of extraneous ending newline that is not unmarshaled.
 	pack.RawForm = []byte(strings.TrimSuffix(build.String(), "\n"))
 
+	if w.inline {
+		return marshalInline(pack)
+	}
+
 	var marshaled []byte
 	marshaled, err = json.Marshal(pack)
 	if err != nil {
@@ -63,25 +143,147 @@ func (w *Wrapper[T]) MarshalJSON() ([]byte, error) {
 	return marshaled, nil
 }
 
-var errEmptyTypeField = errors.New("empty type field")
+var (
+	// ErrInlineCollision is returned when marshaling an item in inline mode
+	// that has its own type field or only a data field.
+	ErrInlineCollision = errors.New("item field collides with envelope field")
 
+	// ErrInlineNotObject is returned when marshaling an item in inline mode
+	// that does not serialize as a JSON object.
+	ErrInlineNotObject = errors.New("inline item not a JSON object")
+
+	// ErrInlineTypeString is returned when unmarshaling an inline item
+	// with a type field that is not a string.
+	ErrInlineTypeString = errors.New("inline type field not a string")
+)
+
+// UnmarshalJSON unwraps the serialized item.
+// Errors are returned as *wrapper.DecodeError.
 func (w *Wrapper[T]) UnmarshalJSON(marshaled []byte) error {
-	var pack packed
-	if err := json.Unmarshal(marshaled, &pack); err != nil {
-		return fmt.Errorf("unmarshal packed area: %w", err)
+	return w.unmarshalWith(nil, marshaled)
+}
+
+func (w *Wrapper[T]) unmarshalWith(opts *options, marshaled []byte) error {
+	if bytes.Equal(bytes.TrimSpace(marshaled), jsonNull) {
+		var zero T
+		w.item = zero
+		return nil
+	}
+
+	pack, inline, err := unpack(marshaled)
+	if err != nil {
+		return wrapper.NewDecodeError[T](wrapper.PhaseEnvelope, pack.TypeName, err)
+	}
+	item, err := wrapper.Make[T](pack.TypeName, w.registry, opts.getRegistry())
+	if err != nil {
+		return err
+	} else if err = json.NewDecoder(strings.NewReader(string(pack.RawForm))).Decode(&item); err != nil {
+		return wrapper.NewDecodeError[T](wrapper.PhaseDecode, pack.TypeName, err)
 	}
+	w.item = item
+	w.inline = inline
+	return nil
+}
 
-	var ok bool
+// unpack returns the packed form of a serialized item
+// which may be in either the packed or inline form.
+// The returned flag is true if the inline form was found.
+func unpack(marshaled []byte) (packed, bool, error) {
+	var pack packed
+	inline, err := isInline(marshaled)
+	if err != nil {
+		return pack, false, err
+	} else if inline {
+		if pack, err = unmarshalInline(marshaled); err != nil {
+			return pack, true, err
+		}
+	} else if err = json.Unmarshal(marshaled, &pack); err != nil {
+		return pack, false, err
+	}
 	if pack.TypeName == "" {
-		return errEmptyTypeField
-	} else if temp, err := reg.Make(pack.TypeName); err != nil {
-		return fmt.Errorf("make instance of type %s: %w", pack.TypeName, err)
-	} else if err = json.NewDecoder(strings.NewReader(string(pack.RawForm))).Decode(&temp); err != nil {
-		return fmt.Errorf("decode wrapper contents: %w", err)
-	} else if w.item, ok = temp.(T); !ok {
-		// TODO(mAdkins): How to get name of T?
-		return fmt.Errorf("type %s not generic type", pack.TypeName)
-	} else {
-		return nil
+		return pack, inline, wrapper.ErrEmptyTypeField
+	}
+	return pack, inline, nil
+}
+
+// -----------------------------------------------------------------------
+
+// marshalInline merges the type name from the packed form into the marshaled item.
+// The marshaled item must be a JSON object without its own type field.
+// An item with only a data field would be read as the packed form so it is also rejected.
+func marshalInline(pack packed) ([]byte, error) {
+	raw := bytes.TrimSpace(pack.RawForm)
+	if len(raw) < 2 || raw[0] != '{' {
+		return nil, fmt.Errorf("%w: %s", ErrInlineNotObject, pack.TypeName)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("unmarshal inline item: %w", err)
+	} else if _, found := fields[typeField]; found {
+		return nil, fmt.Errorf("%w: %s", ErrInlineCollision, pack.TypeName)
+	} else if _, found = fields[dataField]; found && len(fields) == 1 {
+		return nil, fmt.Errorf("%w: %s", ErrInlineCollision, pack.TypeName)
+	}
+
+	typeName, err := json.Marshal(pack.TypeName)
+	if err != nil {
+		return nil, fmt.Errorf("marshal type name: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"` + typeField + `":`)
+	buf.Write(typeName)
+	if len(fields) > 0 {
+		buf.WriteByte(',')
 	}
+	// Item object contents follow the type name, without the opening brace.
+	buf.Write(raw[1:])
+	return buf.Bytes(), nil
+}
+
+// unmarshalInline splits marshaled inline JSON into a packed form.
+// The type field is removed from the item data.
+func unmarshalInline(marshaled []byte) (packed, error) {
+	var pack packed
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(marshaled, &fields); err != nil {
+		return pack, fmt.Errorf("unmarshal inline object: %w", err)
+	}
+
+	if rawType, found := fields[typeField]; !found {
+		return pack, wrapper.ErrEmptyTypeField
+	} else if err := json.Unmarshal(rawType, &pack.TypeName); err != nil {
+		return pack, ErrInlineTypeString
+	}
+	delete(fields, typeField)
+
+	var err error
+	if pack.RawForm, err = json.Marshal(fields); err != nil {
+		return pack, fmt.Errorf("marshal inline item: %w", err)
+	}
+	return pack, nil
+}
+
+// isInline returns true if the marshaled JSON object is not in the packed form.
+// The packed form has a data field and no fields other than type and data.
+func isInline(marshaled []byte) (bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(marshaled, &fields); err != nil {
+		return false, err
+	}
+	if _, found := fields[dataField]; !found {
+		return true, nil
+	}
+	for name := range fields {
+		if name != typeField && name != dataField {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// heldItems returns the wrapped item for use by collectTargets.
+func (w *Wrapper[T]) heldItems() []interface{} {
+	return []interface{}{w.item}
 }
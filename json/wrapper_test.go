@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -14,6 +15,7 @@ import (
 	"github.com/madkins23/go-type/reg"
 
 	"github.com/madkins23/go-serial/test"
+	"github.com/madkins23/go-serial/wrapper"
 )
 
 type JsonWrapperTestSuite struct {
@@ -32,6 +34,10 @@ func (suite *JsonWrapperTestSuite) SetupSuite() {
 	suite.Require().NoError(test.Register())
 	suite.Require().NoError(reg.Register(Bond{}))
 	suite.Require().NoError(reg.Register(WrappedBond{}))
+	suite.Require().NoError(reg.Register(Typed{}))
+	suite.Require().NoError(reg.Register(DataOnly{}))
+	suite.Require().NoError(reg.Register(DataAndMore{}))
+	suite.Require().NoError(reg.Register(Count(0)))
 }
 
 func TestJsonWrapperSuite(t *testing.T) {
@@ -61,6 +67,151 @@ func (suite *JsonWrapperTestSuite) TestWrapper() {
 	suite.Assert().Contains(marshaled, "[test]Stock")
 }
 
+// TestWrapper_Nil verifies the serialization of nil items.
+func (suite *JsonWrapperTestSuite) TestWrapper_Nil() {
+	var nilWrapper *Wrapper[test.Investment]
+	suite.Assert().True(nilWrapper.IsZero())
+	marshaled, err := nilWrapper.MarshalJSON()
+	suite.Require().NoError(err)
+	suite.Assert().Equal("null", string(marshaled))
+
+	var nilStock *test.Stock
+	for _, wrapped := range []*Wrapper[test.Investment]{
+		Wrap[test.Investment](nil), Wrap[test.Investment](nilStock), WrapInline[test.Investment](nil),
+	} {
+		suite.Assert().True(wrapped.IsZero())
+		marshaled, err = json.Marshal(wrapped)
+		suite.Require().NoError(err)
+		suite.Assert().Equal("null", string(marshaled))
+	}
+
+	holder := &struct {
+		Item Wrapper[test.Investment]
+	}{}
+	holder.Item.Set(test.MakeCostco())
+	suite.Assert().False(holder.Item.IsZero())
+	suite.Require().NoError(json.Unmarshal([]byte(`{"Item":null}`), holder))
+	suite.Assert().Nil(holder.Item.Get())
+	suite.Assert().True(holder.Item.IsZero())
+}
+
+// TestWrapper_OmitZero verifies that the omitzero field tag option
+// applies to wrappers with nil items.
+func (suite *JsonWrapperTestSuite) TestWrapper_OmitZero() {
+	holder := &struct {
+		Item *Wrapper[test.Investment] `json:",omitzero"`
+	}{Item: Wrap[test.Investment](nil)}
+	marshaled, err := json.Marshal(holder)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("{}", string(marshaled))
+}
+
+func (suite *JsonWrapperTestSuite) TestInline() {
+	wrapped := WrapInline[test.Investment](test.MakeWalmart())
+	suite.Require().NotNil(wrapped)
+	suite.Assert().True(wrapped.Inline())
+	marshaledBytes, err := json.Marshal(wrapped)
+	suite.Require().NoError(err)
+	marshaled := string(marshaledBytes)
+	suite.Assert().True(strings.HasPrefix(marshaled, `{"type":"[test]Stock","Market":"NYSE",`))
+	suite.Assert().NotContains(marshaled, "data\":")
+
+	unwrapped := new(Wrapper[test.Investment])
+	suite.Require().NoError(json.Unmarshal(marshaledBytes, unwrapped))
+	suite.Assert().True(unwrapped.Inline())
+	suite.Assert().Equal(wrapped, unwrapped)
+	remarshaled, err := json.Marshal(unwrapped)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(marshaled, string(remarshaled))
+}
+
+func (suite *JsonWrapperTestSuite) TestInline_Empty() {
+	marshaled, err := json.Marshal(WrapInline[test.Borrower](test.TBillSource()))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`{"type":"[test]Federal"}`, string(marshaled))
+	unwrapped := new(Wrapper[test.Borrower])
+	suite.Require().NoError(json.Unmarshal(marshaled, unwrapped))
+	suite.Assert().Equal(test.TBillSource(), unwrapped.Get())
+}
+
+func (suite *JsonWrapperTestSuite) TestInline_Errors() {
+	_, err := json.Marshal(WrapInline[any](&Typed{Type: "oops"}))
+	suite.Assert().ErrorIs(err, ErrInlineCollision)
+	_, err = json.Marshal(WrapInline[any](&DataOnly{Data: "hello"}))
+	suite.Assert().ErrorIs(err, ErrInlineCollision)
+	_, err = json.Marshal(WrapInline[any](Count(17)))
+	suite.Assert().ErrorIs(err, ErrInlineNotObject)
+	unwrapped := new(Wrapper[test.Investment])
+	suite.Assert().ErrorIs(json.Unmarshal([]byte(`{"Market":"NYSE"}`), unwrapped), wrapper.ErrEmptyTypeField)
+	suite.Assert().ErrorIs(json.Unmarshal([]byte(`{"type":17,"Market":"NYSE"}`), unwrapped), ErrInlineTypeString)
+}
+
+// TestInline_DataField verifies that items with a data field round trip.
+func (suite *JsonWrapperTestSuite) TestInline_DataField() {
+	for _, wrapped := range []*Wrapper[any]{
+		Wrap[any](&DataOnly{Data: "hello"}),
+		Wrap[any](&DataAndMore{Data: "hello", Name: "Fred"}),
+		WrapInline[any](&DataAndMore{Data: "hello", Name: "Fred"}),
+	} {
+		marshaled, err := json.Marshal(wrapped)
+		suite.Require().NoError(err)
+		unwrapped := new(Wrapper[any])
+		suite.Require().NoError(json.Unmarshal(marshaled, unwrapped), string(marshaled))
+		suite.Assert().Equal(wrapped, unwrapped, string(marshaled))
+	}
+}
+
+// TestDecodeError verifies the phase and type information in decode errors.
+func (suite *JsonWrapperTestSuite) TestDecodeError() {
+	for _, tc := range []struct {
+		json     string
+		phase    wrapper.Phase
+		typeName string
+		is       error
+	}{
+		{json: `[]`, phase: wrapper.PhaseEnvelope},
+		{json: `{"data":{}}`, phase: wrapper.PhaseEnvelope, is: wrapper.ErrEmptyTypeField},
+		{json: `{"type":"[test]Unknown","data":{}}`, phase: wrapper.PhaseInstantiate, typeName: "[test]Unknown"},
+		{json: `{"type":"[test]Stock","data":[]}`, phase: wrapper.PhaseDecode, typeName: "[test]Stock"},
+		{json: `{"type":"[test]Federal","data":{}}`, phase: wrapper.PhaseTypeAssert, typeName: "[test]Federal",
+			is: wrapper.ErrTypeMismatch},
+	} {
+		err := json.Unmarshal([]byte(tc.json), new(Wrapper[test.Investment]))
+		var decodeErr *wrapper.DecodeError
+		if suite.Assert().ErrorAs(err, &decodeErr, tc.json) {
+			suite.Assert().Equal(tc.phase, decodeErr.Phase, tc.json)
+			suite.Assert().Equal(tc.typeName, decodeErr.TypeName, tc.json)
+			suite.Assert().Equal("test.Investment", decodeErr.Expected.String(), tc.json)
+		}
+		if tc.is != nil {
+			suite.Assert().ErrorIs(err, tc.is, tc.json)
+		}
+	}
+}
+
+// TestFactory verifies that the generated test.Borrower factory is used instead of go-type/reg.
+func (suite *JsonWrapperTestSuite) TestFactory() {
+	// The factory is not registered by importing the test package.
+	suite.Require().Nil(wrapper.GetFactory[test.Borrower]())
+	wrapper.SetFactory[test.Borrower](test.BorrowerFactory())
+	defer wrapper.SetFactory[test.Borrower](nil)
+	registry := reg.Singleton()
+	defer reg.SetSingleton(registry)
+	reg.SetSingleton(reg.NewRegistry())
+
+	wrapped := Wrap[test.Borrower](test.StateBondSource())
+	marshaled, err := json.Marshal(wrapped)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "[test]State")
+	unwrapped := new(Wrapper[test.Borrower])
+	suite.Require().NoError(json.Unmarshal(marshaled, unwrapped))
+	suite.Assert().Equal(wrapped, unwrapped)
+
+	// The empty registry doesn't know about other types.
+	_, err = json.Marshal(Wrap[test.Investment](test.MakeCostco()))
+	suite.Assert().Error(err)
+}
+
 //------------------------------------------------------------------------
 
 // TestNormal tests the "normal" case which requires custom un/marshaling.
@@ -106,6 +257,23 @@ func (suite *JsonWrapperTestSuite) TestWrapped() {
 		})
 }
 
+//------------------------------------------------------------------------
+
+// TestWrappedInline tests a mixture of inline and packed wrappers.
+func (suite *JsonWrapperTestSuite) TestWrappedInline() {
+	portfolio := MakeWrappedPortfolio()
+	for _, position := range portfolio.Positions {
+		if _, ok := position.Get().(*test.Stock); ok {
+			position.SetInline(true)
+		}
+	}
+	MarshalCycle[WrappedPortfolio](suite, portfolio,
+		func(suite *JsonWrapperTestSuite, marshaled string) {
+			suite.Assert().Contains(marshaled, `{"type":"[test]Stock","Market":`)
+			suite.Assert().Contains(marshaled, `{"type":"[test]State","data":`)
+		}, nil)
+}
+
 //////////////////////////////////////////////////////////////////////////
 
 // MarshalCycle has common code for testing a marshal/unmarshal cycle.
@@ -320,3 +488,25 @@ func MakeWrappedTBill() *WrappedBond {
 		Source:   Wrap[test.Borrower](test.TBillSource()),
 	}
 }
+
+//////////////////////////////////////////////////////////////////////////
+// Types that can't be serialized in inline mode.
+
+// Typed has a field that collides with the inline type field.
+type Typed struct {
+	Type string `json:"type"`
+}
+
+// DataOnly has only a field that collides with the data field of the packed form.
+type DataOnly struct {
+	Data string `json:"data"`
+}
+
+// DataAndMore has a data field along with other fields.
+type DataAndMore struct {
+	Data string `json:"data"`
+	Name string
+}
+
+// Count is not serialized as a JSON object.
+type Count int
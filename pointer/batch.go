@@ -0,0 +1,189 @@
+package pointer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BatchFinder returns new Target items for the specified keys to fill in a Cache.
+// The result contains a Target for each key that was found, keys not found are absent.
+// This method can be defined to pull many items out of a DB or other source in one query.
+type BatchFinder func(keys []string) (map[string]Target, error)
+
+// single returns a ContextFinder that calls the BatchFinder for one key.
+// A nil BatchFinder returns a nil ContextFinder.
+func (f BatchFinder) single() ContextFinder {
+	if f == nil {
+		return nil
+	}
+	return func(ctx context.Context, key string) (Target, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		targets, err := f([]string{key})
+		if err != nil {
+			return nil, err
+		} else if target := targets[key]; target != nil {
+			return target, nil
+		}
+		return nil, ErrNoSuchTarget
+	}
+}
+
+// -----------------------------------------------------------------------
+
+// Batch collects references to Target items so that they can be acquired
+// with a single GetTargets call per Cache and group.
+// This avoids calling a Finder once for each reference when decoding a large document.
+//
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	refs    []batchRef
+	after   []func()
+	defined []batchDef
+	missing func(cache *Cache, group, key string) (Target, error)
+}
+
+// batchRef is a reference to a Target item to be acquired by a Batch.
+type batchRef struct {
+	cache      *Cache
+	group, key string
+	set        func(Target) error
+}
+
+// batchDef is a Target defined in the document being decoded.
+type batchDef struct {
+	cache  *Cache
+	target Target
+}
+
+// batchGroup identifies the Cache and group for a GetTargets call.
+type batchGroup struct {
+	cache *Cache
+	group string
+}
+
+// Add a reference to the Target with the specified group and key in the Cache.
+// The set function is called with the Target when the Batch is resolved.
+// A nil Cache is the same as the default Cache.
+func (b *Batch) Add(cache *Cache, group, key string, set func(Target) error) {
+	if cache == nil {
+		cache = defaultCache
+	}
+	b.refs = append(b.refs, batchRef{cache: cache, group: group, key: key, set: set})
+}
+
+// Define adds a Target that was found in full in the data being decoded.
+// When the Batch is resolved the Target is added to the Cache (unless it already has one
+// with the same group and key) and is used for any references to its group and key
+// in preference to Target items from any Cache.
+// This supports references that precede the definition of their Target.
+// A Target with an empty group or key is ignored.
+// A nil Cache is the same as the default Cache.
+func (b *Batch) Define(cache *Cache, target Target) {
+	if cache == nil {
+		cache = defaultCache
+	}
+	b.defined = append(b.defined, batchDef{cache: cache, target: target})
+}
+
+// After adds a function to be called after all references have been set by Resolve.
+// Functions are called in the order in which they were added.
+func (b *Batch) After(fn func()) {
+	b.after = append(b.after, fn)
+}
+
+// SetMissing specifies a function to be called by Resolve for each reference
+// to a Target that can't be found instead of failing.
+// If the function returns a Target it is passed to the set function for the reference.
+// If it returns nil the reference is skipped.
+// If it returns an error Resolve fails with that error.
+func (b *Batch) SetMissing(fn func(cache *Cache, group, key string) (Target, error)) {
+	b.missing = fn
+}
+
+// Len returns the number of references added to the Batch.
+// A nil Batch is acceptable.
+func (b *Batch) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.refs)
+}
+
+// Resolve acquires the Target items for all references in the Batch
+// and passes each one to the set function for its reference.
+// Target items added via Define are used first,
+// the rest are acquired via a single Cache.GetTargets call for each Cache and group.
+// If any Target is not found an ErrNoSuchTarget error is returned
+// unless a function was specified via SetMissing.
+// The Batch is empty after Resolve is called.
+func (b *Batch) Resolve(ctx context.Context) error {
+	refs, after, defined := b.refs, b.after, b.defined
+	b.refs, b.after, b.defined = nil, nil, nil
+
+	local := make(map[string]map[string]Target)
+	for _, def := range defined {
+		group, key := def.target.Group(), def.target.Key()
+		if group == "" || key == "" {
+			// Not usable as a Target.
+			continue
+		} else if err := def.cache.Remember(def.target, false); err != nil &&
+			!errors.Is(err, ErrTargetAlreadyExists) {
+			return fmt.Errorf("define target %s/%s: %w", group, key, err)
+		}
+		if local[group] == nil {
+			local[group] = make(map[string]Target)
+		}
+		if local[group][key] == nil {
+			local[group][key] = def.target
+		}
+	}
+
+	var order []batchGroup
+	keys := make(map[batchGroup][]string)
+	for _, ref := range refs {
+		if local[ref.group][ref.key] != nil {
+			continue
+		}
+		bg := batchGroup{cache: ref.cache, group: ref.group}
+		if _, found := keys[bg]; !found {
+			order = append(order, bg)
+		}
+		keys[bg] = append(keys[bg], ref.key)
+	}
+
+	targets := make(map[batchGroup]map[string]Target, len(order))
+	for _, bg := range order {
+		found, err := bg.cache.GetTargets(ctx, bg.group, keys[bg])
+		if err != nil {
+			return fmt.Errorf("get targets for %s: %w", bg.group, err)
+		}
+		targets[bg] = found
+	}
+
+	for _, ref := range refs {
+		target, found := local[ref.group][ref.key]
+		if !found {
+			target, found = targets[batchGroup{cache: ref.cache, group: ref.group}][ref.key]
+		}
+		if !found && b.missing != nil {
+			var err error
+			if target, err = b.missing(ref.cache, ref.group, ref.key); err != nil {
+				return fmt.Errorf("get target %s/%s: %w", ref.group, ref.key, err)
+			} else if target == nil {
+				continue
+			}
+		} else if !found {
+			return fmt.Errorf("get target %s/%s: %w", ref.group, ref.key, ErrNoSuchTarget)
+		}
+		if err := ref.set(target); err != nil {
+			return fmt.Errorf("set target %s/%s: %w", ref.group, ref.key, err)
+		}
+	}
+	for _, fn := range after {
+		fn()
+	}
+	return nil
+}
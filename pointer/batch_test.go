@@ -0,0 +1,175 @@
+package pointer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchTestSuite struct {
+	suite.Suite
+	cache *Cache
+	calls [][]string
+}
+
+func (suite *BatchTestSuite) SetupTest() {
+	suite.calls = nil
+	suite.cache = NewCache()
+	suite.Require().NoError(suite.cache.SetBatchFinder(testFinder, suite.batchFinder, false))
+}
+
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, new(BatchTestSuite))
+}
+
+// batchFinder finds all keys except testNone.
+func (suite *BatchTestSuite) batchFinder(keys []string) (map[string]Target, error) {
+	suite.calls = append(suite.calls, keys)
+	targets := make(map[string]Target, len(keys))
+	for _, key := range keys {
+		if key != testNone {
+			targets[key] = newTestTarget(testFinder, key, 0)
+		}
+	}
+	return targets, nil
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+func (suite *BatchTestSuite) TestBatchFinder() {
+	suite.Assert().True(suite.cache.HasBatchFinder(testFinder))
+	suite.Assert().False(suite.cache.HasFinder(testFinder))
+	suite.Assert().ErrorIs(suite.cache.SetBatchFinder(testFinder, suite.batchFinder, false), ErrFinderAlreadyExists)
+	suite.Assert().NoError(suite.cache.SetBatchFinder(testFinder, suite.batchFinder, true))
+	suite.Assert().ErrorIs(suite.cache.SetBatchFinder("", suite.batchFinder, false), ErrNoFinderGroup)
+	suite.Assert().ErrorIs(suite.cache.SetBatchFinder(testGroup, nil, false), ErrFinderIsNil)
+	suite.cache.ClearFinders()
+	suite.Assert().False(suite.cache.HasBatchFinder(testFinder))
+}
+
+func (suite *BatchTestSuite) TestGetTargets() {
+	suite.Require().NoError(suite.cache.SetTarget(newTestTarget(testFinder, "a", 1), false))
+	targets, err := suite.cache.GetTargets(context.Background(), testFinder,
+		[]string{"a", "b", "c", "b", testNone})
+	suite.Require().NoError(err)
+	suite.Assert().Len(targets, 3)
+	suite.Assert().Equal(1, targets["a"].(*testTarget).value)
+	suite.Assert().NotContains(targets, testNone)
+	suite.Assert().Equal([][]string{{"b", "c", testNone}}, suite.calls)
+	suite.Assert().True(suite.cache.HasTarget(testFinder, "c"))
+
+	// Everything is now cached.
+	_, err = suite.cache.GetTargets(context.Background(), testFinder, []string{"a", "b", "c"})
+	suite.Require().NoError(err)
+	suite.Assert().Len(suite.calls, 1)
+}
+
+func (suite *BatchTestSuite) TestGetTargets_Finder() {
+	var calls int
+	suite.Require().NoError(suite.cache.SetFinder(testGroup, func(key string) (Target, error) {
+		calls++
+		if key == testNone {
+			return nil, ErrNoSuchTarget
+		}
+		return newTestTarget(testGroup, key, 0), nil
+	}, false))
+	targets, err := suite.cache.GetTargets(context.Background(), testGroup, []string{"a", "b", testNone})
+	suite.Require().NoError(err)
+	suite.Assert().Len(targets, 2)
+	suite.Assert().Equal(3, calls)
+
+	targets, err = suite.cache.GetTargets(context.Background(), badGroup, []string{"a"})
+	suite.Require().NoError(err)
+	suite.Assert().Empty(targets)
+}
+
+func (suite *BatchTestSuite) TestGetTargets_Errors() {
+	failure := errors.New("failure")
+	suite.Require().NoError(suite.cache.SetBatchFinder(testGroup, func(keys []string) (map[string]Target, error) {
+		return nil, failure
+	}, false))
+	_, err := suite.cache.GetTargets(context.Background(), testGroup, []string{"a"})
+	suite.Assert().ErrorIs(err, failure)
+
+	suite.Require().NoError(suite.cache.SetBatchFinder(testGroup, func(keys []string) (map[string]Target, error) {
+		return map[string]Target{"a": newTestTarget(badGroup, "a", 0)}, nil
+	}, true))
+	_, err = suite.cache.GetTargets(context.Background(), testGroup, []string{"a"})
+	suite.Assert().ErrorIs(err, ErrBadTargetGroup)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = suite.cache.GetTargets(ctx, testFinder, []string{"a"})
+	suite.Assert().ErrorIs(err, context.Canceled)
+	suite.Assert().Empty(suite.calls)
+}
+
+// TestGetTarget verifies that GetTarget uses the BatchFinder if there is no Finder.
+func (suite *BatchTestSuite) TestGetTarget() {
+	target, err := suite.cache.GetTarget(testFinder, testKey, nil)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(testKey, target.Key())
+	_, err = suite.cache.GetTarget(testFinder, testNone, nil)
+	suite.Assert().ErrorIs(err, ErrNoSuchTarget)
+	suite.Assert().Equal([][]string{{testKey}, {testNone}}, suite.calls)
+}
+
+func (suite *BatchTestSuite) TestResolve() {
+	other := NewCache()
+	suite.Require().NoError(other.SetTarget(newTestTarget(testGroup, testKey, 0), false))
+	targets := make(map[string]Target)
+	setter := func(name string) func(Target) error {
+		return func(target Target) error {
+			targets[name] = target
+			return nil
+		}
+	}
+	var after []string
+	batch := new(Batch)
+	suite.Assert().Equal(0, batch.Len())
+	batch.Add(suite.cache, testFinder, "a", setter("one"))
+	batch.Add(other, testGroup, testKey, setter("two"))
+	batch.Add(suite.cache, testFinder, "b", setter("three"))
+	batch.Add(suite.cache, testFinder, "a", setter("four"))
+	batch.After(func() { after = append(after, "first") })
+	batch.After(func() { after = append(after, "second") })
+	suite.Assert().Equal(4, batch.Len())
+	suite.Require().NoError(batch.Resolve(context.Background()))
+	suite.Assert().Equal(0, batch.Len())
+	suite.Assert().Len(targets, 4)
+	suite.Assert().Same(targets["one"], targets["four"])
+	suite.Assert().Equal(testGroup, targets["two"].Group())
+	suite.Assert().Equal([][]string{{"a", "b"}}, suite.calls)
+	suite.Assert().Equal([]string{"first", "second"}, after)
+
+	batch.Add(suite.cache, testFinder, testNone, setter("none"))
+	suite.Assert().ErrorIs(batch.Resolve(context.Background()), ErrNoSuchTarget)
+}
+
+func (suite *BatchTestSuite) TestDefine() {
+	suite.Require().NoError(suite.cache.SetTarget(newTestTarget(testGroup, "old", oldValue), false))
+	targets := make(map[string]Target)
+	setter := func(name string) func(Target) error {
+		return func(target Target) error {
+			targets[name] = target
+			return nil
+		}
+	}
+	batch := new(Batch)
+	batch.Add(suite.cache, testGroup, "new", setter("new"))
+	batch.Add(suite.cache, testGroup, "old", setter("old"))
+	batch.Add(suite.cache, testFinder, "a", setter("a"))
+	batch.Define(suite.cache, newTestTarget(testGroup, "new", newValue))
+	batch.Define(suite.cache, newTestTarget(testGroup, "old", newValue))
+	batch.Define(suite.cache, newTestTarget(testGroup, "", 0))
+	suite.Require().NoError(batch.Resolve(context.Background()))
+	suite.Assert().Equal(newValue, targets["new"].(*testTarget).value)
+	suite.Assert().Equal(newValue, targets["old"].(*testTarget).value, "defined takes precedence")
+	suite.Assert().Equal([][]string{{"a"}}, suite.calls)
+	suite.Assert().True(suite.cache.HasTarget(testGroup, "new"))
+	target, err := suite.cache.GetTarget(testGroup, "old", nil)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(oldValue, target.(*testTarget).value, "existing Target not replaced")
+}
@@ -0,0 +1,501 @@
+package pointer
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache holds Target items by group and key along with Finder functions by group.
+// Cache objects are safe for concurrent use.
+//
+// The package functions (e.g. GetTarget and SetFinder) use a default Cache.
+// Separate Cache objects may be used to isolate Target items,
+// for example when decoding separate documents or in tests.
+//
+// By default a Cache keeps every Target item until it is cleared.
+// CacheOption values (e.g. WithMaxEntries and WithGroupTTL) bound the Cache
+// by evicting least recently used or expired Target items.
+// Evicted Target items will be acquired from the Finder for their group when next requested.
+type Cache struct {
+	targets      map[string]*targetGroup
+	lru          *list.List
+	config       cacheConfig
+	evicted      []eviction
+	now          func() time.Time
+	targetMutex  sync.Mutex
+	finders      map[string]ContextFinder
+	batches      map[string]BatchFinder
+	placeholders map[string]Placeholder
+	storers      map[string]Storer
+	finderMutex  sync.RWMutex
+	storeMutex   sync.Mutex
+}
+
+// targetGroup holds the Target items for a group in a Cache.
+type targetGroup struct {
+	entries map[string]*targetEntry
+	lru     *list.List
+}
+
+// targetEntry holds a Target item in a Cache along with its eviction data.
+// The entry is in both the Cache and targetGroup least recently used lists,
+// with the most recently used entries at the front.
+type targetEntry struct {
+	target  Target
+	expires time.Time
+	all     *list.Element
+	inGroup *list.Element
+}
+
+// eviction records an evicted Target item until the eviction function can be called.
+type eviction struct {
+	target Target
+	reason EvictionReason
+}
+
+// NewCache returns a new, empty Cache configured by the specified CacheOption values.
+func NewCache(opts ...CacheOption) *Cache {
+	c := &Cache{
+		targets:      make(map[string]*targetGroup),
+		lru:          list.New(),
+		now:          time.Now,
+		finders:      make(map[string]ContextFinder),
+		batches:      make(map[string]BatchFinder),
+		placeholders: make(map[string]Placeholder),
+		storers:      make(map[string]Storer),
+	}
+	c.Configure(opts...)
+	return c
+}
+
+var defaultCache = NewCache()
+
+// DefaultCache returns the Cache used by the package functions.
+// Use Cache.Configure to bound the default Cache.
+func DefaultCache() *Cache {
+	return defaultCache
+}
+
+// Configure applies the specified CacheOption values to the Cache.
+// Options not specified are left unchanged.
+// Target items in excess of any new limits are evicted immediately.
+func (c *Cache) Configure(opts ...CacheOption) {
+	c.targetMutex.Lock()
+	defer c.unlockTargets()
+	for _, opt := range opts {
+		opt(&c.config)
+	}
+	for group := range c.targets {
+		c.trimGroup(group)
+	}
+	c.trim()
+}
+
+//------------------------------------------------------------------------
+
+// ClearTargets removes all Target items from the Cache.
+// The eviction function is not called for these Target items.
+func (c *Cache) ClearTargets() {
+	c.targetMutex.Lock()
+	defer c.unlockTargets()
+	c.targets = make(map[string]*targetGroup)
+	c.lru.Init()
+}
+
+// Len returns the number of Target items in the Cache, including any that have expired.
+func (c *Cache) Len() int {
+	c.targetMutex.Lock()
+	defer c.unlockTargets()
+	return c.lru.Len()
+}
+
+// Prune evicts all expired Target items from the Cache.
+// Expired Target items are also evicted as they are requested,
+// so this is only necessary to release memory held by Target items that are no longer used.
+func (c *Cache) Prune() {
+	c.targetMutex.Lock()
+	defer c.unlockTargets()
+	now := c.now()
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if entry := elem.Value.(*targetEntry); entry.expired(now) {
+			c.evict(entry, EvictedExpired)
+		}
+		elem = next
+	}
+}
+
+// HasTarget returns true if the specified group and key have a Target in the Cache.
+func (c *Cache) HasTarget(group, key string) bool {
+	return c.lookupTarget(group, key) != nil
+}
+
+// lookupTarget returns the Target in the Cache for the group and key or nil.
+// An expired Target is evicted and nil is returned.
+// A returned Target becomes the most recently used.
+func (c *Cache) lookupTarget(group, key string) Target {
+	c.targetMutex.Lock()
+	defer c.unlockTargets()
+	if entry := c.entry(group, key); entry != nil {
+		c.touch(entry)
+		return entry.target
+	}
+	return nil
+}
+
+// GetTarget returns a Target object from the Cache for use in Pointer implementations.
+// If there is no such Target the specified Finder is used or,
+// if that is nil, the Finder or BatchFinder for the group in the Cache.
+// If there is no Finder the ErrNoSuchTarget error is returned.
+// If the Finder is used to acquire the Target it is added to the Cache and returned.
+//
+// The Finder is called without holding any lock so concurrent calls for the same
+// group and key may each call the Finder. In that case the first Target
+// added to the Cache is returned from all such calls.
+func (c *Cache) GetTarget(group, key string, finder Finder) (Target, error) {
+	return c.GetTargetContext(context.Background(), group, key, finder.withContext())
+}
+
+// GetTargetContext returns a Target object from the Cache as does GetTarget,
+// passing the specified context to the ContextFinder.
+// A Target already in the Cache is returned regardless of the context.
+// Otherwise, if the context is done its error is returned without calling the ContextFinder.
+func (c *Cache) GetTargetContext(ctx context.Context, group, key string, finder ContextFinder) (Target, error) {
+	if target := c.lookupTarget(group, key); target != nil {
+		return target, nil
+	}
+	if finder == nil {
+		finder = c.GetContextFinder(group)
+	}
+	if finder == nil {
+		finder = c.GetBatchFinder(group).single()
+	}
+	if finder == nil {
+		return nil, ErrNoSuchTarget
+	} else if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("find item: %w", err)
+	} else if target, err := finder(ctx, key); err != nil {
+		return nil, fmt.Errorf("find item: %w", err)
+	} else if target == nil {
+		return nil, ErrFinderTargetIsNil
+	} else if target.Group() != group {
+		return nil, ErrBadTargetGroup
+	} else if target.Key() != key {
+		return nil, ErrBadTargetKey
+	} else if err := c.Remember(target, false); errors.Is(err, ErrTargetAlreadyExists) {
+		// Another goroutine added the Target first.
+		if existing := c.lookupTarget(group, key); existing != nil {
+			return existing, nil
+		}
+		return target, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("set target: %w", err)
+	} else {
+		return target, nil
+	}
+}
+
+// GetTargets returns Target objects for the specified group and keys from the Cache.
+// Keys without a Target in the Cache are passed in a single call to the
+// BatchFinder for the group or, if there is none, to the Finder for the group one at a time.
+// Target items acquired in this way are added to the Cache.
+// The result contains a Target for each key that was found, keys not found are absent.
+func (c *Cache) GetTargets(ctx context.Context, group string, keys []string) (map[string]Target, error) {
+	found := make(map[string]Target, len(keys))
+	missing := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if target := c.lookupTarget(group, key); target != nil {
+			found[key] = target
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) < 1 {
+		return found, nil
+	}
+
+	batch := c.GetBatchFinder(group)
+	if batch == nil {
+		for _, key := range missing {
+			if target, err := c.GetTargetContext(ctx, group, key, nil); errors.Is(err, ErrNoSuchTarget) {
+				continue
+			} else if err != nil {
+				return nil, err
+			} else {
+				found[key] = target
+			}
+		}
+		return found, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("find items: %w", err)
+	}
+	targets, err := batch(missing)
+	if err != nil {
+		return nil, fmt.Errorf("find items: %w", err)
+	}
+	for _, key := range missing {
+		target := targets[key]
+		if target == nil {
+			continue
+		} else if target.Group() != group {
+			return nil, ErrBadTargetGroup
+		} else if target.Key() != key {
+			return nil, ErrBadTargetKey
+		} else if err := c.Remember(target, false); errors.Is(err, ErrTargetAlreadyExists) {
+			// Another goroutine added the Target first.
+			if existing := c.lookupTarget(group, key); existing != nil {
+				target = existing
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("set target: %w", err)
+		}
+		found[key] = target
+	}
+	return found, nil
+}
+
+// SetTarget adds the specified Target to the Cache.
+// Use this method for application writes and preloading the Cache.
+// If the group has a Storer the Target is first passed to it (see Storer).
+// Calls to SetTarget for groups with a Storer are serialized
+// so that a Target is only stored once unless replace is true.
+func (c *Cache) SetTarget(target Target, replace bool) error {
+	if target != nil && target.Group() != "" && target.Key() != "" {
+		if store := c.GetStorer(target.Group()); store != nil {
+			c.storeMutex.Lock()
+			defer c.storeMutex.Unlock()
+			if !replace && c.peekTarget(target.Group(), target.Key()) {
+				return ErrTargetAlreadyExists
+			} else if err := store(target); err != nil {
+				return fmt.Errorf("store target: %w", err)
+			}
+		}
+	}
+	return c.Remember(target, replace)
+}
+
+// Remember adds the specified Target to the Cache without calling the Storer for its group.
+// Use this method for Pointer implementations that only mean to cache Target items.
+// The Target becomes the most recently used and its time to live starts over,
+// which may cause other Target items to be evicted.
+func (c *Cache) Remember(target Target, replace bool) error {
+	if target == nil {
+		return ErrTargetIsNil
+	} else if group := target.Group(); group == "" {
+		return ErrNoTargetGroup
+	} else if key := target.Key(); key == "" {
+		return ErrNoTargetKey
+	} else {
+		c.targetMutex.Lock()
+		defer c.unlockTargets()
+		if entry := c.entry(group, key); entry != nil {
+			if !replace {
+				return ErrTargetAlreadyExists
+			}
+			entry.target = target
+			entry.expires = c.expires(group)
+			c.touch(entry)
+			return nil
+		}
+		tg, found := c.targets[group]
+		if !found {
+			tg = &targetGroup{entries: make(map[string]*targetEntry), lru: list.New()}
+			c.targets[group] = tg
+		}
+		entry := &targetEntry{target: target, expires: c.expires(group)}
+		entry.all = c.lru.PushFront(entry)
+		entry.inGroup = tg.lru.PushFront(entry)
+		tg.entries[key] = entry
+		c.trimGroup(group)
+		c.trim()
+		return nil
+	}
+}
+
+// -----------------------------------------------------------------------
+
+// unlockTargets releases the target lock and then calls the eviction function
+// for any Target items evicted while the lock was held.
+func (c *Cache) unlockTargets() {
+	evicted, onEvict := c.evicted, c.config.onEvict
+	c.evicted = nil
+	c.targetMutex.Unlock()
+	if onEvict != nil {
+		for _, ev := range evicted {
+			onEvict(ev.target, ev.reason)
+		}
+	}
+}
+
+// entry returns the unexpired entry for the group and key or nil.
+// An expired entry is evicted.
+// The target lock must be held.
+func (c *Cache) entry(group, key string) *targetEntry {
+	tg, found := c.targets[group]
+	if !found {
+		return nil
+	}
+	entry, found := tg.entries[key]
+	if !found || entry == nil || entry.target == nil {
+		return nil
+	} else if entry.expired(c.now()) {
+		c.evict(entry, EvictedExpired)
+		return nil
+	}
+	return entry
+}
+
+// expires returns the expiration time for a new entry in the specified group.
+func (c *Cache) expires(group string) time.Time {
+	if ttl := c.config.ttlFor(group); ttl > 0 {
+		return c.now().Add(ttl)
+	}
+	return time.Time{}
+}
+
+// touch makes the entry the most recently used.
+// The target lock must be held.
+func (c *Cache) touch(entry *targetEntry) {
+	c.lru.MoveToFront(entry.all)
+	c.targets[entry.target.Group()].lru.MoveToFront(entry.inGroup)
+}
+
+// evict removes the entry and records it for the eviction function.
+// The target lock must be held.
+func (c *Cache) evict(entry *targetEntry, reason EvictionReason) {
+	group := entry.target.Group()
+	tg := c.targets[group]
+	delete(tg.entries, entry.target.Key())
+	tg.lru.Remove(entry.inGroup)
+	c.lru.Remove(entry.all)
+	if len(tg.entries) == 0 {
+		delete(c.targets, group)
+	}
+	c.evicted = append(c.evicted, eviction{target: entry.target, reason: reason})
+}
+
+// trimGroup evicts the least recently used entries in excess of the group limit.
+// The target lock must be held.
+func (c *Cache) trimGroup(group string) {
+	max := c.config.groupMax[group]
+	if max <= 0 {
+		return
+	}
+	for tg := c.targets[group]; tg != nil && tg.lru.Len() > max; tg = c.targets[group] {
+		c.evict(tg.lru.Back().Value.(*targetEntry), EvictedCapacity)
+	}
+}
+
+// trim evicts the least recently used entries in excess of the Cache limit.
+// The target lock must be held.
+func (c *Cache) trim() {
+	if c.config.maxEntries <= 0 {
+		return
+	}
+	for c.lru.Len() > c.config.maxEntries {
+		c.evict(c.lru.Back().Value.(*targetEntry), EvictedCapacity)
+	}
+}
+
+// expired returns true if the entry has a time to live that ended before now.
+func (entry *targetEntry) expired(now time.Time) bool {
+	return !entry.expires.IsZero() && !now.Before(entry.expires)
+}
+
+//------------------------------------------------------------------------
+
+// ClearFinders removes all Finder, Placeholder and Storer functions from the Cache.
+func (c *Cache) ClearFinders() {
+	c.finderMutex.Lock()
+	defer c.finderMutex.Unlock()
+	c.finders = make(map[string]ContextFinder)
+	c.batches = make(map[string]BatchFinder)
+	c.placeholders = make(map[string]Placeholder)
+	c.storers = make(map[string]Storer)
+}
+
+// HasFinder returns true if the specified group has a Finder or ContextFinder in the Cache.
+func (c *Cache) HasFinder(group string) bool {
+	return c.GetContextFinder(group) != nil
+}
+
+// GetFinder returns the Finder for the specified group or nil if there is none.
+// If the group has a ContextFinder it is called with context.Background().
+func (c *Cache) GetFinder(group string) Finder {
+	return c.GetContextFinder(group).withoutContext()
+}
+
+// GetContextFinder returns the ContextFinder for the specified group or nil if there is none.
+// If the group has a Finder it is called without the context.
+func (c *Cache) GetContextFinder(group string) ContextFinder {
+	c.finderMutex.RLock()
+	defer c.finderMutex.RUnlock()
+	return c.finders[group]
+}
+
+// SetFinder configures a Finder for the specified group.
+func (c *Cache) SetFinder(group string, finder Finder, replace bool) error {
+	if finder == nil {
+		return ErrFinderIsNil
+	}
+	return c.SetContextFinder(group, finder.withContext(), replace)
+}
+
+// SetContextFinder configures a ContextFinder for the specified group.
+// A group has either a Finder or a ContextFinder, not both.
+func (c *Cache) SetContextFinder(group string, finder ContextFinder, replace bool) error {
+	if group == "" {
+		return ErrNoFinderGroup
+	} else if finder == nil {
+		return ErrFinderIsNil
+	}
+
+	c.finderMutex.Lock()
+	defer c.finderMutex.Unlock()
+	if c.finders[group] != nil && !replace {
+		return ErrFinderAlreadyExists
+	}
+	c.finders[group] = finder
+	return nil
+}
+
+// HasBatchFinder returns true if the specified group has a BatchFinder in the Cache.
+func (c *Cache) HasBatchFinder(group string) bool {
+	return c.GetBatchFinder(group) != nil
+}
+
+// GetBatchFinder returns the BatchFinder for the specified group or nil if there is none.
+func (c *Cache) GetBatchFinder(group string) BatchFinder {
+	c.finderMutex.RLock()
+	defer c.finderMutex.RUnlock()
+	return c.batches[group]
+}
+
+// SetBatchFinder configures a BatchFinder for the specified group.
+// A group may have a BatchFinder in addition to a Finder or ContextFinder.
+func (c *Cache) SetBatchFinder(group string, finder BatchFinder, replace bool) error {
+	if group == "" {
+		return ErrNoFinderGroup
+	} else if finder == nil {
+		return ErrFinderIsNil
+	}
+
+	c.finderMutex.Lock()
+	defer c.finderMutex.Unlock()
+	if c.batches[group] != nil && !replace {
+		return ErrFinderAlreadyExists
+	}
+	c.batches[group] = finder
+	return nil
+}
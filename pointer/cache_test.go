@@ -0,0 +1,85 @@
+package pointer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+}
+
+func (suite *CacheTestSuite) SetupTest() {
+	ClearFinderCache()
+	ClearTargetCache()
+}
+
+func (suite *CacheTestSuite) TearDownTest() {
+	ClearFinderCache()
+	ClearTargetCache()
+}
+
+func TestCacheSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+func (suite *CacheTestSuite) TestDefaultCache() {
+	suite.Require().NotNil(DefaultCache())
+	suite.Assert().Same(DefaultCache(), DefaultCache())
+	suite.Require().NoError(SetTarget(newTestTarget(testGroup, testKey, oldValue), false))
+	suite.Assert().True(DefaultCache().HasTarget(testGroup, testKey))
+	suite.Require().NoError(DefaultCache().SetFinder(testFinder, testFinderFn, false))
+	suite.Assert().True(HasFinder(testFinder))
+}
+
+// TestIsolation verifies that separate Cache objects don't share Target items or Finder functions.
+func (suite *CacheTestSuite) TestIsolation() {
+	one, two := NewCache(), NewCache()
+	suite.Require().NoError(one.SetTarget(newTestTarget(testGroup, testKey, oldValue), false))
+	suite.Require().NoError(two.SetTarget(newTestTarget(testGroup, testKey, newValue), false))
+	suite.Assert().False(HasTarget(testGroup, testKey))
+
+	target, err := one.GetTarget(testGroup, testKey, nil)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(oldValue, target.(*testTarget).value)
+	target, err = two.GetTarget(testGroup, testKey, nil)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(newValue, target.(*testTarget).value)
+
+	suite.Require().NoError(one.SetFinder(testFinder, testFinderFn, false))
+	suite.Assert().True(one.HasFinder(testFinder))
+	suite.Assert().False(two.HasFinder(testFinder))
+	suite.Assert().False(HasFinder(testFinder))
+	target, err = one.GetTarget(testFinder, testKey, nil)
+	suite.Require().NoError(err)
+	suite.Assert().True(one.HasTarget(testFinder, testKey))
+	suite.Assert().Equal(testKey, target.Key())
+	_, err = two.GetTarget(testFinder, testKey, nil)
+	suite.Assert().ErrorIs(err, ErrNoSuchTarget)
+
+	one.ClearTargets()
+	suite.Assert().False(one.HasTarget(testGroup, testKey))
+	suite.Assert().True(two.HasTarget(testGroup, testKey))
+	one.ClearFinders()
+	suite.Assert().False(one.HasFinder(testFinder))
+}
+
+func (suite *CacheTestSuite) TestErrors() {
+	cache := NewCache()
+	suite.Assert().ErrorIs(cache.SetTarget(nil, false), ErrTargetIsNil)
+	suite.Assert().ErrorIs(cache.SetTarget(newTestTarget("", testKey, 0), false), ErrNoTargetGroup)
+	suite.Assert().ErrorIs(cache.SetTarget(newTestTarget(testGroup, "", 0), false), ErrNoTargetKey)
+	suite.Require().NoError(cache.SetTarget(newTestTarget(testGroup, testKey, oldValue), false))
+	suite.Assert().ErrorIs(cache.SetTarget(newTestTarget(testGroup, testKey, newValue), false), ErrTargetAlreadyExists)
+	suite.Assert().ErrorIs(cache.SetFinder("", testFinderFn, false), ErrNoFinderGroup)
+	suite.Assert().ErrorIs(cache.SetFinder(testFinder, nil, false), ErrFinderIsNil)
+	suite.Require().NoError(cache.SetFinder(testFinder, testFinderFn, false))
+	suite.Assert().ErrorIs(cache.SetFinder(testFinder, testFinderFn, false), ErrFinderAlreadyExists)
+}
+
+func testFinderFn(key string) (Target, error) {
+	return newTestTarget(testFinder, key, 0), nil
+}
@@ -0,0 +1,187 @@
+package pointer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Resolution specifies how a reference found by an integrity check can be resolved.
+type Resolution int
+
+const (
+	// Dangling means that the Target can't be found.
+	Dangling Resolution = iota
+
+	// InDocument means that the Target is embedded in the checked document.
+	InDocument
+
+	// InCache means that the Target is in the Cache.
+	InCache
+
+	// ByFinder means that the Target can be acquired by the Finder or BatchFinder for its group.
+	ByFinder
+)
+
+func (r Resolution) String() string {
+	switch r {
+	case Dangling:
+		return "dangling"
+	case InDocument:
+		return "document"
+	case InCache:
+		return "cache"
+	case ByFinder:
+		return "finder"
+	default:
+		return fmt.Sprintf("Resolution(%d)", int(r))
+	}
+}
+
+// CheckedReference is a reference to a Target found in a serialized document
+// by an integrity check (e.g. json.CheckReferences).
+type CheckedReference struct {
+	Group, Key string
+
+	// Path is the RFC 6901 JSON pointer to the reference within the document.
+	Path string
+
+	// Line and Column locate the start of the reference within the document, starting from 1.
+	Line, Column int
+
+	// Embedded is true if the Target is embedded in the reference.
+	Embedded bool
+
+	// Resolution specifies how the reference can be resolved.
+	Resolution Resolution
+}
+
+func (r CheckedReference) String() string {
+	return fmt.Sprintf("%s/%s at %s (%d:%d): %s", r.Group, r.Key, r.Path, r.Line, r.Column, r.Resolution)
+}
+
+// CheckReport lists all references found in a serialized document by an integrity check
+// in the order in which they appear.
+type CheckReport struct {
+	References []CheckedReference
+}
+
+// Dangling returns the references in the CheckReport that can't be resolved.
+func (r *CheckReport) Dangling() []CheckedReference {
+	var dangling []CheckedReference
+	for _, ref := range r.References {
+		if ref.Resolution == Dangling {
+			dangling = append(dangling, ref)
+		}
+	}
+	return dangling
+}
+
+// OK returns true if all references in the CheckReport can be resolved.
+func (r *CheckReport) OK() bool {
+	return len(r.Dangling()) < 1
+}
+
+// -----------------------------------------------------------------------
+
+// Check sets the Resolution of each reference and returns a CheckReport of the references.
+// References with a Resolution other than Dangling are left unchanged.
+// References to Target items embedded anywhere in the references are InDocument.
+// Otherwise the Cache and then the Finder or BatchFinder for the group are checked.
+// Each BatchFinder is called once per group for all keys not in the Cache.
+//
+// The Cache is not changed: Target items acquired from Finder functions are not added,
+// expired Target items are not evicted, and least recently used order is unchanged.
+// Errors from Finder functions other than ErrNoSuchTarget are returned.
+func (c *Cache) Check(ctx context.Context, refs []CheckedReference) (*CheckReport, error) {
+	embedded := make(map[string]map[string]bool)
+	for _, ref := range refs {
+		if ref.Embedded {
+			if embedded[ref.Group] == nil {
+				embedded[ref.Group] = make(map[string]bool)
+			}
+			embedded[ref.Group][ref.Key] = true
+		}
+	}
+
+	var order []string
+	missing := make(map[string][]string)
+	seen := make(map[DanglingReference]bool)
+	for i, ref := range refs {
+		if ref.Resolution != Dangling {
+			continue
+		} else if embedded[ref.Group][ref.Key] {
+			refs[i].Resolution = InDocument
+		} else if c.peekTarget(ref.Group, ref.Key) {
+			refs[i].Resolution = InCache
+		} else if key := (DanglingReference{Group: ref.Group, Key: ref.Key}); !seen[key] {
+			seen[key] = true
+			if _, found := missing[ref.Group]; !found {
+				order = append(order, ref.Group)
+			}
+			missing[ref.Group] = append(missing[ref.Group], ref.Key)
+		}
+	}
+
+	found := make(map[string]map[string]bool, len(order))
+	for _, group := range order {
+		keys, err := c.findKeys(ctx, group, missing[group])
+		if err != nil {
+			return nil, err
+		}
+		found[group] = keys
+	}
+	for i, ref := range refs {
+		if ref.Resolution == Dangling && found[ref.Group][ref.Key] {
+			refs[i].Resolution = ByFinder
+		}
+	}
+	return &CheckReport{References: refs}, nil
+}
+
+// peekTarget returns true if the Cache has an unexpired Target for the group and key
+// without changing the Cache.
+func (c *Cache) peekTarget(group, key string) bool {
+	c.targetMutex.Lock()
+	defer c.unlockTargets()
+	if tg, found := c.targets[group]; found {
+		entry := tg.entries[key]
+		return entry != nil && entry.target != nil && !entry.expired(c.now())
+	}
+	return false
+}
+
+// findKeys returns the keys for which the Finder or BatchFinder for the group returns a Target
+// without adding the Target items to the Cache.
+func (c *Cache) findKeys(ctx context.Context, group string, keys []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(keys))
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("check %s: %w", group, err)
+	}
+	if batch := c.GetBatchFinder(group); batch != nil {
+		targets, err := batch(keys)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", group, err)
+		}
+		for _, key := range keys {
+			found[key] = targets[key] != nil
+		}
+	} else if finder := c.GetContextFinder(group); finder != nil {
+		for _, key := range keys {
+			target, err := finder(ctx, key)
+			if errors.Is(err, ErrNoSuchTarget) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("check %s/%s: %w", group, key, err)
+			}
+			found[key] = target != nil && target.Group() == group && target.Key() == key
+		}
+	}
+	return found, nil
+}
+
+// CheckReferences sets the Resolution of each reference using the default Cache.
+// See Cache.Check.
+func CheckReferences(ctx context.Context, refs []CheckedReference) (*CheckReport, error) {
+	return defaultCache.Check(ctx, refs)
+}
@@ -0,0 +1,96 @@
+package pointer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolution_String(t *testing.T) {
+	assert.Equal(t, "dangling", Dangling.String())
+	assert.Equal(t, "document", InDocument.String())
+	assert.Equal(t, "cache", InCache.String())
+	assert.Equal(t, "finder", ByFinder.String())
+	assert.Equal(t, "Resolution(9)", Resolution(9).String())
+}
+
+func TestCheck(t *testing.T) {
+	clock := time.Now()
+	cache := NewCache(WithGroupTTL("expiring", time.Minute))
+	cache.now = func() time.Time { return clock }
+	require.NoError(t, cache.SetTarget(newTestTarget("cached", "a", 0), false))
+	require.NoError(t, cache.SetTarget(newTestTarget("cached", "b", 0), false))
+	require.NoError(t, cache.SetTarget(newTestTarget("expiring", "a", 0), false))
+	clock = clock.Add(time.Hour)
+	var calls []string
+	require.NoError(t, cache.SetFinder(testFinder, func(key string) (Target, error) {
+		calls = append(calls, key)
+		if key == testNone {
+			return nil, ErrNoSuchTarget
+		}
+		return newTestTarget(testFinder, key, 0), nil
+	}, false))
+	var batches [][]string
+	require.NoError(t, cache.SetBatchFinder("batch", func(keys []string) (map[string]Target, error) {
+		batches = append(batches, keys)
+		return map[string]Target{"x": newTestTarget("batch", "x", 0)}, nil
+	}, false))
+
+	refs := []CheckedReference{
+		{Group: "cached", Key: "b", Path: "/0"},
+		{Group: "cached", Key: "a", Path: "/1"},
+		{Group: "cached", Key: "c", Path: "/2"},
+		{Group: "expiring", Key: "a", Path: "/3"},
+		{Group: testFinder, Key: testKey, Path: "/4"},
+		{Group: testFinder, Key: testNone, Path: "/5"},
+		{Group: testFinder, Key: testKey, Path: "/6"},
+		{Group: "batch", Key: "x", Path: "/7"},
+		{Group: "batch", Key: "y", Path: "/8"},
+		{Group: "embedded", Key: "e", Path: "/9"},
+		{Group: "embedded", Key: "e", Path: "/10", Embedded: true},
+		{Group: "", Key: "", Path: "/11", Resolution: InDocument},
+	}
+	report, err := cache.Check(context.Background(), refs)
+	require.NoError(t, err)
+	var resolutions []Resolution
+	for _, ref := range report.References {
+		resolutions = append(resolutions, ref.Resolution)
+	}
+	assert.Equal(t, []Resolution{
+		InCache, InCache, Dangling, Dangling, ByFinder, Dangling,
+		ByFinder, ByFinder, Dangling, InDocument, InDocument, InDocument,
+	}, resolutions)
+	assert.False(t, report.OK())
+	dangling := report.Dangling()
+	require.Len(t, dangling, 4)
+	assert.Equal(t, "/2", dangling[0].Path)
+	assert.Equal(t, "cached/c at /2 (0:0): dangling", dangling[0].String())
+
+	// Each key is only checked once.
+	assert.Equal(t, []string{testKey, testNone}, calls)
+	assert.Equal(t, [][]string{{"x", "y"}}, batches)
+
+	// The Cache is unchanged, including the expired Target and least recently used order.
+	assert.Equal(t, 3, cache.Len())
+	assert.False(t, cache.peekTarget(testFinder, testKey))
+	assert.Equal(t, "expiring", cache.lru.Front().Value.(*targetEntry).target.Group())
+
+	// Finder errors are returned, except ErrNoSuchTarget.
+	require.NoError(t, cache.SetFinder(testFinder, func(key string) (Target, error) {
+		return nil, errors.New("database down")
+	}, true))
+	_, err = cache.Check(context.Background(), []CheckedReference{{Group: testFinder, Key: testKey}})
+	assert.ErrorContains(t, err, "database down")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cache.Check(ctx, []CheckedReference{{Group: testFinder, Key: testKey}})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	report, err = cache.Check(context.Background(), []CheckedReference{{Group: "cached", Key: "a"}})
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+}
@@ -0,0 +1,140 @@
+package pointer
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	stressGroup      = "stressGroup"
+	stressKeys       = 50
+	stressGoroutines = 16
+	stressLoops      = 200
+)
+
+func stressKey(i int) string {
+	return fmt.Sprintf("key-%d", i%stressKeys)
+}
+
+// TestConcurrent_GetSetClear exercises the Target and Finder caches concurrently.
+// Run with the -race flag to verify the absence of data races.
+func TestConcurrent_GetSetClear(t *testing.T) {
+	ClearFinderCache()
+	ClearTargetCache()
+	defer ClearFinderCache()
+	defer ClearTargetCache()
+	var found int64
+	require.NoError(t, SetFinder(stressGroup, func(key string) (Target, error) {
+		atomic.AddInt64(&found, 1)
+		return newTestTarget(stressGroup, key, 0), nil
+	}, false))
+
+	var wg sync.WaitGroup
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < stressLoops; i++ {
+				key := stressKey(g + i)
+				switch (g + i) % 8 {
+				case 0:
+					err := SetTarget(newTestTarget(stressGroup, key, g), g%2 == 0)
+					if err != nil {
+						assert.ErrorIs(t, err, ErrTargetAlreadyExists)
+					}
+				case 1:
+					if i%50 == 0 {
+						ClearTargetCache()
+					}
+				case 2:
+					HasTarget(stressGroup, key)
+				case 3:
+					assert.True(t, HasFinder(stressGroup))
+				case 4:
+					assert.NoError(t, SetFinder(fmt.Sprintf("group-%d", g), GetFinder(stressGroup), true))
+				default:
+					target, err := GetTarget(stressGroup, key, nil)
+					if assert.NoError(t, err) {
+						assert.Equal(t, key, target.Key())
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Positive(t, atomic.LoadInt64(&found))
+}
+
+// TestConcurrent_GetTarget verifies that concurrent GetTarget calls
+// for the same missing Target all return the same Target.
+func TestConcurrent_GetTarget(t *testing.T) {
+	ClearTargetCache()
+	defer ClearTargetCache()
+	finder := func(key string) (Target, error) {
+		return newTestTarget(stressGroup, key, 0), nil
+	}
+
+	targets := make([]Target, stressGoroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			target, err := GetTarget(stressGroup, testKey, finder)
+			assert.NoError(t, err)
+			targets[g] = target
+		}(g)
+	}
+	wg.Wait()
+	for _, target := range targets {
+		assert.Same(t, targets[0], target)
+	}
+}
+
+// TestConcurrent_Bounded exercises a bounded Cache concurrently.
+// Run with the -race flag to verify the absence of data races.
+func TestConcurrent_Bounded(t *testing.T) {
+	var evicted int64
+	cache := NewCache(
+		WithMaxEntries(stressKeys/2),
+		WithGroupMaxEntries(stressGroup, stressKeys/4),
+		WithTTL(time.Millisecond),
+		WithEvictionFunc(func(Target, EvictionReason) {
+			atomic.AddInt64(&evicted, 1)
+		}))
+	require.NoError(t, cache.SetFinder(stressGroup, func(key string) (Target, error) {
+		return newTestTarget(stressGroup, key, 0), nil
+	}, false))
+
+	var wg sync.WaitGroup
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < stressLoops; i++ {
+				key := stressKey(g + i)
+				switch (g + i) % 4 {
+				case 0:
+					err := cache.SetTarget(newTestTarget(testGroup, key, g), true)
+					assert.NoError(t, err)
+				case 1:
+					cache.Prune()
+				default:
+					target, err := cache.GetTarget(stressGroup, key, nil)
+					if assert.NoError(t, err) {
+						assert.Equal(t, key, target.Key())
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, cache.Len(), stressKeys/2)
+	assert.Positive(t, atomic.LoadInt64(&evicted))
+}
@@ -0,0 +1,136 @@
+package pointer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DanglingPolicy specifies how references to Target items that can't be found
+// are handled when decoding Pointer objects.
+type DanglingPolicy int
+
+const (
+	// DanglingFail fails decoding at the first reference to a missing Target.
+	// This is the default.
+	DanglingFail DanglingPolicy = iota
+
+	// DanglingKeep leaves the Pointer without a Target item,
+	// remembering the group and key so that it is serialized as it was read.
+	DanglingKeep
+
+	// DanglingPlaceholder sets the Pointer to a Target item returned by
+	// the Placeholder for the group (see Cache.SetPlaceholder).
+	// Groups without a Placeholder fail as with DanglingFail.
+	DanglingPlaceholder
+
+	// DanglingReport leaves the Pointer without a Target item as with DanglingKeep
+	// and returns a DanglingError listing all such references after decoding is complete.
+	DanglingReport
+)
+
+func (p DanglingPolicy) String() string {
+	switch p {
+	case DanglingFail:
+		return "fail"
+	case DanglingKeep:
+		return "keep"
+	case DanglingPlaceholder:
+		return "placeholder"
+	case DanglingReport:
+		return "report"
+	default:
+		return fmt.Sprintf("DanglingPolicy(%d)", int(p))
+	}
+}
+
+// Placeholder returns a Target item to stand in for a Target with the specified key
+// that can't be found.
+// The Target item should have the group and key of the missing Target
+// so that the Pointer is serialized as it was read.
+// Placeholder Target items are not added to the Cache.
+type Placeholder func(key string) Target
+
+var (
+	// ErrPlaceholderIsNil is returned from SetPlaceholder if the specified Placeholder is nil.
+	ErrPlaceholderIsNil = errors.New("placeholder is nil")
+
+	// ErrNoPlaceholderGroup is returned from SetPlaceholder when the specified group is empty ("").
+	ErrNoPlaceholderGroup = errors.New("empty group for placeholder")
+
+	// ErrPlaceholderAlreadyExists is returned from SetPlaceholder if the Cache already
+	// has a Placeholder for the specified group and the replace flag is false.
+	ErrPlaceholderAlreadyExists = errors.New("placeholder already exists")
+)
+
+// -----------------------------------------------------------------------
+
+// DanglingReference specifies a Target that could not be found by group and key.
+type DanglingReference struct {
+	Group, Key string
+}
+
+func (r DanglingReference) String() string {
+	return r.Group + "/" + r.Key
+}
+
+// DanglingError reports all references to Target items that could not be found
+// when decoding with DanglingReport.
+// The error matches ErrNoSuchTarget via errors.Is.
+type DanglingError struct {
+	References []DanglingReference
+}
+
+func (e *DanglingError) Error() string {
+	refs := make([]string, len(e.References))
+	for i, ref := range e.References {
+		refs[i] = ref.String()
+	}
+	return fmt.Sprintf("%d dangling references: %s", len(refs), strings.Join(refs, ", "))
+}
+
+func (e *DanglingError) Unwrap() error {
+	return ErrNoSuchTarget
+}
+
+// -----------------------------------------------------------------------
+
+// HasPlaceholder returns true if the specified group has a Placeholder in the Cache.
+func (c *Cache) HasPlaceholder(group string) bool {
+	return c.GetPlaceholder(group) != nil
+}
+
+// GetPlaceholder returns the Placeholder for the specified group or nil if there is none.
+func (c *Cache) GetPlaceholder(group string) Placeholder {
+	c.finderMutex.RLock()
+	defer c.finderMutex.RUnlock()
+	return c.placeholders[group]
+}
+
+// SetPlaceholder configures a Placeholder for the specified group.
+// Placeholders are removed along with Finder functions by ClearFinders.
+func (c *Cache) SetPlaceholder(group string, placeholder Placeholder, replace bool) error {
+	if group == "" {
+		return ErrNoPlaceholderGroup
+	} else if placeholder == nil {
+		return ErrPlaceholderIsNil
+	}
+
+	c.finderMutex.Lock()
+	defer c.finderMutex.Unlock()
+	if c.placeholders[group] != nil && !replace {
+		return ErrPlaceholderAlreadyExists
+	}
+	c.placeholders[group] = placeholder
+	return nil
+}
+
+// GetPlaceholder acquires a pointer.Placeholder by group from the default Cache.
+func GetPlaceholder(group string) Placeholder {
+	return defaultCache.GetPlaceholder(group)
+}
+
+// SetPlaceholder configures a pointer.Placeholder for the specified group in the default Cache.
+func SetPlaceholder(group string, placeholder Placeholder, replace bool) error {
+	return defaultCache.SetPlaceholder(group, placeholder, replace)
+}
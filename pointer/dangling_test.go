@@ -0,0 +1,94 @@
+package pointer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDanglingPolicy_String(t *testing.T) {
+	assert.Equal(t, "fail", DanglingFail.String())
+	assert.Equal(t, "keep", DanglingKeep.String())
+	assert.Equal(t, "placeholder", DanglingPlaceholder.String())
+	assert.Equal(t, "report", DanglingReport.String())
+	assert.Equal(t, "DanglingPolicy(17)", DanglingPolicy(17).String())
+}
+
+func TestDanglingError(t *testing.T) {
+	var err error = &DanglingError{References: []DanglingReference{
+		{Group: "cat", Key: "Garfield"},
+		{Group: "dog", Key: "Odie"},
+	}}
+	assert.Equal(t, "2 dangling references: cat/Garfield, dog/Odie", err.Error())
+	assert.ErrorIs(t, err, ErrNoSuchTarget)
+	var dangling *DanglingError
+	require.True(t, errors.As(err, &dangling))
+	assert.Len(t, dangling.References, 2)
+}
+
+func TestPlaceholder(t *testing.T) {
+	cache := NewCache()
+	placeholder := func(key string) Target {
+		return newTestTarget(testFinder, key, -1)
+	}
+	assert.False(t, cache.HasPlaceholder(testFinder))
+	assert.ErrorIs(t, cache.SetPlaceholder("", placeholder, false), ErrNoPlaceholderGroup)
+	assert.ErrorIs(t, cache.SetPlaceholder(testFinder, nil, false), ErrPlaceholderIsNil)
+	require.NoError(t, cache.SetPlaceholder(testFinder, placeholder, false))
+	assert.ErrorIs(t, cache.SetPlaceholder(testFinder, placeholder, false), ErrPlaceholderAlreadyExists)
+	require.NoError(t, cache.SetPlaceholder(testFinder, placeholder, true))
+	assert.True(t, cache.HasPlaceholder(testFinder))
+	assert.Equal(t, newTestTarget(testFinder, testKey, -1), cache.GetPlaceholder(testFinder)(testKey))
+	cache.ClearFinders()
+	assert.False(t, cache.HasPlaceholder(testFinder))
+}
+
+func TestBatch_SetMissing(t *testing.T) {
+	cache := NewCache()
+	require.NoError(t, cache.SetTarget(newTestTarget(testFinder, "a", 1), false))
+	var batch Batch
+	found := make(map[string]Target)
+	for _, key := range []string{"a", "b", "c"} {
+		key := key
+		batch.Add(cache, testFinder, key, func(target Target) error {
+			found[key] = target
+			return nil
+		})
+	}
+
+	// Without a missing function the Batch fails.
+	assert.ErrorIs(t, batch.Resolve(context.Background()), ErrNoSuchTarget)
+
+	var missing []string
+	batch.SetMissing(func(c *Cache, group, key string) (Target, error) {
+		assert.Same(t, cache, c)
+		missing = append(missing, group+"/"+key)
+		if key == "b" {
+			return newTestTarget(group, key, -1), nil
+		}
+		return nil, nil
+	})
+	found = make(map[string]Target)
+	for _, key := range []string{"a", "b", "c"} {
+		key := key
+		batch.Add(cache, testFinder, key, func(target Target) error {
+			found[key] = target
+			return nil
+		})
+	}
+	require.NoError(t, batch.Resolve(context.Background()))
+	assert.Equal(t, []string{testFinder + "/b", testFinder + "/c"}, missing)
+	assert.Len(t, found, 2)
+	assert.Equal(t, newTestTarget(testFinder, "b", -1), found["b"])
+	assert.False(t, cache.HasTarget(testFinder, "b"))
+
+	// Errors from the missing function fail the Batch.
+	batch.SetMissing(func(*Cache, string, string) (Target, error) {
+		return nil, ErrNoSuchTarget
+	})
+	batch.Add(cache, testFinder, "d", func(Target) error { return nil })
+	assert.ErrorIs(t, batch.Resolve(context.Background()), ErrNoSuchTarget)
+}
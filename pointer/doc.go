@@ -1,9 +1,36 @@
 // Package pointer supports serialization and deserialization of pointer references.
 //
 // A pointer reference is stored as a combination of group and key strings.
-// When referenced later the Target item is pulled from an internal targetCache.
-// The targetCache may be preloaded or a Finder function may be used to load Target
-// items into the targetCache dynamically as they are referenced.
+// When referenced later the Target item is pulled from a Cache.
+// The Cache may be preloaded or a Finder function may be used to load Target
+// items into the Cache dynamically as they are referenced.
+//
+// The package functions (e.g. GetTarget and SetFinder) use a default Cache.
+// Separate Cache objects created via NewCache keep their Target items
+// and Finder functions isolated from the default Cache and each other.
+//
+// By default a Cache keeps every Target item it is given.
+// Long-running programs that use a Finder to load Target items from a large source
+// may bound a Cache via CacheOption values such as WithMaxEntries and WithGroupTTL.
+// Least recently used or expired Target items are then evicted
+// and the Finder is called again the next time they are requested.
+//
+// A ContextFinder may be used instead of a Finder for lookups that should observe
+// context cancellation and deadlines (see GetTargetContext).
+// A BatchFinder acquires Target items for many keys at once (see GetTargets and Batch).
+// A Storer writes Target items passed to SetTarget through to the source read by the Finder.
+//
+// A DanglingPolicy specifies how references to Target items that can't be found are handled
+// when decoding, for example by substituting a Placeholder registered for the group.
+// Cache.Check reports whether references found in a serialized document
+// (e.g. by json.CheckReferences) can be resolved without changing the Cache.
+//
+// Target items with keys that are not strings (e.g. integer IDs, UUIDs or composite key structs)
+// implement KeyedTarget and are held via a KeyedCache, which stores them in a Cache
+// by the string form of their keys (see KeyString) and uses KeyedFinder functions.
+//
+// Cache objects are safe for concurrent use.
+// Finder functions may be called concurrently and should be safe for concurrent use.
 //
 // This package defines the Target interface.
 // Pointer implementations are defined in the json and yaml packages.
@@ -0,0 +1,107 @@
+package pointer
+
+import (
+	"fmt"
+	"time"
+)
+
+// EvictionReason specifies why a Target was evicted from a Cache.
+type EvictionReason int
+
+const (
+	// EvictedCapacity means that the Target was the least recently used
+	// when its group or the Cache exceeded the maximum number of entries.
+	EvictedCapacity EvictionReason = iota
+
+	// EvictedExpired means that the Target was older than the time to live for its group.
+	EvictedExpired
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictedCapacity:
+		return "capacity"
+	case EvictedExpired:
+		return "expired"
+	default:
+		return fmt.Sprintf("EvictionReason(%d)", int(r))
+	}
+}
+
+// EvictionFunc is called after a Target is evicted from a Cache.
+// The function is called without holding any Cache lock so it may use the Cache.
+// Eviction functions may be called concurrently and should be safe for concurrent use.
+type EvictionFunc func(target Target, reason EvictionReason)
+
+// -----------------------------------------------------------------------
+
+// CacheOption configures eviction for a Cache.
+// Options are specified via NewCache or Cache.Configure.
+type CacheOption func(*cacheConfig)
+
+// WithMaxEntries limits the total number of Target items in a Cache.
+// When the limit is exceeded the least recently used Target items are evicted.
+// A limit of zero (the default) means no limit.
+func WithMaxEntries(max int) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.maxEntries = max
+	}
+}
+
+// WithGroupMaxEntries limits the number of Target items for the specified group in a Cache.
+// When the limit is exceeded the least recently used Target items in the group are evicted.
+// A limit of zero (the default) means no limit other than that set by WithMaxEntries.
+func WithGroupMaxEntries(group string, max int) CacheOption {
+	return func(cfg *cacheConfig) {
+		if cfg.groupMax == nil {
+			cfg.groupMax = make(map[string]int)
+		}
+		cfg.groupMax[group] = max
+	}
+}
+
+// WithTTL sets the time to live for Target items in groups with no TTL of their own.
+// Target items older than this are evicted instead of being returned,
+// so that the Finder for the group will be called again.
+// A duration of zero (the default) means Target items don't expire.
+func WithTTL(ttl time.Duration) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithGroupTTL sets the time to live for Target items in the specified group.
+// See WithTTL.
+func WithGroupTTL(group string, ttl time.Duration) CacheOption {
+	return func(cfg *cacheConfig) {
+		if cfg.groupTTL == nil {
+			cfg.groupTTL = make(map[string]time.Duration)
+		}
+		cfg.groupTTL[group] = ttl
+	}
+}
+
+// WithEvictionFunc specifies a function to be called after each Target is evicted.
+// Target items removed by ClearTargets or replaced by SetTarget are not evicted.
+func WithEvictionFunc(fn EvictionFunc) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.onEvict = fn
+	}
+}
+
+// cacheConfig holds the eviction configuration for a Cache.
+type cacheConfig struct {
+	maxEntries int
+	groupMax   map[string]int
+	ttl        time.Duration
+	groupTTL   map[string]time.Duration
+	onEvict    EvictionFunc
+}
+
+// ttlFor returns the time to live for the specified group.
+func (cfg *cacheConfig) ttlFor(group string) time.Duration {
+	if ttl, found := cfg.groupTTL[group]; found {
+		return ttl
+	}
+	return cfg.ttl
+}
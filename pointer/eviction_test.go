@@ -0,0 +1,165 @@
+package pointer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EvictionTestSuite struct {
+	suite.Suite
+	evicted []string
+	reasons []EvictionReason
+	mutex   sync.Mutex
+	clock   time.Time
+}
+
+func (suite *EvictionTestSuite) SetupTest() {
+	suite.evicted = nil
+	suite.reasons = nil
+	suite.clock = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestEvictionSuite(t *testing.T) {
+	suite.Run(t, new(EvictionTestSuite))
+}
+
+// newCache returns a Cache using the test clock that records evictions.
+func (suite *EvictionTestSuite) newCache(opts ...CacheOption) *Cache {
+	cache := NewCache(append(opts, WithEvictionFunc(suite.onEvict))...)
+	cache.now = func() time.Time { return suite.clock }
+	return cache
+}
+
+func (suite *EvictionTestSuite) onEvict(target Target, reason EvictionReason) {
+	suite.mutex.Lock()
+	defer suite.mutex.Unlock()
+	suite.evicted = append(suite.evicted, target.Group()+"/"+target.Key())
+	suite.reasons = append(suite.reasons, reason)
+}
+
+func (suite *EvictionTestSuite) set(cache *Cache, group string, keys ...string) {
+	for _, key := range keys {
+		suite.Require().NoError(cache.SetTarget(newTestTarget(group, key, 0), false))
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+func (suite *EvictionTestSuite) TestUnbounded() {
+	cache := suite.newCache()
+	suite.set(cache, testGroup, "a", "b", "c", "d", "e")
+	suite.Assert().Equal(5, cache.Len())
+	suite.Assert().Empty(suite.evicted)
+}
+
+func (suite *EvictionTestSuite) TestMaxEntries() {
+	cache := suite.newCache(WithMaxEntries(3))
+	suite.set(cache, testGroup, "a", "b", "c")
+	suite.Assert().True(cache.HasTarget(testGroup, "a"))
+	suite.set(cache, finderGroup, "d")
+	suite.Assert().Equal(3, cache.Len())
+	suite.Assert().Equal([]string{testGroup + "/b"}, suite.evicted)
+	suite.Assert().Equal([]EvictionReason{EvictedCapacity}, suite.reasons)
+	suite.Assert().True(cache.HasTarget(testGroup, "a"))
+	suite.Assert().False(cache.HasTarget(testGroup, "b"))
+	suite.Assert().True(cache.HasTarget(finderGroup, "d"))
+}
+
+func (suite *EvictionTestSuite) TestGroupMaxEntries() {
+	cache := suite.newCache(WithGroupMaxEntries(testGroup, 2))
+	suite.set(cache, finderGroup, "x", "y", "z")
+	suite.set(cache, testGroup, "a", "b")
+	_, err := cache.GetTarget(testGroup, "a", nil)
+	suite.Require().NoError(err)
+	suite.set(cache, testGroup, "c")
+	suite.Assert().Equal([]string{testGroup + "/b"}, suite.evicted)
+	suite.Assert().Equal(5, cache.Len())
+	suite.Assert().True(cache.HasTarget(finderGroup, "x"))
+}
+
+func (suite *EvictionTestSuite) TestTTL() {
+	cache := suite.newCache(WithTTL(time.Hour), WithGroupTTL(finderGroup, time.Minute))
+	suite.set(cache, testGroup, "a")
+	suite.set(cache, finderGroup, "x")
+	suite.clock = suite.clock.Add(2 * time.Minute)
+	suite.Assert().True(cache.HasTarget(testGroup, "a"))
+	suite.Assert().False(cache.HasTarget(finderGroup, "x"))
+	suite.Assert().Equal([]string{finderGroup + "/x"}, suite.evicted)
+	suite.Assert().Equal([]EvictionReason{EvictedExpired}, suite.reasons)
+
+	// Replacing a Target restarts its time to live.
+	suite.clock = suite.clock.Add(50 * time.Minute)
+	suite.Require().NoError(cache.SetTarget(newTestTarget(testGroup, "a", 1), true))
+	suite.clock = suite.clock.Add(50 * time.Minute)
+	suite.Assert().True(cache.HasTarget(testGroup, "a"))
+	suite.clock = suite.clock.Add(time.Hour)
+	suite.Assert().False(cache.HasTarget(testGroup, "a"))
+	suite.Assert().Equal(0, cache.Len())
+}
+
+// TestTTL_Finder verifies that the Finder is called only when there is no unexpired Target.
+func (suite *EvictionTestSuite) TestTTL_Finder() {
+	cache := suite.newCache(WithGroupTTL(testFinder, time.Minute))
+	var calls int
+	suite.Require().NoError(cache.SetFinder(testFinder, func(key string) (Target, error) {
+		calls++
+		return testFinderFn(key)
+	}, false))
+	for i := 0; i < 3; i++ {
+		_, err := cache.GetTarget(testFinder, testKey, nil)
+		suite.Require().NoError(err)
+	}
+	suite.Assert().Equal(1, calls)
+	suite.clock = suite.clock.Add(time.Minute)
+	_, err := cache.GetTarget(testFinder, testKey, nil)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(2, calls)
+	suite.Assert().Equal([]EvictionReason{EvictedExpired}, suite.reasons)
+}
+
+func (suite *EvictionTestSuite) TestPrune() {
+	cache := suite.newCache(WithGroupTTL(testGroup, time.Minute))
+	suite.set(cache, testGroup, "a", "b")
+	suite.set(cache, finderGroup, "x")
+	suite.clock = suite.clock.Add(time.Minute)
+	suite.Assert().Equal(3, cache.Len())
+	cache.Prune()
+	suite.Assert().Equal(1, cache.Len())
+	suite.Assert().ElementsMatch([]string{testGroup + "/a", testGroup + "/b"}, suite.evicted)
+}
+
+func (suite *EvictionTestSuite) TestConfigure() {
+	cache := suite.newCache()
+	suite.set(cache, testGroup, "a", "b", "c", "d")
+	cache.Configure(WithGroupMaxEntries(testGroup, 3))
+	suite.Assert().Equal([]string{testGroup + "/a"}, suite.evicted)
+	cache.Configure(WithMaxEntries(1))
+	suite.Assert().Equal(1, cache.Len())
+	suite.Assert().True(cache.HasTarget(testGroup, "d"))
+
+	// ClearTargets does not evict.
+	suite.evicted = nil
+	cache.ClearTargets()
+	suite.Assert().Equal(0, cache.Len())
+	suite.Assert().Empty(suite.evicted)
+}
+
+// TestEvictionFunc_UsesCache verifies that the eviction function may use the Cache.
+func (suite *EvictionTestSuite) TestEvictionFunc_UsesCache() {
+	var cache *Cache
+	var has []bool
+	cache = NewCache(WithMaxEntries(1), WithEvictionFunc(func(target Target, _ EvictionReason) {
+		has = append(has, cache.HasTarget(target.Group(), target.Key()))
+	}))
+	suite.set(cache, testGroup, "a", "b")
+	suite.Assert().Equal([]bool{false}, has)
+}
+
+func (suite *EvictionTestSuite) TestEvictionReason_String() {
+	suite.Assert().Equal("capacity", EvictedCapacity.String())
+	suite.Assert().Equal("expired", EvictedExpired.String())
+	suite.Assert().Equal("EvictionReason(17)", EvictionReason(17).String())
+}
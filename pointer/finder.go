@@ -1,11 +1,42 @@
 package pointer
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
-// Finder returns a new Target item with the specified key to fill in the targetCache.
+// Finder returns a new Target item with the specified key to fill in a Cache.
 // This method can be defined to pull items out of a DB or other source.
 type Finder func(key string) (Target, error)
 
+// ContextFinder returns a new Target item with the specified key to fill in a Cache
+// as does Finder, observing the cancellation and deadline of the specified context.
+// This method can be defined to pull items out of a DB or remote source
+// so that slow lookups are abandoned when the context is done.
+type ContextFinder func(ctx context.Context, key string) (Target, error)
+
+// withContext returns a ContextFinder that calls the Finder without the context.
+// A nil Finder returns a nil ContextFinder.
+func (f Finder) withContext() ContextFinder {
+	if f == nil {
+		return nil
+	}
+	return func(_ context.Context, key string) (Target, error) {
+		return f(key)
+	}
+}
+
+// withoutContext returns a Finder that calls the ContextFinder with context.Background().
+// A nil ContextFinder returns a nil Finder.
+func (f ContextFinder) withoutContext() Finder {
+	if f == nil {
+		return nil
+	}
+	return func(key string) (Target, error) {
+		return f(context.Background(), key)
+	}
+}
+
 // -----------------------------------------------------------------------
 
 var (
@@ -15,45 +46,49 @@ var (
 	// ErrNoFinderGroup is returned from SetFinder when the specified group is empty ("").
 	ErrNoFinderGroup = errors.New("empty group for finder")
 
-	// ErrFinderAlreadyExists is returned from SetFinder if the targetCache already
-	// has a Target for the new Target's group and key and the replace flag is false.
+	// ErrFinderAlreadyExists is returned from SetFinder if the Cache already
+	// has a Finder for the specified group and the replace flag is false.
 	ErrFinderAlreadyExists = errors.New("finder already exists")
 )
 
 // -----------------------------------------------------------------------
 
-var finderCache = make(map[string]Finder)
-
-// ClearFinderCache clears the finderCache of Finder functions by group.
+// ClearFinderCache removes all Finder functions from the default Cache.
 func ClearFinderCache() {
-	finderCache = make(map[string]Finder)
+	defaultCache.ClearFinders()
 }
 
-// HasFinder returns true if the specified group have a Finder in the Finder cache.
+// HasFinder returns true if the specified group has a Finder in the default Cache.
 func HasFinder(group string) bool {
-	finder, found := finderCache[group]
-	return found && finder != nil
+	return defaultCache.HasFinder(group)
 }
 
-// GetFinder acquires a pointer.Finder by group.
+// GetFinder acquires a pointer.Finder by group from the default Cache.
 func GetFinder(group string) Finder {
-	if finder, found := finderCache[group]; found {
-		return finder
-	} else {
-		return nil
-	}
+	return defaultCache.GetFinder(group)
 }
 
-// SetFinder configures a pointer.Finder for the specified group.
+// GetContextFinder acquires a pointer.ContextFinder by group from the default Cache.
+func GetContextFinder(group string) ContextFinder {
+	return defaultCache.GetContextFinder(group)
+}
+
+// SetFinder configures a pointer.Finder for the specified group in the default Cache.
 func SetFinder(group string, finder Finder, replace bool) error {
-	if group == "" {
-		return ErrNoFinderGroup
-	} else if finder == nil {
-		return ErrFinderIsNil
-	} else if HasFinder(group) && !replace {
-		return ErrFinderAlreadyExists
-	}
+	return defaultCache.SetFinder(group, finder, replace)
+}
+
+// SetContextFinder configures a pointer.ContextFinder for the specified group in the default Cache.
+func SetContextFinder(group string, finder ContextFinder, replace bool) error {
+	return defaultCache.SetContextFinder(group, finder, replace)
+}
+
+// GetBatchFinder acquires a pointer.BatchFinder by group from the default Cache.
+func GetBatchFinder(group string) BatchFinder {
+	return defaultCache.GetBatchFinder(group)
+}
 
-	finderCache[group] = finder
-	return nil
+// SetBatchFinder configures a pointer.BatchFinder for the specified group in the default Cache.
+func SetBatchFinder(group string, finder BatchFinder, replace bool) error {
+	return defaultCache.SetBatchFinder(group, finder, replace)
 }
@@ -1,7 +1,9 @@
 package pointer
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -26,3 +28,67 @@ func TestFinder(t *testing.T) {
 		ErrNoFinderGroup)
 	assert.ErrorIs(t, SetFinder(testGroup, nil, false), ErrFinderIsNil)
 }
+
+func TestContextFinder(t *testing.T) {
+	ClearFinderCache()
+	ClearTargetCache()
+	defer ClearFinderCache()
+	defer ClearTargetCache()
+	assert.Nil(t, GetContextFinder(testGroup))
+	assert.ErrorIs(t, SetContextFinder(testGroup, nil, false), ErrFinderIsNil)
+	assert.ErrorIs(t, SetContextFinder("", testContextFinderFn, false), ErrNoFinderGroup)
+	assert.NoError(t, SetContextFinder(testFinder, testContextFinderFn, false))
+	assert.True(t, HasFinder(testFinder))
+	assert.NotNil(t, GetContextFinder(testFinder))
+	assert.ErrorIs(t, SetFinder(testFinder, testFinderFn, false), ErrFinderAlreadyExists)
+
+	// A ContextFinder can be used as a Finder and vice versa.
+	target, err := GetFinder(testFinder)(testKey)
+	assert.NoError(t, err)
+	assert.Equal(t, testKey, target.Key())
+	assert.NoError(t, SetFinder(testGroup, testFinderFn, false))
+	target, err = GetContextFinder(testGroup)(context.Background(), testKey)
+	assert.NoError(t, err)
+	assert.Equal(t, testFinder, target.Group())
+}
+
+func TestGetTargetContext(t *testing.T) {
+	ClearFinderCache()
+	ClearTargetCache()
+	defer ClearFinderCache()
+	defer ClearTargetCache()
+	assert.NoError(t, SetContextFinder(testFinder, testContextFinderFn, false))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	target, err := GetTargetContext(ctx, testFinder, testKey, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, target)
+	assert.False(t, HasTarget(testFinder, testKey))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = GetTargetContext(ctx, testFinder, testKey, func(ctx context.Context, key string) (Target, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	target, err = GetTargetContext(context.Background(), testFinder, testKey, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, testKey, target.Key())
+
+	// A cached Target is returned even if the context is done.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	cached, err := GetTargetContext(ctx, testFinder, testKey, nil)
+	assert.NoError(t, err)
+	assert.Same(t, target, cached)
+}
+
+func testContextFinderFn(ctx context.Context, key string) (Target, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return testFinderFn(key)
+}
@@ -0,0 +1,222 @@
+package pointer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// KeyedTarget defines the interface for items with keys of type K
+// (e.g. integer IDs, UUIDs or composite key structs) that can be referenced by Pointer objects.
+// A Target is a KeyedTarget[string].
+type KeyedTarget[K comparable] interface {
+	// Group returns an arbitrary group name for the target.
+	Group() string
+
+	// Key returns a unique key for the target within its group.
+	Key() K
+}
+
+// KeyedFinder returns a new KeyedTarget item with the specified key to fill in a KeyedCache.
+// This method can be defined to pull items out of a DB or other source.
+type KeyedFinder[K comparable] func(ctx context.Context, key K) (KeyedTarget[K], error)
+
+// ErrWrongKeyType is returned from KeyedCache methods when the Target found in the Cache
+// for the group and key is not a KeyedTarget with the key type of the KeyedCache.
+var ErrWrongKeyType = errors.New("target key of wrong type")
+
+// -----------------------------------------------------------------------
+
+// KeyedCache holds KeyedTarget items by group and key along with KeyedFinder functions by group.
+// KeyedCache objects are safe for concurrent use.
+//
+// Target items are held in an underlying Cache by the string form of their keys (see KeyString),
+// so a KeyedCache has the configuration (e.g. eviction) of its Cache and
+// Target items set via a KeyedCache are visible via the Cache and vice versa.
+// Each group should only be used with a single key type.
+type KeyedCache[K comparable] struct {
+	cache *Cache
+}
+
+// NewKeyedCache returns a KeyedCache that holds its Target items in the specified Cache.
+// A nil Cache is the same as the default Cache.
+func NewKeyedCache[K comparable](cache *Cache) *KeyedCache[K] {
+	if cache == nil {
+		cache = defaultCache
+	}
+	return &KeyedCache[K]{cache: cache}
+}
+
+// Cache returns the underlying Cache.
+func (c *KeyedCache[K]) Cache() *Cache {
+	return c.cache
+}
+
+// HasTarget returns true if the KeyedCache has a Target for the specified group and key.
+func (c *KeyedCache[K]) HasTarget(group string, key K) bool {
+	str, err := KeyString(key)
+	return err == nil && c.cache.HasTarget(group, str)
+}
+
+// GetTarget returns a KeyedTarget object from the KeyedCache as does Cache.GetTargetContext,
+// using the KeyedFinder for the group if the Target isn't already in the KeyedCache.
+func (c *KeyedCache[K]) GetTarget(ctx context.Context, group string, key K) (KeyedTarget[K], error) {
+	str, err := KeyString(key)
+	if err != nil {
+		return nil, err
+	}
+	target, err := c.cache.GetTargetContext(ctx, group, str, nil)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapKeyed[K](target)
+}
+
+// GetTargets returns KeyedTarget objects for the specified group and keys from the KeyedCache
+// as does Cache.GetTargets.
+// The result contains a Target for each key that was found, keys not found are absent.
+func (c *KeyedCache[K]) GetTargets(ctx context.Context, group string, keys []K) (map[K]KeyedTarget[K], error) {
+	strs := make([]string, len(keys))
+	for i, key := range keys {
+		str, err := KeyString(key)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = str
+	}
+	targets, err := c.cache.GetTargets(ctx, group, strs)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[K]KeyedTarget[K], len(targets))
+	for i, key := range keys {
+		if target, ok := targets[strs[i]]; ok {
+			if found[key], err = unwrapKeyed[K](target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return found, nil
+}
+
+// SetTarget adds the specified KeyedTarget to the KeyedCache as does Cache.SetTarget.
+func (c *KeyedCache[K]) SetTarget(target KeyedTarget[K], replace bool) error {
+	wrapped, err := wrapKeyed(target)
+	if err != nil {
+		return err
+	}
+	return c.cache.SetTarget(wrapped, replace)
+}
+
+// Remember adds the specified KeyedTarget to the KeyedCache as does Cache.Remember.
+func (c *KeyedCache[K]) Remember(target KeyedTarget[K], replace bool) error {
+	wrapped, err := wrapKeyed(target)
+	if err != nil {
+		return err
+	}
+	return c.cache.Remember(wrapped, replace)
+}
+
+// SetFinder configures a KeyedFinder for the specified group as does Cache.SetContextFinder.
+func (c *KeyedCache[K]) SetFinder(group string, finder KeyedFinder[K], replace bool) error {
+	if finder == nil {
+		return ErrFinderIsNil
+	}
+	return c.cache.SetContextFinder(group, func(ctx context.Context, str string) (Target, error) {
+		key, err := ParseKey[K](str)
+		if err != nil {
+			return nil, err
+		}
+		target, err := finder(ctx, key)
+		if err != nil || target == nil {
+			return nil, err
+		}
+		return wrapKeyed(target)
+	}, replace)
+}
+
+// AddToBatch adds a reference to the Target with the specified group and key to the Batch.
+// The set function is called with the Target when the Batch is resolved.
+func (c *KeyedCache[K]) AddToBatch(batch *Batch, group string, key K, set func(KeyedTarget[K]) error) error {
+	str, err := KeyString(key)
+	if err != nil {
+		return err
+	}
+	batch.Add(c.cache, group, str, func(target Target) error {
+		keyed, err := unwrapKeyed[K](target)
+		if err != nil {
+			return err
+		}
+		return set(keyed)
+	})
+	return nil
+}
+
+// -----------------------------------------------------------------------
+
+// KeyString returns the string form of a key used to hold KeyedTarget items in a Cache.
+// Keys of kind string are used as is, so a KeyedTarget[string] has the same key as a Target.
+// Other keys are encoded as JSON (e.g. 42 or {"Row":3,"Seat":"C"}).
+func KeyString[K comparable](key K) (string, error) {
+	if value := reflect.ValueOf(key); value.Kind() == reflect.String {
+		return value.String(), nil
+	}
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("key %v to string: %w", key, err)
+	}
+	return string(encoded), nil
+}
+
+// ParseKey returns the key for the string form returned by KeyString.
+func ParseKey[K comparable](str string) (K, error) {
+	var key K
+	if value := reflect.ValueOf(&key).Elem(); value.Kind() == reflect.String {
+		value.SetString(str)
+	} else if err := json.Unmarshal([]byte(str), &key); err != nil {
+		return key, fmt.Errorf("parse key '%s': %w", str, err)
+	}
+	return key, nil
+}
+
+// keyedTarget holds a KeyedTarget in a Cache with the string form of its key.
+type keyedTarget[K comparable] struct {
+	target KeyedTarget[K]
+	key    string
+}
+
+func (t *keyedTarget[K]) Group() string {
+	return t.target.Group()
+}
+
+func (t *keyedTarget[K]) Key() string {
+	return t.key
+}
+
+// wrapKeyed returns a Target for the KeyedTarget.
+// A KeyedTarget that is already a Target is returned as is.
+func wrapKeyed[K comparable](target KeyedTarget[K]) (Target, error) {
+	if target == nil {
+		return nil, ErrTargetIsNil
+	} else if t, ok := target.(Target); ok {
+		return t, nil
+	}
+	str, err := KeyString(target.Key())
+	if err != nil {
+		return nil, err
+	}
+	return &keyedTarget[K]{target: target, key: str}, nil
+}
+
+// unwrapKeyed returns the KeyedTarget for a Target returned by wrapKeyed.
+func unwrapKeyed[K comparable](target Target) (KeyedTarget[K], error) {
+	switch t := target.(type) {
+	case *keyedTarget[K]:
+		return t.target, nil
+	case KeyedTarget[K]:
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: %s/%s", ErrWrongKeyType, target.Group(), target.Key())
+}
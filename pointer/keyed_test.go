@@ -0,0 +1,192 @@
+package pointer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTicket struct {
+	id    int
+	owner string
+}
+
+func (t *testTicket) Group() string {
+	return "ticket"
+}
+
+func (t *testTicket) Key() int {
+	return t.id
+}
+
+type testSeatKey struct {
+	Row    int
+	Letter string
+}
+
+type testSeat struct {
+	key testSeatKey
+}
+
+func (s *testSeat) Group() string {
+	return "seat"
+}
+
+func (s *testSeat) Key() testSeatKey {
+	return s.key
+}
+
+type testName string
+
+type testNamed struct {
+	name testName
+}
+
+func (n *testNamed) Group() string {
+	return "named"
+}
+
+func (n *testNamed) Key() testName {
+	return n.name
+}
+
+func TestKeyString(t *testing.T) {
+	for key, expected := range map[interface{}]string{
+		42:                               "42",
+		"Lacey":                          "Lacey",
+		testName("Noah"):                 "Noah",
+		testSeatKey{Row: 3, Letter: "C"}: `{"Row":3,"Letter":"C"}`,
+		[2]int{1, 2}:                     "[1,2]",
+	} {
+		var str string
+		var err error
+		switch k := key.(type) {
+		case int:
+			str, err = KeyString(k)
+		case string:
+			str, err = KeyString(k)
+		case testName:
+			str, err = KeyString(k)
+		case testSeatKey:
+			str, err = KeyString(k)
+		case [2]int:
+			str, err = KeyString(k)
+		}
+		require.NoError(t, err)
+		assert.Equal(t, expected, str)
+	}
+
+	id, err := ParseKey[int]("42")
+	require.NoError(t, err)
+	assert.Equal(t, 42, id)
+	name, err := ParseKey[testName]("Noah")
+	require.NoError(t, err)
+	assert.Equal(t, testName("Noah"), name)
+	seat, err := ParseKey[testSeatKey](`{"Row":3,"Letter":"C"}`)
+	require.NoError(t, err)
+	assert.Equal(t, testSeatKey{Row: 3, Letter: "C"}, seat)
+	_, err = ParseKey[int]("Lacey")
+	assert.Error(t, err)
+	_, err = KeyString(make(chan int))
+	assert.Error(t, err)
+}
+
+func TestKeyedCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCache()
+	tickets := NewKeyedCache[int](cache)
+	assert.Same(t, cache, tickets.Cache())
+	assert.Same(t, defaultCache, NewKeyedCache[int](nil).Cache())
+
+	ticket := &testTicket{id: 7, owner: "Lacey"}
+	require.NoError(t, tickets.SetTarget(ticket, false))
+	assert.ErrorIs(t, tickets.SetTarget(ticket, false), ErrTargetAlreadyExists)
+	assert.ErrorIs(t, tickets.SetTarget(nil, false), ErrTargetIsNil)
+	assert.True(t, tickets.HasTarget("ticket", 7))
+	assert.True(t, cache.HasTarget("ticket", "7"))
+	found, err := tickets.GetTarget(ctx, "ticket", 7)
+	require.NoError(t, err)
+	assert.Same(t, ticket, found)
+	_, err = tickets.GetTarget(ctx, "ticket", 8)
+	assert.ErrorIs(t, err, ErrNoSuchTarget)
+
+	// The same group and key with another key type.
+	_, err = NewKeyedCache[testSeatKey](cache).GetTarget(ctx, "ticket", testSeatKey{})
+	assert.ErrorIs(t, err, ErrNoSuchTarget)
+	require.NoError(t, cache.SetTarget(newTestTarget("ticket", "9", 0), false))
+	_, err = tickets.GetTarget(ctx, "ticket", 9)
+	assert.ErrorIs(t, err, ErrWrongKeyType)
+
+	// Keys of kind string are held as is.
+	named := NewKeyedCache[testName](cache)
+	require.NoError(t, named.SetTarget(&testNamed{name: "Noah"}, false))
+	assert.True(t, cache.HasTarget("named", "Noah"))
+	strs := NewKeyedCache[string](cache)
+	target := newTestTarget("plain", "Orca", 0)
+	require.NoError(t, cache.SetTarget(target, false))
+	plain, err := strs.GetTarget(ctx, "plain", "Orca")
+	require.NoError(t, err)
+	assert.Same(t, target, plain)
+}
+
+func TestKeyedCache_Finder(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCache(WithMaxEntries(2))
+	seats := NewKeyedCache[testSeatKey](cache)
+	var calls int
+	assert.ErrorIs(t, seats.SetFinder("seat", nil, false), ErrFinderIsNil)
+	require.NoError(t, seats.SetFinder("seat", func(ctx context.Context, key testSeatKey) (KeyedTarget[testSeatKey], error) {
+		calls++
+		if key.Row < 1 {
+			return nil, ErrNoSuchTarget
+		}
+		return &testSeat{key: key}, nil
+	}, false))
+
+	key := testSeatKey{Row: 3, Letter: "C"}
+	seat, err := seats.GetTarget(ctx, "seat", key)
+	require.NoError(t, err)
+	assert.Equal(t, key, seat.Key())
+	again, err := seats.GetTarget(ctx, "seat", key)
+	require.NoError(t, err)
+	assert.Same(t, seat, again)
+	assert.Equal(t, 1, calls)
+	_, err = seats.GetTarget(ctx, "seat", testSeatKey{})
+	assert.ErrorIs(t, err, ErrNoSuchTarget)
+
+	other := testSeatKey{Row: 4, Letter: "A"}
+	targets, err := seats.GetTargets(ctx, "seat", []testSeatKey{key, other, {}})
+	require.NoError(t, err)
+	assert.Len(t, targets, 2)
+	assert.Same(t, seat, targets[key])
+	assert.Equal(t, other, targets[other].Key())
+
+	// Evicted Target items are found again.
+	for row := 5; row < 8; row++ {
+		_, err = seats.GetTarget(ctx, "seat", testSeatKey{Row: row})
+		require.NoError(t, err)
+	}
+	assert.False(t, seats.HasTarget("seat", key))
+	calls = 0
+	_, err = seats.GetTarget(ctx, "seat", key)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestKeyedCache_AddToBatch(t *testing.T) {
+	cache := NewCache()
+	tickets := NewKeyedCache[int](cache)
+	require.NoError(t, tickets.SetTarget(&testTicket{id: 1, owner: "Noah"}, false))
+	var batch Batch
+	var found KeyedTarget[int]
+	require.NoError(t, tickets.AddToBatch(&batch, "ticket", 1, func(target KeyedTarget[int]) error {
+		found = target
+		return nil
+	}))
+	assert.Nil(t, found)
+	require.NoError(t, batch.Resolve(context.Background()))
+	require.NotNil(t, found)
+	assert.Equal(t, "Noah", found.(*testTicket).owner)
+}
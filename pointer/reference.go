@@ -0,0 +1,61 @@
+package pointer
+
+import (
+	"context"
+	"sync"
+)
+
+// Reference specifies a Target by group and key and acquires it when first resolved.
+// This allows a Pointer to be deserialized before its Target is available
+// and avoids acquiring Target items that are never used.
+//
+// Reference objects are safe for concurrent use.
+// A successfully resolved Target is kept by the Reference,
+// a failure is not so that resolution may be retried.
+type Reference struct {
+	cache      *Cache
+	group, key string
+	target     Target
+	mutex      sync.Mutex
+}
+
+// NewReference returns a Reference to the Target with the specified group and key
+// that will be acquired from the specified Cache.
+// A nil Cache is the same as the default Cache.
+func NewReference(cache *Cache, group, key string) *Reference {
+	if cache == nil {
+		cache = defaultCache
+	}
+	return &Reference{cache: cache, group: group, key: key}
+}
+
+// Group returns the group of the referenced Target.
+func (r *Reference) Group() string {
+	return r.group
+}
+
+// Key returns the key of the referenced Target.
+func (r *Reference) Key() string {
+	return r.key
+}
+
+// Resolved returns true if the Target has been acquired.
+func (r *Reference) Resolved() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.target != nil
+}
+
+// Resolve returns the referenced Target, acquiring it via Cache.GetTargetContext if necessary.
+func (r *Reference) Resolve(ctx context.Context) (Target, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.target == nil {
+		target, err := r.cache.GetTargetContext(ctx, r.group, r.key, nil)
+		if err != nil {
+			return nil, err
+		}
+		r.target = target
+	}
+	return r.target, nil
+}
@@ -0,0 +1,63 @@
+package pointer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReference(t *testing.T) {
+	cache := NewCache()
+	ref := NewReference(cache, testFinder, testKey)
+	assert.Equal(t, testFinder, ref.Group())
+	assert.Equal(t, testKey, ref.Key())
+	assert.False(t, ref.Resolved())
+
+	// Resolution fails until a Finder is available and may then be retried.
+	_, err := ref.Resolve(context.Background())
+	assert.ErrorIs(t, err, ErrNoSuchTarget)
+	assert.False(t, ref.Resolved())
+	var calls int64
+	require.NoError(t, cache.SetFinder(testFinder, func(key string) (Target, error) {
+		atomic.AddInt64(&calls, 1)
+		return testFinderFn(key)
+	}, false))
+
+	var wg sync.WaitGroup
+	targets := make([]Target, stressGoroutines)
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			target, err := ref.Resolve(context.Background())
+			assert.NoError(t, err)
+			targets[g] = target
+		}(g)
+	}
+	wg.Wait()
+	assert.True(t, ref.Resolved())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+	for _, target := range targets {
+		assert.Same(t, targets[0], target)
+	}
+
+	// A resolved Reference doesn't need the Cache.
+	cache.ClearTargets()
+	cache.ClearFinders()
+	target, err := ref.Resolve(context.Background())
+	assert.NoError(t, err)
+	assert.Same(t, targets[0], target)
+}
+
+func TestReference_DefaultCache(t *testing.T) {
+	ClearTargetCache()
+	defer ClearTargetCache()
+	require.NoError(t, SetTarget(newTestTarget(testGroup, testKey, 0), false))
+	target, err := NewReference(nil, testGroup, testKey).Resolve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, testKey, target.Key())
+}
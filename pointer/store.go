@@ -0,0 +1,66 @@
+package pointer
+
+import "errors"
+
+// Storer persists a Target passed to SetTarget for its group (write-through),
+// for example by writing it to the DB from which the Finder for the group reads.
+// The Storer is called before the Target is added to the Cache
+// and if it returns an error the Target is not added.
+// Target items acquired from Finder functions or added via Remember
+// (e.g. by Pointer implementations while marshaling) are not passed to the Storer.
+// Storer functions may be called concurrently and should be safe for concurrent use.
+type Storer func(target Target) error
+
+var (
+	// ErrStorerIsNil is returned from SetStorer if the specified Storer is nil.
+	ErrStorerIsNil = errors.New("storer is nil")
+
+	// ErrNoStorerGroup is returned from SetStorer when the specified group is empty ("").
+	ErrNoStorerGroup = errors.New("empty group for storer")
+
+	// ErrStorerAlreadyExists is returned from SetStorer if the Cache already
+	// has a Storer for the specified group and the replace flag is false.
+	ErrStorerAlreadyExists = errors.New("storer already exists")
+)
+
+// -----------------------------------------------------------------------
+
+// HasStorer returns true if the specified group has a Storer in the Cache.
+func (c *Cache) HasStorer(group string) bool {
+	return c.GetStorer(group) != nil
+}
+
+// GetStorer returns the Storer for the specified group or nil if there is none.
+func (c *Cache) GetStorer(group string) Storer {
+	c.finderMutex.RLock()
+	defer c.finderMutex.RUnlock()
+	return c.storers[group]
+}
+
+// SetStorer configures a Storer for the specified group.
+// Storer functions are removed along with Finder functions by ClearFinders.
+func (c *Cache) SetStorer(group string, storer Storer, replace bool) error {
+	if group == "" {
+		return ErrNoStorerGroup
+	} else if storer == nil {
+		return ErrStorerIsNil
+	}
+
+	c.finderMutex.Lock()
+	defer c.finderMutex.Unlock()
+	if c.storers[group] != nil && !replace {
+		return ErrStorerAlreadyExists
+	}
+	c.storers[group] = storer
+	return nil
+}
+
+// GetStorer acquires a pointer.Storer by group from the default Cache.
+func GetStorer(group string) Storer {
+	return defaultCache.GetStorer(group)
+}
+
+// SetStorer configures a pointer.Storer for the specified group in the default Cache.
+func SetStorer(group string, storer Storer, replace bool) error {
+	return defaultCache.SetStorer(group, storer, replace)
+}
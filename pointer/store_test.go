@@ -0,0 +1,79 @@
+package pointer
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorer(t *testing.T) {
+	cache := NewCache()
+	var stored []Target
+	storer := func(target Target) error {
+		if target.Key() == testNone {
+			return errors.New("disk full")
+		}
+		stored = append(stored, target)
+		return nil
+	}
+	assert.False(t, cache.HasStorer(testFinder))
+	assert.ErrorIs(t, cache.SetStorer("", storer, false), ErrNoStorerGroup)
+	assert.ErrorIs(t, cache.SetStorer(testFinder, nil, false), ErrStorerIsNil)
+	require.NoError(t, cache.SetStorer(testFinder, storer, false))
+	assert.ErrorIs(t, cache.SetStorer(testFinder, storer, false), ErrStorerAlreadyExists)
+	require.NoError(t, cache.SetStorer(testFinder, storer, true))
+	assert.True(t, cache.HasStorer(testFinder))
+
+	first := newTestTarget(testFinder, testKey, 1)
+	require.NoError(t, cache.SetTarget(first, false))
+	assert.ErrorIs(t, cache.SetTarget(newTestTarget(testFinder, testKey, 2), false), ErrTargetAlreadyExists)
+	require.NoError(t, cache.SetTarget(newTestTarget(testFinder, testKey, 3), true))
+	assert.ErrorContains(t, cache.SetTarget(newTestTarget(testFinder, testNone, 4), false), "disk full")
+	assert.False(t, cache.HasTarget(testFinder, testNone))
+	require.NoError(t, cache.SetTarget(newTestTarget("other", testKey, 5), false))
+	assert.Equal(t, []Target{first, newTestTarget(testFinder, testKey, 3)}, stored)
+
+	// Target items acquired from the Finder are not stored.
+	require.NoError(t, cache.SetFinder(testFinder, func(key string) (Target, error) {
+		return newTestTarget(testFinder, key, 6), nil
+	}, false))
+	_, err := cache.GetTarget(testFinder, "found", nil)
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+
+	// Target items added via Remember are not stored.
+	require.NoError(t, cache.Remember(newTestTarget(testFinder, "remembered", 7), false))
+	assert.True(t, cache.HasTarget(testFinder, "remembered"))
+	assert.Len(t, stored, 2)
+
+	cache.ClearFinders()
+	assert.False(t, cache.HasStorer(testFinder))
+}
+
+func TestStorer_Concurrent(t *testing.T) {
+	cache := NewCache()
+	var mutex sync.Mutex
+	var stored int
+	require.NoError(t, cache.SetStorer(testFinder, func(target Target) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		stored++
+		return nil
+	}, false))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := cache.SetTarget(newTestTarget(testFinder, testKey, i), false)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrTargetAlreadyExists)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, stored)
+}
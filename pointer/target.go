@@ -1,8 +1,8 @@
 package pointer
 
 import (
+	"context"
 	"errors"
-	"fmt"
 )
 
 // Target defines the interface for items that can be referenced by Pointer objects.
@@ -48,17 +48,14 @@ var (
 	// ErrFinderTargetIsNil is returned from SetTarget if the Target returned by the Finder is nil.
 	ErrFinderTargetIsNil = errors.New("target returned by finder is nil")
 
-	// ErrTargetAlreadyExists is returned from SetTarget if the targetCache already
+	// ErrTargetAlreadyExists is returned from SetTarget if the Cache already
 	// has a Target for the new Target's group and key and the replace flag is false.
 	ErrTargetAlreadyExists = errors.New("target already exists")
 )
 
 //------------------------------------------------------------------------
 
-// Internal targetCache for Target items.
-var targetCache = make(map[string]map[string]Target)
-
-// ClearCache removes all target finderCache entries.
+// ClearCache removes all Target items from the default Cache.
 // For test purposes, all other usage suspect.
 //
 // Deprecated: use ClearTargetCache instead.
@@ -66,66 +63,40 @@ func ClearCache() {
 	ClearTargetCache()
 }
 
-// ClearTargetCache removes all target finderCache entries.
+// ClearTargetCache removes all Target items from the default Cache.
 // For test purposes, all other usage suspect.
 func ClearTargetCache() {
-	targetCache = make(map[string]map[string]Target)
+	defaultCache.ClearTargets()
 }
 
 //------------------------------------------------------------------------
 
-// HasTarget returns true if the specified group and key have a Target in the Target cache.
+// HasTarget returns true if the specified group and key have a Target in the default Cache.
 func HasTarget(group, key string) bool {
-	target, found := targetCache[group][key]
-	return found && target != nil
+	return defaultCache.HasTarget(group, key)
 }
 
-// GetTarget returns a Target object from the targetCache for use in Pointer implementations.
-// If there is no such Target and no Finder the ErrNoSuchTarget error is returned.
-// If the Finder is used to acquire the Target it is added to the targetCache and returned.
+// GetTarget returns a Target object from the default Cache for use in Pointer implementations.
+// See Cache.GetTarget.
 func GetTarget(group, key string, finder Finder) (Target, error) {
-	if target, found := targetCache[group][key]; found && target != nil {
-		return target, nil
-	}
-	if finder == nil {
-		finder = GetFinder(group)
-	}
-	if finder == nil {
-		return nil, ErrNoSuchTarget
-	} else if target, err := finder(key); err != nil {
-		return nil, fmt.Errorf("find item: %w", err)
-	} else if target == nil {
-		return nil, ErrFinderTargetIsNil
-	} else if target.Group() != group {
-		return nil, ErrBadTargetGroup
-	} else if target.Key() != key {
-		return nil, ErrBadTargetKey
-	} else if err := SetTarget(target, false); err != nil {
-		return nil, fmt.Errorf("set target: %w", err)
-	} else {
-		return target, nil
-	}
+	return defaultCache.GetTarget(group, key, finder)
+}
+
+// GetTargetContext returns a Target object from the default Cache
+// using a ContextFinder with the specified context.
+// See Cache.GetTargetContext.
+func GetTargetContext(ctx context.Context, group, key string, finder ContextFinder) (Target, error) {
+	return defaultCache.GetTargetContext(ctx, group, key, finder)
+}
+
+// GetTargets returns Target objects for the specified group and keys from the default Cache.
+// See Cache.GetTargets.
+func GetTargets(ctx context.Context, group string, keys []string) (map[string]Target, error) {
+	return defaultCache.GetTargets(ctx, group, keys)
 }
 
-// SetTarget adds the specified Target to the targetCache.
-// Use this function for Pointer implementations and preloading the targetCache.
+// SetTarget adds the specified Target to the default Cache.
+// Use this function for application writes and preloading the default Cache.
 func SetTarget(target Target, replace bool) error {
-	if target == nil {
-		return ErrTargetIsNil
-	} else if group := target.Group(); group == "" {
-		return ErrNoTargetGroup
-	} else if key := target.Key(); key == "" {
-		return ErrNoTargetKey
-	} else {
-		if HasTarget(group, key) && !replace {
-			return ErrTargetAlreadyExists
-		}
-		cacheGroup, found := targetCache[group]
-		if !found {
-			targetCache[group] = make(map[string]Target)
-			cacheGroup = targetCache[group]
-		}
-		cacheGroup[key] = target
-		return nil
-	}
+	return defaultCache.SetTarget(target, replace)
 }
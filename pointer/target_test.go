@@ -1,6 +1,7 @@
 package pointer
 
 import (
+	"container/list"
 	"errors"
 	"testing"
 
@@ -60,8 +61,10 @@ func (suite *TargetTestSuite) TestGetTarget_NoTarget() {
 	target, err := GetTarget(badGroup, badKey, nil)
 	suite.Assert().ErrorIs(err, ErrNoSuchTarget)
 	suite.Assert().Nil(target)
-	targetCache[badGroup] = make(map[string]Target)
-	targetCache[badGroup][badKey] = nil
+	defaultCache.targets[badGroup] = &targetGroup{
+		entries: map[string]*targetEntry{badKey: nil},
+		lru:     list.New(),
+	}
 	suite.Assert().False(HasTarget(badGroup, badKey))
 	target, err = GetTarget(badGroup, badKey, nil)
 	suite.Assert().ErrorIs(err, ErrNoSuchTarget)
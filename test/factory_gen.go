@@ -0,0 +1,34 @@
+// Code generated by serialgen. DO NOT EDIT.
+
+package test
+
+import (
+	"fmt"
+
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+// borrowerFactory creates Borrower items by type name without reflection.
+type borrowerFactory struct{}
+
+// NameFor returns the type name for the specified item.
+func (borrowerFactory) NameFor(item Borrower) (string, error) {
+	switch item.(type) {
+	case *Federal:
+		return "[test]Federal", nil
+	case *State:
+		return "[test]State", nil
+	}
+	return "", fmt.Errorf("%w: %T", wrapper.ErrUnknownItemType, item)
+}
+
+// Make creates a new, empty item of the type with the specified name.
+func (borrowerFactory) Make(name string) (Borrower, error) {
+	switch name {
+	case "[test]Federal", "github.com/madkins23/go-serial/test/Federal":
+		return &Federal{}, nil
+	case "[test]State", "github.com/madkins23/go-serial/test/State":
+		return &State{}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", wrapper.ErrUnknownTypeName, name)
+}
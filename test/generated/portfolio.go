@@ -0,0 +1,39 @@
+// Package generated holds structs with code generated by serialgen
+// so that the generated serialization methods can be tested.
+package generated
+
+//go:generate go run github.com/madkins23/go-serial/cmd/serialgen -type Portfolio,Bond
+
+import (
+	"github.com/madkins23/go-serial/test"
+)
+
+var _ test.Investment = &Bond{}
+
+type Portfolio struct {
+	Favorite  test.Investment `json:"favorite,omitempty"`
+	Positions []test.Investment
+	Top       [2]test.Investment
+	Lookup    map[string]test.Investment
+	Owner     string
+	Failure   error `json:"-" yaml:"-"`
+}
+
+type Bond struct {
+	test.BondData
+	Source test.Borrower
+}
+
+// MakePortfolio returns a Portfolio with Stock and Bond investments.
+func MakePortfolio() *Portfolio {
+	walmart := test.MakeWalmart()
+	tbill := &Bond{BondData: test.TBillData(), Source: test.TBillSource()}
+	roads := &Bond{BondData: test.StateBondData(), Source: test.StateBondSource()}
+	return &Portfolio{
+		Favorite:  walmart,
+		Positions: []test.Investment{test.MakeCostco(), tbill, roads},
+		Top:       [2]test.Investment{walmart, roads},
+		Lookup:    map[string]test.Investment{"T-Bill": tbill, "Walmart": walmart},
+		Owner:     "Fred",
+	}
+}
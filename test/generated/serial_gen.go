@@ -0,0 +1,242 @@
+// Code generated by serialgen. DO NOT EDIT.
+
+package generated
+
+import (
+	"encoding/json"
+
+	sjson "github.com/madkins23/go-serial/json"
+	"github.com/madkins23/go-serial/test"
+	syaml "github.com/madkins23/go-serial/yaml"
+	"gopkg.in/yaml.v3"
+)
+
+// portfolioJSON is the JSON shadow structure for Portfolio.
+// Interface fields are replaced by json.Wrapper fields.
+type portfolioJSON struct {
+	Favorite  *sjson.Wrapper[test.Investment] `json:"favorite,omitempty"`
+	Positions []*sjson.Wrapper[test.Investment]
+	Top       [2]*sjson.Wrapper[test.Investment]
+	Lookup    map[string]*sjson.Wrapper[test.Investment]
+	Owner     string
+	Failure   error `json:"-" yaml:"-"`
+}
+
+// MarshalJSON copies the Portfolio into a portfolioJSON shadow structure and marshals that.
+func (p *Portfolio) MarshalJSON() ([]byte, error) {
+	shadow := new(portfolioJSON)
+	if p.Favorite != nil {
+		shadow.Favorite = sjson.Wrap[test.Investment](p.Favorite)
+	}
+	if p.Positions != nil {
+		shadow.Positions = make([]*sjson.Wrapper[test.Investment], len(p.Positions))
+		for index, item := range p.Positions {
+			if item != nil {
+				shadow.Positions[index] = sjson.Wrap[test.Investment](item)
+			}
+		}
+	}
+	for index, item := range p.Top {
+		if item != nil {
+			shadow.Top[index] = sjson.Wrap[test.Investment](item)
+		}
+	}
+	if p.Lookup != nil {
+		shadow.Lookup = make(map[string]*sjson.Wrapper[test.Investment], len(p.Lookup))
+		for key, item := range p.Lookup {
+			shadow.Lookup[key] = nil
+			if item != nil {
+				shadow.Lookup[key] = sjson.Wrap[test.Investment](item)
+			}
+		}
+	}
+	shadow.Owner = p.Owner
+	shadow.Failure = p.Failure
+	return json.Marshal(shadow)
+}
+
+// UnmarshalJSON unmarshals a portfolioJSON shadow structure and copies it into the Portfolio.
+func (p *Portfolio) UnmarshalJSON(marshaled []byte) error {
+	shadow := new(portfolioJSON)
+	if err := json.Unmarshal(marshaled, shadow); err != nil {
+		return err
+	}
+	p.Favorite = nil
+	if shadow.Favorite != nil {
+		p.Favorite = shadow.Favorite.Get()
+	}
+	p.Positions = nil
+	if shadow.Positions != nil {
+		p.Positions = make([]test.Investment, len(shadow.Positions))
+		for index, item := range shadow.Positions {
+			if item != nil {
+				p.Positions[index] = item.Get()
+			}
+		}
+	}
+	p.Top = [2]test.Investment{}
+	for index, item := range shadow.Top {
+		if item != nil {
+			p.Top[index] = item.Get()
+		}
+	}
+	p.Lookup = nil
+	if shadow.Lookup != nil {
+		p.Lookup = make(map[string]test.Investment, len(shadow.Lookup))
+		for key, item := range shadow.Lookup {
+			p.Lookup[key] = nil
+			if item != nil {
+				p.Lookup[key] = item.Get()
+			}
+		}
+	}
+	p.Owner = shadow.Owner
+	p.Failure = shadow.Failure
+	return nil
+}
+
+// portfolioYAML is the YAML shadow structure for Portfolio.
+// Interface fields are replaced by yaml.Wrapper fields.
+type portfolioYAML struct {
+	Favorite  *syaml.Wrapper[test.Investment] `json:"favorite,omitempty"`
+	Positions []*syaml.Wrapper[test.Investment]
+	Top       [2]*syaml.Wrapper[test.Investment]
+	Lookup    map[string]*syaml.Wrapper[test.Investment]
+	Owner     string
+	Failure   error `json:"-" yaml:"-"`
+}
+
+// MarshalYAML copies the Portfolio into a portfolioYAML shadow structure to be marshaled.
+func (p *Portfolio) MarshalYAML() (interface{}, error) {
+	shadow := new(portfolioYAML)
+	if p.Favorite != nil {
+		shadow.Favorite = syaml.Wrap[test.Investment](p.Favorite)
+	}
+	if p.Positions != nil {
+		shadow.Positions = make([]*syaml.Wrapper[test.Investment], len(p.Positions))
+		for index, item := range p.Positions {
+			if item != nil {
+				shadow.Positions[index] = syaml.Wrap[test.Investment](item)
+			}
+		}
+	}
+	for index, item := range p.Top {
+		if item != nil {
+			shadow.Top[index] = syaml.Wrap[test.Investment](item)
+		}
+	}
+	if p.Lookup != nil {
+		shadow.Lookup = make(map[string]*syaml.Wrapper[test.Investment], len(p.Lookup))
+		for key, item := range p.Lookup {
+			shadow.Lookup[key] = nil
+			if item != nil {
+				shadow.Lookup[key] = syaml.Wrap[test.Investment](item)
+			}
+		}
+	}
+	shadow.Owner = p.Owner
+	shadow.Failure = p.Failure
+	return shadow, nil
+}
+
+// UnmarshalYAML decodes a portfolioYAML shadow structure and copies it into the Portfolio.
+func (p *Portfolio) UnmarshalYAML(node *yaml.Node) error {
+	shadow := new(portfolioYAML)
+	if err := node.Decode(shadow); err != nil {
+		return err
+	}
+	p.Favorite = nil
+	if shadow.Favorite != nil {
+		p.Favorite = shadow.Favorite.Get()
+	}
+	p.Positions = nil
+	if shadow.Positions != nil {
+		p.Positions = make([]test.Investment, len(shadow.Positions))
+		for index, item := range shadow.Positions {
+			if item != nil {
+				p.Positions[index] = item.Get()
+			}
+		}
+	}
+	p.Top = [2]test.Investment{}
+	for index, item := range shadow.Top {
+		if item != nil {
+			p.Top[index] = item.Get()
+		}
+	}
+	p.Lookup = nil
+	if shadow.Lookup != nil {
+		p.Lookup = make(map[string]test.Investment, len(shadow.Lookup))
+		for key, item := range shadow.Lookup {
+			p.Lookup[key] = nil
+			if item != nil {
+				p.Lookup[key] = item.Get()
+			}
+		}
+	}
+	p.Owner = shadow.Owner
+	p.Failure = shadow.Failure
+	return nil
+}
+
+// bondJSON is the JSON shadow structure for Bond.
+// Interface fields are replaced by json.Wrapper fields.
+type bondJSON struct {
+	test.BondData
+	Source *sjson.Wrapper[test.Borrower]
+}
+
+// MarshalJSON copies the Bond into a bondJSON shadow structure and marshals that.
+func (b *Bond) MarshalJSON() ([]byte, error) {
+	shadow := new(bondJSON)
+	shadow.BondData = b.BondData
+	if b.Source != nil {
+		shadow.Source = sjson.Wrap[test.Borrower](b.Source)
+	}
+	return json.Marshal(shadow)
+}
+
+// UnmarshalJSON unmarshals a bondJSON shadow structure and copies it into the Bond.
+func (b *Bond) UnmarshalJSON(marshaled []byte) error {
+	shadow := new(bondJSON)
+	if err := json.Unmarshal(marshaled, shadow); err != nil {
+		return err
+	}
+	b.BondData = shadow.BondData
+	b.Source = nil
+	if shadow.Source != nil {
+		b.Source = shadow.Source.Get()
+	}
+	return nil
+}
+
+// bondYAML is the YAML shadow structure for Bond.
+// Interface fields are replaced by yaml.Wrapper fields.
+type bondYAML struct {
+	test.BondData
+	Source *syaml.Wrapper[test.Borrower]
+}
+
+// MarshalYAML copies the Bond into a bondYAML shadow structure to be marshaled.
+func (b *Bond) MarshalYAML() (interface{}, error) {
+	shadow := new(bondYAML)
+	shadow.BondData = b.BondData
+	if b.Source != nil {
+		shadow.Source = syaml.Wrap[test.Borrower](b.Source)
+	}
+	return shadow, nil
+}
+
+// UnmarshalYAML decodes a bondYAML shadow structure and copies it into the Bond.
+func (b *Bond) UnmarshalYAML(node *yaml.Node) error {
+	shadow := new(bondYAML)
+	if err := node.Decode(shadow); err != nil {
+		return err
+	}
+	b.BondData = shadow.BondData
+	b.Source = nil
+	if shadow.Source != nil {
+		b.Source = shadow.Source.Get()
+	}
+	return nil
+}
@@ -1,10 +1,14 @@
 package test
 
+//go:generate go run github.com/madkins23/go-serial/cmd/serialgen -factory Borrower -impl Federal,State -alias test -init=false -output factory_gen.go
+
 import (
 	"fmt"
 	"time"
 
 	"github.com/madkins23/go-type/reg"
+
+	"github.com/madkins23/go-serial/wrapper"
 )
 
 var _ Investment = &Stock{}
@@ -29,6 +33,14 @@ func Register() error {
 	return nil
 }
 
+// BorrowerFactory returns the generated wrapper.Factory for Borrower items.
+// It is not registered automatically so that tests which expect
+// go-type/reg lookups for Borrower items are unaffected.
+// Register it with wrapper.SetFactory in tests that need it.
+func BorrowerFactory() wrapper.Factory[Borrower] {
+	return borrowerFactory{}
+}
+
 //////////////////////////////////////////////////////////////////////////
 
 type Investment interface {
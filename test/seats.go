@@ -0,0 +1,43 @@
+package test
+
+import (
+	"github.com/madkins23/go-serial/pointer"
+)
+
+// Passenger is a KeyedTarget with an integer key.
+type Passenger struct {
+	ID   int
+	Name string
+}
+
+var _ pointer.KeyedTarget[int] = &Passenger{}
+
+func (p *Passenger) Group() string {
+	return "passenger"
+}
+
+func (p *Passenger) Key() int {
+	return p.ID
+}
+
+// SeatKey is a composite key.
+type SeatKey struct {
+	Row    int
+	Letter string
+}
+
+// Seat is a KeyedTarget with a composite key.
+type Seat struct {
+	Number SeatKey
+	Window bool
+}
+
+var _ pointer.KeyedTarget[SeatKey] = &Seat{}
+
+func (s *Seat) Group() string {
+	return "seat"
+}
+
+func (s *Seat) Key() SeatKey {
+	return s.Number
+}
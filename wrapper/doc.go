@@ -0,0 +1,16 @@
+// Package wrapper provides support shared by the json and yaml Wrapper implementations.
+//
+// A Registry provides type names for items and creates new items by type name.
+// By default the go-type/reg singleton is used.
+// A different Registry may be set for a specific Wrapper object,
+// for all wrappers of an interface type (via Adapt and SetFactory),
+// for a single serialization (e.g. json.MarshalWith),
+// or as the default for all wrappers (via SetDefaultRegistry).
+//
+// A Factory provides reflection-free type name lookup and item creation
+// for a specific interface type.
+// Factories are normally generated by the serialgen command
+// (see the -factory flag) and registered during initialization.
+// When a Factory is registered for the interface type of a Wrapper
+// it is used instead of the go-type/reg registry.
+package wrapper
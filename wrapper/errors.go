@@ -0,0 +1,82 @@
+package wrapper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrEmptyTypeField is returned when a serialized item has no type name.
+	ErrEmptyTypeField = errors.New("empty type field")
+
+	// ErrNotEnvelope is returned when a serialized item is not in a recognized form.
+	ErrNotEnvelope = errors.New("not a type/data envelope")
+
+	// ErrNilInstance is returned when a Registry or Factory makes a nil item.
+	ErrNilInstance = errors.New("nil instance")
+
+	// ErrTypeMismatch is returned when an item of the named type
+	// does not implement the expected interface type.
+	ErrTypeMismatch = errors.New("type does not match interface")
+)
+
+// -----------------------------------------------------------------------
+
+// Phase identifies the step of unwrapping an item during which an error occurred.
+type Phase string
+
+const (
+	// PhaseEnvelope is reading the type name and data from the serialized form.
+	PhaseEnvelope Phase = "envelope"
+
+	// PhaseInstantiate is making a new item of the named type.
+	PhaseInstantiate Phase = "instantiate"
+
+	// PhaseDecode is filling the new item from the serialized data.
+	PhaseDecode Phase = "decode"
+
+	// PhaseTypeAssert is converting the new item to the expected interface type.
+	PhaseTypeAssert Phase = "type-assert"
+)
+
+// DecodeError describes a failure to unwrap a serialized item.
+// The underlying error is available via errors.Unwrap, errors.Is and errors.As.
+type DecodeError struct {
+	// TypeName is the type name from the serialized form, if it was read.
+	TypeName string
+
+	// Expected is the interface type into which the item was being decoded.
+	Expected reflect.Type
+
+	// Phase is the step during which the error occurred.
+	Phase Phase
+
+	// Err is the underlying error.
+	Err error
+}
+
+// NewDecodeError returns a DecodeError for an item expected to be of interface type T.
+func NewDecodeError[T any](phase Phase, typeName string, err error) *DecodeError {
+	return &DecodeError{
+		TypeName: typeName,
+		Expected: typeOf[T](),
+		Phase:    phase,
+		Err:      err,
+	}
+}
+
+func (e *DecodeError) Error() string {
+	expected := "<unknown>"
+	if e.Expected != nil {
+		expected = e.Expected.String()
+	}
+	if e.TypeName == "" {
+		return fmt.Sprintf("%s %s: %v", e.Phase, expected, e.Err)
+	}
+	return fmt.Sprintf("%s %s as %s: %v", e.Phase, e.TypeName, expected, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,37 @@
+package wrapper
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeError(t *testing.T) {
+	err := fmt.Errorf("outer: %w", NewDecodeError[animal](PhaseTypeAssert, "[wrapper]Rock", ErrTypeMismatch))
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, PhaseTypeAssert, decodeErr.Phase)
+	assert.Equal(t, "[wrapper]Rock", decodeErr.TypeName)
+	assert.Equal(t, typeOf[animal](), decodeErr.Expected)
+	assert.Equal(t, "type-assert [wrapper]Rock as wrapper.animal: type does not match interface", decodeErr.Error())
+
+	decodeErr = NewDecodeError[animal](PhaseEnvelope, "", ErrEmptyTypeField)
+	assert.Equal(t, "envelope wrapper.animal: empty type field", decodeErr.Error())
+	assert.Equal(t, ErrEmptyTypeField, errors.Unwrap(decodeErr))
+}
+
+func TestMake_Errors(t *testing.T) {
+	ClearFactories()
+	defer ClearFactories()
+	SetFactory[animal](animalFactory{})
+	_, err := Make[animal]("dog")
+	assert.ErrorIs(t, err, ErrUnknownTypeName)
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, PhaseInstantiate, decodeErr.Phase)
+	assert.Equal(t, "dog", decodeErr.TypeName)
+}
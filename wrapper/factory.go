@@ -0,0 +1,80 @@
+package wrapper
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Factory creates items that implement interface type T by type name.
+// Implementations are expected to use type switches instead of reflection.
+type Factory[T any] interface {
+	// NameFor returns the type name for the specified item.
+	NameFor(item T) (string, error)
+
+	// Make creates a new, empty item of the type with the specified name.
+	Make(name string) (T, error)
+}
+
+// -----------------------------------------------------------------------
+
+var (
+	// ErrUnknownItemType may be returned from Factory.NameFor
+	// when the item is not of a type known to the Factory.
+	ErrUnknownItemType = errors.New("unknown item type")
+
+	// ErrUnknownTypeName may be returned from Factory.Make
+	// when the type name is not known to the Factory.
+	ErrUnknownTypeName = errors.New("unknown type name")
+)
+
+// -----------------------------------------------------------------------
+
+// factoryEntry holds a Factory and a Registry view of that Factory.
+type factoryEntry struct {
+	factory  interface{}
+	registry Registry
+}
+
+var (
+	factories    = make(map[reflect.Type]factoryEntry)
+	factoryMutex sync.RWMutex
+)
+
+// ClearFactories removes all Factory registrations.
+// For test purposes, all other usage suspect.
+func ClearFactories() {
+	factoryMutex.Lock()
+	defer factoryMutex.Unlock()
+	factories = make(map[reflect.Type]factoryEntry)
+}
+
+// GetFactory returns the Factory registered for interface type T or nil if there is none.
+func GetFactory[T any]() Factory[T] {
+	factoryMutex.RLock()
+	defer factoryMutex.RUnlock()
+	if entry, found := factories[typeOf[T]()]; found {
+		return entry.factory.(Factory[T])
+	}
+	return nil
+}
+
+// SetFactory registers a Factory for interface type T, replacing any previous Factory.
+// A nil Factory removes any previous registration.
+func SetFactory[T any](factory Factory[T]) {
+	factoryMutex.Lock()
+	defer factoryMutex.Unlock()
+	if factory == nil {
+		delete(factories, typeOf[T]())
+	} else {
+		factories[typeOf[T]()] = factoryEntry{
+			factory:  factory,
+			registry: &untyped[T]{factory: factory},
+		}
+	}
+}
+
+// typeOf returns the reflect.Type for T, which may be an interface type.
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
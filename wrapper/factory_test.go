@@ -0,0 +1,56 @@
+package wrapper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type animal interface {
+	Sound() string
+}
+
+type cat struct{}
+
+func (c *cat) Sound() string {
+	return "meow"
+}
+
+type animalFactory struct{}
+
+func (animalFactory) NameFor(item animal) (string, error) {
+	switch item.(type) {
+	case *cat:
+		return "cat", nil
+	}
+	return "", fmt.Errorf("%w: %T", ErrUnknownItemType, item)
+}
+
+func (animalFactory) Make(name string) (animal, error) {
+	switch name {
+	case "cat":
+		return &cat{}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownTypeName, name)
+}
+
+func TestFactory(t *testing.T) {
+	ClearFactories()
+	assert.Nil(t, GetFactory[animal]())
+	SetFactory[animal](animalFactory{})
+	factory := GetFactory[animal]()
+	require.NotNil(t, factory)
+	assert.Nil(t, GetFactory[fmt.Stringer]())
+	name, err := factory.NameFor(&cat{})
+	assert.NoError(t, err)
+	assert.Equal(t, "cat", name)
+	item, err := factory.Make("cat")
+	assert.NoError(t, err)
+	assert.Equal(t, &cat{}, item)
+	_, err = factory.Make("dog")
+	assert.ErrorIs(t, err, ErrUnknownTypeName)
+	SetFactory[animal](nil)
+	assert.Nil(t, GetFactory[animal]())
+}
@@ -0,0 +1,148 @@
+package wrapper
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/madkins23/go-type/reg"
+)
+
+// Registry provides type names for items and creates new items by type name.
+// Any go-type/reg.Registry object satisfies this interface.
+type Registry interface {
+	// NameFor returns the type name for the specified item.
+	NameFor(item interface{}) (string, error)
+
+	// Make creates a new, empty item of the type with the specified name.
+	Make(name string) (interface{}, error)
+}
+
+// -----------------------------------------------------------------------
+
+// singleton adapts the current go-type/reg singleton to the Registry interface.
+// The singleton is acquired with each call as it may be replaced via reg.SetSingleton.
+type singleton struct{}
+
+func (singleton) NameFor(item interface{}) (string, error) {
+	return reg.NameFor(item)
+}
+
+func (singleton) Make(name string) (interface{}, error) {
+	return reg.Make(name)
+}
+
+var (
+	defaultRegistry Registry = singleton{}
+	defaultMutex    sync.RWMutex
+)
+
+// DefaultRegistry returns the Registry used when no other Registry or Factory applies.
+// Unless otherwise configured this is the current go-type/reg singleton.
+func DefaultRegistry() Registry {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultRegistry
+}
+
+// SetDefaultRegistry sets the Registry used when no other Registry or Factory applies.
+// A nil Registry restores the go-type/reg singleton.
+func SetDefaultRegistry(registry Registry) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	if registry == nil {
+		registry = singleton{}
+	}
+	defaultRegistry = registry
+}
+
+// -----------------------------------------------------------------------
+
+// Lookup returns the Registry to use for items of the specified interface type.
+// The first non-nil Registry argument is returned if there is one,
+// then a Registry view of any Factory registered for the interface type,
+// and finally the DefaultRegistry.
+func Lookup(iface reflect.Type, registries ...Registry) Registry {
+	for _, registry := range registries {
+		if registry != nil {
+			return registry
+		}
+	}
+	factoryMutex.RLock()
+	entry, found := factories[iface]
+	factoryMutex.RUnlock()
+	if found {
+		return entry.registry
+	}
+	return DefaultRegistry()
+}
+
+// NameFor returns the type name for an item of interface type T
+// using the Registry provided by Lookup.
+func NameFor[T any](item T, registries ...Registry) (string, error) {
+	return Lookup(typeOf[T](), registries...).NameFor(item)
+}
+
+// Make creates a new, empty item of the named type as interface type T
+// using the Registry provided by Lookup.
+// Any error is returned as a *DecodeError.
+func Make[T any](name string, registries ...Registry) (T, error) {
+	var zero T
+	made, err := Lookup(typeOf[T](), registries...).Make(name)
+	if err != nil {
+		return zero, NewDecodeError[T](PhaseInstantiate, name, err)
+	} else if made == nil {
+		return zero, NewDecodeError[T](PhaseInstantiate, name, ErrNilInstance)
+	}
+	item, ok := made.(T)
+	if !ok {
+		return zero, NewDecodeError[T](PhaseTypeAssert, name, ErrTypeMismatch)
+	}
+	return item, nil
+}
+
+// -----------------------------------------------------------------------
+
+// Adapt returns a Factory for interface type T that uses the specified Registry.
+// This can be used with SetFactory to provide a separate Registry for an interface.
+func Adapt[T any](registry Registry) Factory[T] {
+	return &adapted[T]{registry: registry}
+}
+
+type adapted[T any] struct {
+	registry Registry
+}
+
+func (a *adapted[T]) NameFor(item T) (string, error) {
+	return a.registry.NameFor(item)
+}
+
+func (a *adapted[T]) Make(name string) (T, error) {
+	var zero T
+	made, err := a.registry.Make(name)
+	if err != nil {
+		return zero, err
+	}
+	item, ok := made.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s not %s", ErrTypeMismatch, name, typeOf[T]())
+	}
+	return item, nil
+}
+
+// untyped presents a Factory for interface type T as a Registry.
+type untyped[T any] struct {
+	factory Factory[T]
+}
+
+func (u *untyped[T]) NameFor(item interface{}) (string, error) {
+	typed, ok := item.(T)
+	if !ok {
+		return "", fmt.Errorf("%w: %T", ErrUnknownItemType, item)
+	}
+	return u.factory.NameFor(typed)
+}
+
+func (u *untyped[T]) Make(name string) (interface{}, error) {
+	return u.factory.Make(name)
+}
@@ -0,0 +1,92 @@
+package wrapper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/madkins23/go-type/reg"
+)
+
+func TestLookup(t *testing.T) {
+	ClearFactories()
+	defer ClearFactories()
+	animalType := typeOf[animal]()
+	assert.Equal(t, DefaultRegistry(), Lookup(animalType))
+	assert.Equal(t, DefaultRegistry(), Lookup(animalType, nil))
+
+	SetFactory[animal](animalFactory{})
+	factoryRegistry := Lookup(animalType)
+	name, err := factoryRegistry.NameFor(&cat{})
+	assert.NoError(t, err)
+	assert.Equal(t, "cat", name)
+	_, err = factoryRegistry.NameFor("meow")
+	assert.ErrorIs(t, err, ErrUnknownItemType)
+	assert.Equal(t, DefaultRegistry(), Lookup(typeOf[fmt.Stringer]()))
+
+	registry := reg.NewRegistry()
+	assert.Equal(t, registry, Lookup(animalType, nil, registry))
+	assert.Equal(t, registry, Lookup(typeOf[fmt.Stringer](), registry, factoryRegistry))
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	defer SetDefaultRegistry(nil)
+	registry := reg.NewRegistry()
+	require.NoError(t, registry.AddAlias("wrapper", &Dog{}))
+	require.NoError(t, registry.Register(&Dog{}))
+
+	// The go-type/reg singleton doesn't know about Dog.
+	_, err := NameFor[animal](&Dog{})
+	assert.Error(t, err)
+
+	SetDefaultRegistry(registry)
+	assert.Equal(t, registry, DefaultRegistry())
+	name, err := NameFor[animal](&Dog{})
+	assert.NoError(t, err)
+	assert.Equal(t, "[wrapper]Dog", name)
+	item, err := Make[animal](name)
+	assert.NoError(t, err)
+	assert.Equal(t, &Dog{}, item)
+
+	SetDefaultRegistry(nil)
+	assert.Equal(t, Registry(singleton{}), DefaultRegistry())
+}
+
+func TestAdapt(t *testing.T) {
+	ClearFactories()
+	defer ClearFactories()
+	registry := reg.NewRegistry()
+	require.NoError(t, registry.AddAlias("wrapper", &Dog{}))
+	require.NoError(t, registry.Register(&Dog{}))
+	require.NoError(t, registry.Register(&Rock{}))
+
+	SetFactory[animal](Adapt[animal](registry))
+	name, err := NameFor[animal](&Dog{})
+	assert.NoError(t, err)
+	assert.Equal(t, "[wrapper]Dog", name)
+	item, err := Make[animal](name)
+	assert.NoError(t, err)
+	assert.Equal(t, &Dog{}, item)
+
+	// Registered types that don't implement the interface are rejected.
+	name, err = registry.NameFor(&Rock{})
+	require.NoError(t, err)
+	_, err = GetFactory[animal]().Make(name)
+	assert.Error(t, err)
+	_, err = Make[animal](name)
+	assert.Error(t, err)
+}
+
+// -----------------------------------------------------------------------
+
+// Dog is exported so that it can be registered with go-type/reg.
+type Dog struct{}
+
+func (d *Dog) Sound() string {
+	return "woof"
+}
+
+// Rock is registered with go-type/reg but doesn't implement animal.
+type Rock struct{}
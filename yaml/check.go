@@ -0,0 +1,98 @@
+package yaml
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/madkins23/go-serial/pointer"
+)
+
+// CheckReferences scans a YAML document for Pointer references and reports,
+// for each one in document order, its location and whether it can be resolved.
+// The document is not decoded into any Go value and the pointer.Cache is not changed
+// (see pointer.Cache.Check), so this may be used before importing a document
+// to find references to Target items that are missing.
+//
+// References are mappings with the group and key fields (and the type and data fields
+// of embedded Target items, see WithEmbeddedTargets) generated for Pointer objects.
+// Aliases of such mappings (see WithAnchors) are also references.
+// Scalar keys of KeyedPointer objects (e.g. integers) are checked in the string form
+// used by pointer.KeyedCache (see pointer.KeyString).
+// Composite keys are not checked since their YAML field names may differ from that form.
+//
+// The pointer.Cache specified via WithCache (or the default Cache) is checked for Target items
+// and its Finder functions are called with the context specified via WithContext.
+func CheckReferences(in []byte, opts ...Option) (*pointer.CheckReport, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(in, &node); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	var refs []pointer.CheckedReference
+	scanReferences(&node, "", &refs)
+	return o.getCache().Check(o.getContext(), refs)
+}
+
+// refFields are the fields of a serialized Pointer.
+var refFields = map[string]bool{"group": true, "key": true, "type": true, "data": true}
+
+// pathEscaper escapes a JSON pointer reference token as specified by RFC 6901.
+var pathEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// scanReferences adds the references in the node at the specified path to the list.
+func scanReferences(node *yaml.Node, path string, refs *[]pointer.CheckedReference) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, content := range node.Content {
+			scanReferences(content, path, refs)
+		}
+	case yaml.AliasNode:
+		// Aliased nodes have already been scanned where they are anchored.
+		if ref, ok := reference(node.Alias); ok {
+			ref.Path, ref.Line, ref.Column, ref.Embedded = path, node.Line, node.Column, false
+			*refs = append(*refs, ref)
+		}
+	case yaml.MappingNode:
+		if ref, ok := reference(node); ok {
+			ref.Path = path
+			*refs = append(*refs, ref)
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			scanReferences(node.Content[i+1], path+"/"+pathEscaper.Replace(node.Content[i].Value), refs)
+		}
+	case yaml.SequenceNode:
+		for i, content := range node.Content {
+			scanReferences(content, path+"/"+strconv.Itoa(i), refs)
+		}
+	}
+}
+
+// reference returns a reference for the node if it is a serialized Pointer.
+func reference(node *yaml.Node) (pointer.CheckedReference, bool) {
+	ref := pointer.CheckedReference{Line: node.Line, Column: node.Column}
+	if node.Kind != yaml.MappingNode {
+		return ref, false
+	}
+	var typeName string
+	var data bool
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i].Value, node.Content[i+1]
+		if !refFields[name] {
+			return ref, false
+		}
+		switch name {
+		case "group":
+			ref.Group = value.Value
+		case "key":
+			ref.Key = value.Value
+		case "type":
+			typeName = value.Value
+		case "data":
+			data = !isNull(value)
+		}
+	}
+	ref.Embedded = typeName != "" && data
+	return ref, ref.Group != "" && ref.Key != ""
+}
@@ -0,0 +1,88 @@
+package yaml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/test"
+)
+
+func TestCheckReferences(t *testing.T) {
+	document := []byte(`Cats:
+  - group: cat
+    key: Noah
+  - {group: cat, key: Garfield}
+  - &tom
+    group: cat
+    key: Tom
+    type: '[test]Pet'
+    data: {Name: Tom, Type: cat}
+  - *tom
+  - {group: cat, key: Tom, data: null}
+Wrapped: {type: '[test]Pet', data: {Name: Rex, Type: dog}}
+Other: {group: cat, key: Lacey, Name: not a pointer}
+a/b:
+  - - {group: cat, key: Lacey}
+`)
+	cache := pointer.NewCache()
+	require.NoError(t, cache.SetTarget(test.Noah, false))
+	require.NoError(t, cache.SetFinder("cat", func(key string) (pointer.Target, error) {
+		if key == "Lacey" {
+			return test.Lacey, nil
+		}
+		return nil, pointer.ErrNoSuchTarget
+	}, false))
+
+	report, err := CheckReferences(document, WithCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, []pointer.CheckedReference{
+		{Group: "cat", Key: "Noah", Path: "/Cats/0", Line: 2, Column: 5, Resolution: pointer.InCache},
+		{Group: "cat", Key: "Garfield", Path: "/Cats/1", Line: 4, Column: 5, Resolution: pointer.Dangling},
+		{Group: "cat", Key: "Tom", Path: "/Cats/2", Line: 5, Column: 5, Embedded: true, Resolution: pointer.InDocument},
+		{Group: "cat", Key: "Tom", Path: "/Cats/3", Line: 10, Column: 5, Resolution: pointer.InDocument},
+		{Group: "cat", Key: "Tom", Path: "/Cats/4", Line: 11, Column: 5, Resolution: pointer.InDocument},
+		{Group: "cat", Key: "Lacey", Path: "/a~1b/0/0", Line: 15, Column: 7, Resolution: pointer.ByFinder},
+	}, report.References)
+	assert.Len(t, report.Dangling(), 1)
+
+	// The Cache is not changed.
+	assert.Equal(t, 1, cache.Len())
+	assert.False(t, cache.HasTarget("cat", "Tom"))
+
+	// Documents generated by MarshalWith are checked.
+	marshaled, err := MarshalWith(makeAnimals(), WithCache(pointer.NewCache()))
+	require.NoError(t, err)
+	report, err = CheckReferences(marshaled, WithCache(cache))
+	require.NoError(t, err)
+	require.Len(t, report.References, 4)
+	assert.Equal(t, "/cats/0", report.References[0].Path)
+	assert.Equal(t, "/dog", report.References[3].Path)
+
+	_, err = CheckReferences([]byte("Cats: [\n"))
+	assert.Error(t, err)
+}
+
+// TestCheckReferences_Keyed verifies that references with integer keys
+// are checked using the string form of the keys.
+func TestCheckReferences_Keyed(t *testing.T) {
+	marshaled, err := MarshalWith(&boarding{
+		Passenger: PointKeyed[int](&test.Passenger{ID: 42, Name: "Lacey"}),
+	}, WithCache(pointer.NewCache()))
+	require.NoError(t, err)
+
+	report, err := CheckReferences(marshaled, WithCache(pointer.NewCache()))
+	require.NoError(t, err)
+	require.Len(t, report.References, 1)
+	assert.Equal(t, pointer.CheckedReference{
+		Group: "passenger", Key: "42", Path: "/passenger", Line: 2, Column: 5, Resolution: pointer.Dangling,
+	}, report.References[0])
+
+	cache := pointer.NewCache()
+	require.NoError(t, pointer.NewKeyedCache[int](cache).SetTarget(&test.Passenger{ID: 42}, false))
+	report, err = CheckReferences(marshaled, WithCache(cache))
+	require.NoError(t, err)
+	assert.Empty(t, report.Dangling())
+}
@@ -0,0 +1,262 @@
+package yaml
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+// WrapSlice wraps a slice of items in a YAML wrapper that can handle serialization.
+func WrapSlice[T any](items []T) *Slice[T] {
+	s := new(Slice[T])
+	s.Set(items)
+	return s
+}
+
+// Slice is used to serialize a slice of interface items.
+// Each item is serialized within its own type/data envelope as with Wrapper:
+//
+//	positions:
+//	  - type: '[test]Stock'
+//	    data:
+//	      market: NYSE
+//	      ...
+//
+// Items may be in either the packed or the tagged form when deserialized.
+// A nil slice is serialized as YAML null and a nil item as a YAML null element.
+// Type names are provided and items created as with Wrapper.
+type Slice[T any] struct {
+	items    []T
+	tagged   bool
+	registry wrapper.Registry
+}
+
+// Get the wrapped items.
+func (s *Slice[T]) Get() []T {
+	return s.items
+}
+
+// Set the wrapped items.
+func (s *Slice[T]) Set(items []T) {
+	s.items = items
+}
+
+// IsZero returns true if the slice wrapper is nil or has no items.
+// This supports the omitempty field tag option.
+func (s *Slice[T]) IsZero() bool {
+	return s == nil || len(s.items) == 0
+}
+
+// Tagged returns true if the slice wrapper serializes items in tag mode.
+func (s *Slice[T]) Tagged() bool {
+	return s.tagged
+}
+
+// SetTagged configures whether the slice wrapper serializes items in tag mode.
+func (s *Slice[T]) SetTagged(tagged bool) {
+	s.tagged = tagged
+}
+
+// Registry returns the Registry specific to the slice wrapper, if any.
+func (s *Slice[T]) Registry() wrapper.Registry {
+	return s.registry
+}
+
+// SetRegistry specifies a Registry for all items in the slice wrapper.
+// See Wrapper.SetRegistry.
+func (s *Slice[T]) SetRegistry(registry wrapper.Registry) {
+	s.registry = registry
+}
+
+func (s *Slice[T]) MarshalYAML() (interface{}, error) {
+	return s.marshalWith(nil)
+}
+
+func (s *Slice[T]) marshalWith(opts *options) (interface{}, error) {
+	if s == nil || s.items == nil {
+		return nullNode(), nil
+	}
+
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for i, item := range s.items {
+		w := &Wrapper[T]{item: item, tagged: s.tagged, registry: s.registry}
+		encoded, err := marshalNode(w, opts)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		node.Content = append(node.Content, encoded)
+	}
+	return node, nil
+}
+
+func (s *Slice[T]) UnmarshalYAML(node *yaml.Node) error {
+	return s.unmarshalWith(nil, node)
+}
+
+func (s *Slice[T]) unmarshalWith(opts *options, node *yaml.Node) error {
+	if isNull(node) {
+		s.items = nil
+		return nil
+	} else if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("unmarshal slice: not a sequence")
+	}
+
+	items := make([]T, len(node.Content))
+	for i, child := range node.Content {
+		w := &Wrapper[T]{registry: s.registry}
+		if err := w.unmarshalWith(opts, child); err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+		items[i] = w.item
+	}
+	s.items = items
+	return nil
+}
+
+// heldItems returns the wrapped items for use by collectTargets.
+func (s *Slice[T]) heldItems() []interface{} {
+	items := make([]interface{}, len(s.items))
+	for i, item := range s.items {
+		items[i] = item
+	}
+	return items
+}
+
+// -----------------------------------------------------------------------
+
+// WrapMap wraps a map of items in a YAML wrapper that can handle serialization.
+func WrapMap[K comparable, T any](items map[K]T) *Map[K, T] {
+	m := new(Map[K, T])
+	m.Set(items)
+	return m
+}
+
+// Map is used to serialize a map of interface items.
+// Each item is serialized within its own type/data envelope as with Wrapper:
+//
+//	alpha:
+//	  type: '[test]Stock'
+//	  data:
+//	    market: NYSE
+//	    ...
+//
+// Items may be in either the packed or the tagged form when deserialized.
+// Entries are serialized in key order.
+// A nil map is serialized as YAML null and a nil item as a YAML null value.
+// Type names are provided and items created as with Wrapper.
+type Map[K comparable, T any] struct {
+	items    map[K]T
+	tagged   bool
+	registry wrapper.Registry
+}
+
+// Get the wrapped items.
+func (m *Map[K, T]) Get() map[K]T {
+	return m.items
+}
+
+// Set the wrapped items.
+func (m *Map[K, T]) Set(items map[K]T) {
+	m.items = items
+}
+
+// IsZero returns true if the map wrapper is nil or has no items.
+// This supports the omitempty field tag option.
+func (m *Map[K, T]) IsZero() bool {
+	return m == nil || len(m.items) == 0
+}
+
+// Tagged returns true if the map wrapper serializes items in tag mode.
+func (m *Map[K, T]) Tagged() bool {
+	return m.tagged
+}
+
+// SetTagged configures whether the map wrapper serializes items in tag mode.
+func (m *Map[K, T]) SetTagged(tagged bool) {
+	m.tagged = tagged
+}
+
+// Registry returns the Registry specific to the map wrapper, if any.
+func (m *Map[K, T]) Registry() wrapper.Registry {
+	return m.registry
+}
+
+// SetRegistry specifies a Registry for all items in the map wrapper.
+// See Wrapper.SetRegistry.
+func (m *Map[K, T]) SetRegistry(registry wrapper.Registry) {
+	m.registry = registry
+}
+
+func (m *Map[K, T]) MarshalYAML() (interface{}, error) {
+	return m.marshalWith(nil)
+}
+
+func (m *Map[K, T]) marshalWith(opts *options) (interface{}, error) {
+	if m == nil || m.items == nil {
+		return nullNode(), nil
+	}
+
+	keys := make([]K, 0, len(m.items))
+	for key := range m.items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		keyNode := new(yaml.Node)
+		if err := keyNode.Encode(key); err != nil {
+			return nil, fmt.Errorf("map key %v: %w", key, err)
+		}
+		w := &Wrapper[T]{item: m.items[key], tagged: m.tagged, registry: m.registry}
+		encoded, err := marshalNode(w, opts)
+		if err != nil {
+			return nil, fmt.Errorf("map key %v: %w", key, err)
+		}
+		node.Content = append(node.Content, keyNode, encoded)
+	}
+	return node, nil
+}
+
+func (m *Map[K, T]) UnmarshalYAML(node *yaml.Node) error {
+	return m.unmarshalWith(nil, node)
+}
+
+func (m *Map[K, T]) unmarshalWith(opts *options, node *yaml.Node) error {
+	if isNull(node) {
+		m.items = nil
+		return nil
+	} else if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("unmarshal map: not a mapping")
+	}
+
+	// Decoded in document order, the order of marshalWith,
+	// so that embedded Target items precede references.
+	items := make(map[K]T, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var key K
+		if err := node.Content[i].Decode(&key); err != nil {
+			return fmt.Errorf("map key %s: %w", node.Content[i].Value, err)
+		}
+		w := &Wrapper[T]{registry: m.registry}
+		if err := w.unmarshalWith(opts, node.Content[i+1]); err != nil {
+			return fmt.Errorf("map key %s: %w", node.Content[i].Value, err)
+		}
+		items[key] = w.item
+	}
+	m.items = items
+	return nil
+}
+
+// heldItems returns the wrapped items for use by collectTargets.
+func (m *Map[K, T]) heldItems() []interface{} {
+	items := make([]interface{}, 0, len(m.items))
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+	return items
+}
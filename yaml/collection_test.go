@@ -0,0 +1,151 @@
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+
+	"github.com/madkins23/go-type/reg"
+
+	"github.com/madkins23/go-serial/test"
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+type YamlCollectionTestSuite struct {
+	suite.Suite
+	showSerialized bool
+}
+
+func (suite *YamlCollectionTestSuite) SetupSuite() {
+	if showSerialized, found := os.LookupEnv("GO-TYPE-SHOW-SERIALIZED"); found {
+		var err error
+		suite.showSerialized, err = strconv.ParseBool(showSerialized)
+		suite.Require().NoError(err)
+	}
+	reg.Singleton().Clear()
+	suite.Require().NoError(reg.AddAlias("yaml", Bond{}), "creating yaml test alias")
+	suite.Require().NoError(test.Register())
+	suite.Require().NoError(reg.Register(Bond{}))
+}
+
+func TestYamlCollectionSuite(t *testing.T) {
+	suite.Run(t, new(YamlCollectionTestSuite))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+func (suite *YamlCollectionTestSuite) TestCollections() {
+	holdings := MakeHoldings()
+	marshaled, err := yaml.Marshal(holdings)
+	suite.Require().NoError(err)
+	if suite.showSerialized {
+		fmt.Println(string(marshaled))
+	}
+	suite.Assert().Contains(string(marshaled), `positions:
+    - type: '[test]Stock'
+      data:
+        market: NASDAQ`)
+	suite.Assert().Contains(string(marshaled), `- type: '[yaml]Bond'`)
+	suite.Assert().Contains(string(marshaled), `lenders:
+    federal:
+        type: '[test]Federal'`)
+	suite.Assert().Contains(string(marshaled), `ranked:
+    1:
+        type: '[test]Stock'`)
+
+	newHoldings := new(Holdings)
+	suite.Require().NoError(yaml.Unmarshal(marshaled, newHoldings))
+	suite.Assert().Equal(holdings, newHoldings)
+	suite.Assert().Equal(test.StockWalmartName, newHoldings.Ranked.Get()[2].Name())
+}
+
+func (suite *YamlCollectionTestSuite) TestCollections_Tagged() {
+	positions := WrapSlice([]test.Investment{test.MakeCostco(), nil})
+	positions.SetTagged(true)
+	marshaled, err := yaml.Marshal(positions)
+	suite.Require().NoError(err)
+	suite.Assert().Regexp(`^- !\[test\]Stock\n  market: NASDAQ\n(?s:.*)- null\n$`, string(marshaled))
+	newPositions := new(Slice[test.Investment])
+	suite.Require().NoError(yaml.Unmarshal(marshaled, newPositions))
+	suite.Assert().Equal(positions.Get(), newPositions.Get())
+
+	lenders := WrapMap(map[string]test.Borrower{"state": test.StateBondSource()})
+	lenders.SetTagged(true)
+	marshaled, err = yaml.Marshal(lenders)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("state: ![test]State\n    state: Confusion\n", string(marshaled))
+	newLenders := new(Map[string, test.Borrower])
+	suite.Require().NoError(yaml.Unmarshal(marshaled, newLenders))
+	suite.Assert().Equal(lenders.Get(), newLenders.Get())
+}
+
+func (suite *YamlCollectionTestSuite) TestCollections_Nil() {
+	holdings := &Holdings{}
+	marshaled, err := Marshal(holdings)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("positions: null\nlenders: null\n", string(marshaled))
+	newHoldings := MakeHoldings()
+	suite.Require().NoError(Unmarshal(marshaled, newHoldings))
+	suite.Assert().Nil(newHoldings.Positions)
+	suite.Assert().Nil(newHoldings.Lenders)
+
+	holdings = &Holdings{
+		Positions: WrapSlice[test.Investment](nil),
+		Lenders:   WrapMap[string, test.Borrower](nil),
+		Ranked:    WrapMap(map[int]test.Investment{}),
+	}
+	marshaled, err = yaml.Marshal(holdings)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("positions: null\nlenders: null\n", string(marshaled))
+}
+
+func (suite *YamlCollectionTestSuite) TestCollections_Registry() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("solo", &test.Stock{}))
+	suite.Require().NoError(registry.Register(&test.Stock{}))
+	positions := WrapSlice([]test.Investment{test.MakeWalmart()})
+	marshaled, err := MarshalWith(&struct{ Positions *Slice[test.Investment] }{positions}, WithRegistry(registry))
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "[solo]Stock")
+	positions.SetRegistry(wrapper.DefaultRegistry())
+	marshaled, err = yaml.Marshal(positions)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "[test]Stock")
+}
+
+func (suite *YamlCollectionTestSuite) TestCollections_Errors() {
+	var decodeErr *wrapper.DecodeError
+	suite.Assert().ErrorAs(yaml.Unmarshal([]byte(`[{data: {}}]`), new(Slice[test.Investment])), &decodeErr)
+	suite.Assert().ErrorAs(yaml.Unmarshal([]byte(`{x: {type: '[test]Federal', data: {}}}`),
+		new(Map[string, test.Investment])), &decodeErr)
+	suite.Assert().Equal(wrapper.PhaseTypeAssert, decodeErr.Phase)
+	suite.Assert().Error(yaml.Unmarshal([]byte(`{x: null}`), new(Map[int, test.Investment])))
+	suite.Assert().Error(yaml.Unmarshal([]byte(`{}`), new(Slice[test.Investment])))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// Holdings has collections of interface items without custom serialization code.
+type Holdings struct {
+	Positions *Slice[test.Investment]
+	Lenders   *Map[string, test.Borrower]
+	Ranked    *Map[int, test.Investment] `yaml:",omitempty"`
+}
+
+func MakeHoldings() *Holdings {
+	return &Holdings{
+		Positions: WrapSlice([]test.Investment{test.MakeCostco(), test.MakeWalmart(), MakeTBill()}),
+		Lenders: WrapMap(map[string]test.Borrower{
+			"federal": test.TBillSource(),
+			"state":   test.StateBondSource(),
+		}),
+		Ranked: WrapMap(map[int]test.Investment{
+			1: test.MakeCostco(),
+			2: test.MakeWalmart(),
+		}),
+	}
+}
@@ -0,0 +1,119 @@
+package yaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/madkins23/go-serial/internal/targets"
+	"github.com/madkins23/go-serial/pointer"
+)
+
+// KeyedPointer is used to specify an object with a key of type K
+// (e.g. an integer ID, UUID or composite key struct) that may be found in a cache or DB.
+//
+// The key is serialized in its natural YAML form:
+//
+//	passenger:
+//	  group: passenger
+//	  key: 42
+//	seat:
+//	  group: seat
+//	  key:
+//	    row: 3
+//	    letter: C
+//
+// Target items are stored in and acquired from a pointer.KeyedCache for the
+// pointer.Cache specified as for Pointer (see SetCache and WithCache).
+// The context specified via WithContext and the pointer.Batch specified via WithBatch
+// are used as for Pointer.
+// Other Pointer options (e.g. WithLazy and WithEmbeddedTargets) don't apply to KeyedPointer objects.
+type KeyedPointer[K comparable, T pointer.KeyedTarget[K]] struct {
+	item  T
+	cache *pointer.Cache
+}
+
+func PointKeyed[K comparable, T pointer.KeyedTarget[K]](target T) *KeyedPointer[K, T] {
+	p := new(KeyedPointer[K, T])
+	p.Set(target)
+	return p
+}
+
+// Get the Target item from the KeyedPointer.
+func (p *KeyedPointer[K, T]) Get() T {
+	return p.item
+}
+
+// Set the Target item for the KeyedPointer.
+func (p *KeyedPointer[K, T]) Set(t T) {
+	p.item = t
+}
+
+// IsZero returns true if the KeyedPointer is nil or has a nil Target item.
+// This supports the omitempty field tag option.
+func (p *KeyedPointer[K, T]) IsZero() bool {
+	return p == nil || isNil(p.item)
+}
+
+// Cache returns the pointer.Cache specific to the KeyedPointer, if any.
+func (p *KeyedPointer[K, T]) Cache() *pointer.Cache {
+	return p.cache
+}
+
+// SetCache specifies a pointer.Cache to be used by the KeyedPointer for storing and
+// acquiring its Target item instead of the Cache from the options or the default Cache.
+// A nil Cache reverts to the default behavior.
+func (p *KeyedPointer[K, T]) SetCache(cache *pointer.Cache) {
+	p.cache = cache
+}
+
+// cacheFor returns the pointer.KeyedCache to be used for the specified options.
+func (p *KeyedPointer[K, T]) cacheFor(opts *options) *pointer.KeyedCache[K] {
+	if p.cache != nil {
+		return pointer.NewKeyedCache[K](p.cache)
+	}
+	return pointer.NewKeyedCache[K](opts.getCache())
+}
+
+// -----------------------------------------------------------------------
+
+func (p *KeyedPointer[K, T]) MarshalYAML() (interface{}, error) {
+	return p.marshalWith(nil)
+}
+
+func (p *KeyedPointer[K, T]) marshalWith(opts *options) (interface{}, error) {
+	if p.IsZero() {
+		return nullNode(), nil
+	}
+
+	if err := targets.RememberKeyed[K](p.cacheFor(opts), p.item); err != nil {
+		return nil, err
+	}
+	key := p.item.Key()
+
+	return &keyedPack[K]{Group: p.item.Group(), Key: &key}, nil
+}
+
+// keyedPack is the serialized form of a KeyedPointer.
+type keyedPack[K comparable] struct {
+	Group string `yaml:"group"`
+	Key   *K     `yaml:"key"`
+}
+
+func (p *KeyedPointer[K, T]) UnmarshalYAML(node *yaml.Node) error {
+	return p.unmarshalWith(nil, node)
+}
+
+func (p *KeyedPointer[K, T]) unmarshalWith(opts *options, node *yaml.Node) error {
+	var zero T
+	if isNull(node) {
+		p.Set(zero)
+		return nil
+	}
+
+	var pack keyedPack[K]
+	if err := node.Decode(&pack); err != nil {
+		return fmt.Errorf("unmarshal packed area: %w", err)
+	}
+	return targets.ResolveKeyed[K, T](opts.getContext(), p.cacheFor(opts), opts.getBatch(), pack.Group, pack.Key, p.Set)
+}
@@ -0,0 +1,72 @@
+package yaml
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/test"
+)
+
+type boarding struct {
+	Passenger *KeyedPointer[int, *test.Passenger]
+	Seat      KeyedPointer[test.SeatKey, *test.Seat]
+	Seats     map[string]KeyedPointer[test.SeatKey, *test.Seat]
+	Empty     *KeyedPointer[int, *test.Passenger]
+}
+
+func TestKeyedPointer(t *testing.T) {
+	lacey := &test.Passenger{ID: 42, Name: "Lacey"}
+	window := &test.Seat{Number: test.SeatKey{Row: 3, Letter: "A"}, Window: true}
+	aisle := &test.Seat{Number: test.SeatKey{Row: 3, Letter: "C"}}
+	start := &boarding{
+		Passenger: PointKeyed[int](lacey),
+		Seat:      *PointKeyed[test.SeatKey](window),
+		Seats:     map[string]KeyedPointer[test.SeatKey, *test.Seat]{"aisle": *PointKeyed[test.SeatKey](aisle)},
+	}
+	cache := pointer.NewCache()
+	marshaled, err := MarshalWith(start, WithCache(cache))
+	require.NoError(t, err)
+	assert.Contains(t, string(marshaled), "passenger:\n    group: passenger\n    key: 42\n")
+	assert.Contains(t, string(marshaled), "seat:\n    group: seat\n    key:\n        row: 3\n        letter: A\n")
+	assert.True(t, pointer.NewKeyedCache[int](cache).HasTarget("passenger", 42))
+
+	finish := new(boarding)
+	require.NoError(t, UnmarshalWith(marshaled, finish, WithCache(cache)))
+	assert.Same(t, lacey, finish.Passenger.Get())
+	assert.Same(t, window, finish.Seat.Get())
+	aislePtr := finish.Seats["aisle"]
+	assert.Same(t, aisle, aislePtr.Get())
+	assert.Nil(t, finish.Empty)
+
+	// Target items are acquired via KeyedFinder functions when not in the Cache.
+	cache = pointer.NewCache()
+	seats := pointer.NewKeyedCache[test.SeatKey](cache)
+	require.NoError(t, seats.SetFinder("seat",
+		func(_ context.Context, key test.SeatKey) (pointer.KeyedTarget[test.SeatKey], error) {
+			return &test.Seat{Number: key, Window: key.Letter == "A"}, nil
+		}, false))
+	require.NoError(t, pointer.NewKeyedCache[int](cache).SetTarget(lacey, false))
+	for _, opts := range [][]Option{{WithCache(cache)}, {WithCache(cache), WithBatch()}} {
+		finish = new(boarding)
+		require.NoError(t, UnmarshalWith(marshaled, finish, opts...))
+		assert.Equal(t, start, finish)
+		cached, err := seats.GetTarget(context.Background(), "seat", window.Number)
+		require.NoError(t, err)
+		assert.Same(t, cached, finish.Seat.Get())
+	}
+}
+
+func TestKeyedPointer_Errors(t *testing.T) {
+	ptr := new(KeyedPointer[int, *test.Passenger])
+	assert.ErrorIs(t, UnmarshalWith([]byte("group: passenger"), ptr), errEmptyKeyField)
+	assert.ErrorIs(t, UnmarshalWith([]byte("key: 42"), ptr), errEmptyGroupField)
+	assert.Error(t, UnmarshalWith([]byte("{group: passenger, key: Lacey}"), ptr))
+	assert.ErrorIs(t, UnmarshalWith([]byte("{group: passenger, key: 0}"), ptr,
+		WithCache(pointer.NewCache())), pointer.ErrNoSuchTarget)
+	require.NoError(t, UnmarshalWith([]byte("null"), ptr))
+	assert.True(t, ptr.IsZero())
+}
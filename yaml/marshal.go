@@ -0,0 +1,1182 @@
+package yaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/madkins23/go-serial/pointer"
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+// Marshal serializes the value provided into a YAML document.
+//
+// Interface values (struct fields, slice and array elements, map values)
+// are automatically serialized within the same type/data envelope used by Wrapper.
+// This removes the need for shadow structures using Wrapper fields and
+// custom MarshalYAML methods to copy data into them.
+// The types of all interface values must be known to the Registry
+// returned by wrapper.Lookup for the interface type,
+// normally a Factory for the interface or the go-type/reg singleton.
+//
+// Plain data held in empty interfaces (nil, booleans, numbers, strings,
+// map[string]interface{} and []interface{}) is serialized without the envelope
+// as it would be by gopkg.in/yaml.v3.
+// A map[string]interface{} with only type and data entries would be read as the envelope
+// so it is rejected.
+//
+// Types that implement yaml.Marshaler or yaml.Unmarshaler
+// and types that contain no interface values are serialized using gopkg.in/yaml.v3.
+// Struct fields follow the gopkg.in/yaml.v3 field naming and tag conventions.
+func Marshal(v interface{}) ([]byte, error) {
+	return MarshalWith(v)
+}
+
+// MarshalWith serializes the value provided into a YAML document as does Marshal,
+// configured by the specified Option values (e.g. WithRegistry).
+// With no Option values this is the same as calling Marshal.
+func MarshalWith(v interface{}, opts ...Option) ([]byte, error) {
+	e := &encoder{opts: newOptions(opts)}
+	node, err := e.encode(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(node)
+}
+
+// Unmarshal decodes the first document found within the in byte slice
+// and assigns decoded values into the out value.
+//
+// Interface values are read from the type/data envelope generated by Marshal,
+// instantiated via the Registry returned by wrapper.Lookup
+// and then filled from the envelope data.
+// The tagged form generated by Wrapper in tag mode is also accepted.
+// Other values for empty interfaces are read as by gopkg.in/yaml.v3
+// with any envelopes within them read as above.
+// Failures to unwrap interface values are returned as *wrapper.DecodeError.
+//
+// Types that implement yaml.Marshaler or yaml.Unmarshaler
+// and types that contain no interface values are deserialized using gopkg.in/yaml.v3.
+func Unmarshal(in []byte, out interface{}) error {
+	return UnmarshalWith(in, out)
+}
+
+// UnmarshalWith decodes the first document found within the in byte slice as does Unmarshal,
+// configured by the specified Option values (e.g. WithRegistry).
+// With no Option values this is the same as calling Unmarshal.
+func UnmarshalWith(in []byte, out interface{}, opts ...Option) error {
+	value := reflect.ValueOf(out)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return fmt.Errorf("%w: %T", errNotPointer, out)
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(in, &node); err != nil {
+		return err
+	}
+	if node.Kind == 0 {
+		// Empty document.
+		return nil
+	}
+	d := &decoder{opts: newOptions(opts)}
+	if err := d.decode(&node, value.Elem()); err != nil {
+		return err
+	}
+	return d.opts.resolve(value.Elem())
+}
+
+var (
+	errNotPointer       = errors.New("unmarshal target not a non-nil pointer")
+	errUnexportedInline = errors.New("cannot set pointer to unexported inlined struct")
+	errTagOption        = errors.New("unsupported field tag option")
+	errAmbiguousMap     = errors.New("map with only type and data entries in empty interface")
+)
+
+//////////////////////////////////////////////////////////////////////////
+
+// Option configures a single MarshalWith or UnmarshalWith call.
+type Option func(*options)
+
+// WithRegistry specifies a Registry for all interface values and Wrapper objects
+// that don't have their own Registry.
+// A nil Registry is the same as not specifying one.
+func WithRegistry(registry wrapper.Registry) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithCache specifies a pointer.Cache for all Pointer objects
+// that don't have their own Cache.
+// A nil Cache is the same as not specifying one.
+func WithCache(cache *pointer.Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithContext specifies a context to be passed to any pointer.ContextFinder
+// called while unmarshaling Pointer objects.
+// When the context is done, unmarshaling Pointer objects with Target items
+// not already in the Cache will fail with the context error.
+// A nil context is the same as not specifying one.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithBatch defers acquiring the Target items for Pointer objects while unmarshaling
+// until the entire document has been decoded.
+// The Target items are then acquired with a single pointer.Cache.GetTargets call per group,
+// using the pointer.BatchFinder for the group if there is one.
+// This avoids calling the Finder for the group once for each Pointer.
+//
+// Pointer objects must not be copied during decoding (e.g. by Factory methods
+// that return value types) or the copies will not receive their Target items.
+// Pointer objects that are map values are handled.
+func WithBatch() Option {
+	return func(o *options) {
+		if o.batch == nil {
+			o.batch = new(pointer.Batch)
+		}
+	}
+}
+
+// WithEmbeddedTargets causes the first Pointer to each Target item during a MarshalWith call
+// to be serialized with the Target item embedded in the type/data envelope used by Wrapper:
+//
+//	dog:
+//	  group: dog
+//	  key: Knight
+//	  type: '[test]Pet'
+//	  data:
+//	    name: Knight
+//	    ...
+//
+// Later Pointer objects to the same Target item are serialized as references.
+// The result can be unmarshaled where the pointer.Cache doesn't have the Target items,
+// as each embedded Target item is added to the Cache before any references to it are decoded.
+// The types of embedded Target items must be known to the Registry (see WithRegistry).
+func WithEmbeddedTargets() Option {
+	return func(o *options) {
+		o.embedded = make(map[string]map[string]bool)
+	}
+}
+
+// WithAnchors causes the first Pointer to each Target item during a MarshalWith call
+// to be serialized with the Target item embedded as with WithEmbeddedTargets
+// and later Pointer objects to the same Target item to be serialized as YAML aliases:
+//
+//	dog: &dog-Knight
+//	  group: dog
+//	  key: Knight
+//	  type: '[test]Pet'
+//	  data:
+//	    name: Knight
+//	    ...
+//	walker: *dog-Knight
+//
+// Anchors are only added to Target items referenced more than once.
+// Anchor names are derived from the group and key of the Target item,
+// with characters not allowed in YAML anchors replaced by underscores.
+//
+// UnmarshalWith always sets Pointer objects decoded from an alias
+// to the same Target item as the Pointer decoded from the anchored node.
+func WithAnchors() Option {
+	return func(o *options) {
+		if o.embedded == nil {
+			o.embedded = make(map[string]map[string]bool)
+		}
+		o.anchors = make(map[string]map[string]*yaml.Node)
+		o.anchorNames = make(map[string]bool)
+	}
+}
+
+// WithForwardReferences allows Pointer objects to refer to Target items
+// that are defined later in the document being unmarshaled.
+// Acquiring Target items is deferred as with WithBatch until the entire document is decoded.
+// All Target items found in the decoded document are then added to the pointer.Cache
+// and used in preference to any other Target items with the same group and key.
+// Only Pointer objects referring to Target items neither in the document nor
+// available from the Cache return errors.
+//
+// Target items are found in exported struct fields, slices, arrays, map values,
+// interface values, and the items of Wrapper, Slice and Map objects.
+func WithForwardReferences() Option {
+	return func(o *options) {
+		if o.batch == nil {
+			o.batch = new(pointer.Batch)
+		}
+		o.forward = true
+	}
+}
+
+// WithDangling specifies how Pointer objects referring to Target items that can't be found
+// (neither in the pointer.Cache nor via a Finder) are handled while unmarshaling.
+// By default (pointer.DanglingFail) unmarshaling fails at the first such Pointer.
+// With pointer.DanglingKeep each such Pointer is left without a Target item
+// but remembers the group and key so that it is marshaled as it was read.
+// With pointer.DanglingPlaceholder each such Pointer is set to the Target item
+// returned by the pointer.Placeholder for the group (see pointer.Cache.SetPlaceholder).
+// With pointer.DanglingReport each such Pointer is handled as with pointer.DanglingKeep
+// and after the entire document is decoded a *pointer.DanglingError listing
+// all such Pointer references is returned.
+func WithDangling(policy pointer.DanglingPolicy) Option {
+	return func(o *options) {
+		o.dangling = policy
+	}
+}
+
+// WithLazy causes all Pointer objects to be unmarshaled lazily,
+// recording the group and key of each Target item without acquiring it.
+// The Target item is acquired when first requested via Pointer.Get or Pointer.Resolve.
+// This takes precedence over WithBatch.
+func WithLazy() Option {
+	return func(o *options) {
+		o.lazy = true
+	}
+}
+
+// options holds configuration for a single Marshal or Unmarshal call.
+type options struct {
+	registry wrapper.Registry
+	cache    *pointer.Cache
+	ctx      context.Context
+	batch    *pointer.Batch
+	lazy     bool
+	forward  bool
+	embedded map[string]map[string]bool
+	dangling pointer.DanglingPolicy
+	dangled  []pointer.DanglingReference
+
+	anchors      map[string]map[string]*yaml.Node
+	anchorNames  map[string]bool
+	aliasTargets map[*yaml.Node]pointer.Target
+}
+
+func newOptions(opts []Option) *options {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// getRegistry returns the configured Registry, if any.
+// A nil options pointer is acceptable.
+func (o *options) getRegistry() wrapper.Registry {
+	if o == nil {
+		return nil
+	}
+	return o.registry
+}
+
+// getCache returns the configured pointer.Cache or the default Cache.
+// A nil options pointer is acceptable.
+func (o *options) getCache() *pointer.Cache {
+	if o == nil || o.cache == nil {
+		return pointer.DefaultCache()
+	}
+	return o.cache
+}
+
+// getContext returns the configured context or context.Background().
+// A nil options pointer is acceptable.
+func (o *options) getContext() context.Context {
+	if o == nil || o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// getBatch returns the configured pointer.Batch, if any.
+// A nil options pointer is acceptable.
+func (o *options) getBatch() *pointer.Batch {
+	if o == nil {
+		return nil
+	}
+	return o.batch
+}
+
+// embed returns true if the Target item with the specified group and key is to be embedded,
+// which is the first time it is requested when using WithEmbeddedTargets.
+// A nil options pointer is acceptable.
+func (o *options) embed(group, key string) bool {
+	if o == nil || o.embedded == nil || o.embedded[group][key] {
+		return false
+	}
+	if o.embedded[group] == nil {
+		o.embedded[group] = make(map[string]bool)
+	}
+	o.embedded[group][key] = true
+	return true
+}
+
+// anchor records the node generated for the Target item with the specified group and key
+// so that later Pointer objects to the same Target item may be serialized as aliases.
+// A nil options pointer is acceptable.
+func (o *options) anchor(group, key string, node *yaml.Node) {
+	if o == nil || o.anchors == nil {
+		return
+	}
+	if o.anchors[group] == nil {
+		o.anchors[group] = make(map[string]*yaml.Node)
+	}
+	o.anchors[group][key] = node
+}
+
+// alias returns an alias node for the Target item with the specified group and key
+// or nil if no node has been recorded for the Target item via anchor.
+// The anchor name is set on the recorded node the first time it is aliased.
+// A nil options pointer is acceptable.
+func (o *options) alias(group, key string) *yaml.Node {
+	if o == nil || o.anchors == nil {
+		return nil
+	}
+	node := o.anchors[group][key]
+	if node == nil {
+		return nil
+	}
+	if node.Anchor == "" {
+		node.Anchor = o.anchorName(group, key)
+	}
+	return &yaml.Node{Kind: yaml.AliasNode, Alias: node, Value: node.Anchor}
+}
+
+// anchorName returns a unique anchor name derived from the group and key.
+// YAML anchors may only contain letters, digits, underscores and hyphens.
+func (o *options) anchorName(group, key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= '0' && r <= '9') || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
+			return r
+		}
+		return '_'
+	}, group+"-"+key)
+	unique := name
+	for i := 2; o.anchorNames[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	o.anchorNames[unique] = true
+	return unique
+}
+
+// aliased returns the Target item decoded from the specified anchored node, if any.
+// A nil options pointer is acceptable.
+func (o *options) aliased(node *yaml.Node) pointer.Target {
+	if o == nil {
+		return nil
+	}
+	return o.aliasTargets[node]
+}
+
+// setAliased records the Target item decoded from the specified node if it is anchored,
+// so that Pointer objects decoded from aliases of the node get the same Target item.
+// A nil options pointer is acceptable.
+func (o *options) setAliased(node *yaml.Node, target pointer.Target) {
+	if o == nil || node.Anchor == "" {
+		return
+	}
+	if o.aliasTargets == nil {
+		o.aliasTargets = make(map[*yaml.Node]pointer.Target)
+	}
+	o.aliasTargets[node] = target
+}
+
+// getDangling returns the configured pointer.DanglingPolicy.
+// A nil options pointer is acceptable.
+func (o *options) getDangling() pointer.DanglingPolicy {
+	if o == nil {
+		return pointer.DanglingFail
+	}
+	return o.dangling
+}
+
+// dangle handles a reference to a Target item that can't be found
+// according to the configured pointer.DanglingPolicy.
+// The result is a placeholder Target item, nil if the reference is to be left unresolved,
+// or pointer.ErrNoSuchTarget if unmarshaling is to fail.
+// A nil options pointer is acceptable.
+func (o *options) dangle(cache *pointer.Cache, group, key string) (pointer.Target, error) {
+	switch o.getDangling() {
+	case pointer.DanglingKeep:
+		return nil, nil
+	case pointer.DanglingReport:
+		o.dangled = append(o.dangled, pointer.DanglingReference{Group: group, Key: key})
+		return nil, nil
+	case pointer.DanglingPlaceholder:
+		if placeholder := cache.GetPlaceholder(group); placeholder != nil {
+			if target := placeholder(key); target != nil {
+				return target, nil
+			}
+		}
+	}
+	return nil, pointer.ErrNoSuchTarget
+}
+
+// getLazy returns true if Pointer objects are to be unmarshaled lazily.
+// A nil options pointer is acceptable.
+func (o *options) getLazy() bool {
+	return o != nil && o.lazy
+}
+
+// resolve acquires the Target items for any Pointer objects in the configured pointer.Batch.
+// With forward references the Target items in the decoded value are defined first.
+func (o *options) resolve(decoded reflect.Value) error {
+	if batch := o.getBatch(); batch != nil {
+		if err := o.resolveBatch(batch, decoded); err != nil {
+			return err
+		}
+	}
+	if len(o.dangled) > 0 {
+		return &pointer.DanglingError{References: o.dangled}
+	}
+	return nil
+}
+
+// resolveBatch acquires the Target items for the Pointer objects in the pointer.Batch.
+func (o *options) resolveBatch(batch *pointer.Batch, decoded reflect.Value) error {
+	if o.dangling != pointer.DanglingFail {
+		batch.SetMissing(o.dangle)
+	}
+	if o.forward {
+		collectTargets(decoded, func(target pointer.Target) {
+			batch.Define(o.getCache(), target)
+		})
+	}
+	return batch.Resolve(o.getContext())
+}
+
+// optionsUser is implemented by types (e.g. Wrapper and Pointer) that make use of
+// the options passed down through Marshal and Unmarshal.
+type optionsUser interface {
+	marshalWith(opts *options) (interface{}, error)
+	unmarshalWith(opts *options, node *yaml.Node) error
+}
+
+// usesOptions returns true if a pointer to the type implements optionsUser.
+func usesOptions(t reflect.Type) bool {
+	return t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(optionsUserType)
+}
+
+var (
+	optionsUserType = reflect.TypeOf((*optionsUser)(nil)).Elem()
+	marshalerType   = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	nodeType        = reflect.TypeOf(yaml.Node{})
+	anyMapType      = reflect.TypeOf(map[string]interface{}{})
+	anySliceType    = reflect.TypeOf([]interface{}{})
+)
+
+// customized returns true if the type or a pointer to the type
+// provides its own YAML serialization.
+func customized(t reflect.Type) bool {
+	if t == nodeType {
+		return true
+	}
+	for _, custom := range []reflect.Type{marshalerType, unmarshalerType} {
+		if t.Implements(custom) || reflect.PointerTo(t).Implements(custom) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkCache holds types known to require walking.
+// Types not requiring walking are only stored after a complete search.
+var walkCache sync.Map
+
+// needsWalk returns true if the type contains interface values
+// that can't be serialized by gopkg.in/yaml.v3 without help
+// or values that make use of the options.
+func needsWalk(t reflect.Type) bool {
+	result, _ := walkable(t, make(map[reflect.Type]bool))
+	return result
+}
+
+// walkable returns true if the type requires the walker.
+// The seen map holds the types currently being checked.
+// The second result is true if a recursive type was found so that a false result is not definitive.
+func walkable(t reflect.Type, seen map[reflect.Type]bool) (bool, bool) {
+	if cached, found := walkCache.Load(t); found {
+		return cached.(bool), false
+	} else if seen[t] {
+		// Recursive type, the rest of the type will determine the result.
+		return false, true
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var result, recursive bool
+	if usesOptions(t) || (t.Kind() == reflect.Pointer && usesOptions(t.Elem())) {
+		// Checked before customized as these types also implement the standard interfaces.
+		result = true
+	} else if !customized(t) {
+		switch t.Kind() {
+		case reflect.Interface:
+			result = true
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			result, recursive = walkable(t.Elem(), seen)
+		case reflect.Struct:
+			fields, err := fieldsOf(t)
+			if err != nil {
+				// The walker reports the error.
+				result = true
+			}
+			for _, field := range fields {
+				var r bool
+				if result, r = walkable(field.typ, seen); result {
+					break
+				}
+				recursive = recursive || r
+			}
+		}
+	}
+
+	// A false result that depends on a recursive type is only definitive at the top of the search.
+	if result || !recursive || len(seen) == 1 {
+		walkCache.Store(t, result)
+		recursive = false
+	}
+	return result, recursive
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// field describes a serializable struct field.
+type field struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	flow      bool
+}
+
+var fieldCache sync.Map
+
+// cachedFields holds the result of fieldsOf for a struct type.
+type cachedFields struct {
+	fields []field
+	err    error
+}
+
+// fieldsOf returns the serializable fields for the specified struct type
+// following the gopkg.in/yaml.v3 rules for names, tags and inlined structs.
+// As with gopkg.in/yaml.v3 field tag options other than omitempty, flow and inline
+// (e.g. the string option supported by encoding/json) are errors.
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if cached, found := fieldCache.Load(t); found {
+		return cached.(cachedFields).fields, cached.(cachedFields).err
+	}
+
+	var fields []field
+	var err error
+	var collect func(t reflect.Type, index []int)
+	collect = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() && !sf.Anonymous {
+				continue
+			}
+			tag := sf.Tag.Get("yaml")
+			if tag == "" && !strings.Contains(string(sf.Tag), ":") {
+				tag = string(sf.Tag)
+			}
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			if options != "" {
+				for _, option := range strings.Split(options, ",") {
+					switch option {
+					case "omitempty", "flow", "inline":
+					default:
+						if err == nil {
+							err = fmt.Errorf("%w '%s' in tag '%s' of %s", errTagOption, option, tag, t)
+						}
+					}
+				}
+			}
+			options = "," + options + ","
+			fieldIndex := append(append([]int{}, index...), i)
+			if strings.Contains(options, ",inline,") {
+				inlined := sf.Type
+				if inlined.Kind() == reflect.Pointer {
+					inlined = inlined.Elem()
+				}
+				if inlined.Kind() == reflect.Struct {
+					collect(inlined, fieldIndex)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(sf.Name)
+			}
+			fields = append(fields, field{
+				name:      name,
+				index:     fieldIndex,
+				typ:       sf.Type,
+				omitEmpty: strings.Contains(options, ",omitempty,"),
+				flow:      strings.Contains(options, ",flow,"),
+			})
+		}
+	}
+	collect(t, nil)
+
+	fieldCache.Store(t, cachedFields{fields: fields, err: err})
+	return fields, err
+}
+
+// fieldByIndex returns the field at the specified index path.
+// If alloc is true nil inlined struct pointers are allocated,
+// otherwise an invalid value is returned when one is found.
+// An invalid value is also returned for a nil pointer to an unexported inlined struct
+// which can't be allocated.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// isZero follows the gopkg.in/yaml.v3 definition of empty for omitempty.
+// In addition, addressable values with IsZero methods on pointer receivers
+// (e.g. Wrapper and Pointer) are checked via those methods.
+func isZero(v reflect.Value) bool {
+	if v.Kind() != reflect.Pointer && v.CanAddr() {
+		if zeroer, ok := v.Addr().Interface().(yaml.IsZeroer); ok {
+			return zeroer.IsZero()
+		}
+	}
+	if zeroer, ok := v.Interface().(yaml.IsZeroer); ok {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			return true
+		}
+		return zeroer.IsZero()
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() && !isZero(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return v.IsZero()
+}
+
+// isNil returns true if the item is nil or a nil pointer, map, slice, etc.
+func isNil(item interface{}) bool {
+	if item == nil {
+		return true
+	}
+	v := reflect.ValueOf(item)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// nullNode returns a new YAML null node.
+func nullNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// isNull returns true if the node represents a YAML null.
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// encoder walks a value generating YAML nodes.
+type encoder struct {
+	opts *options
+}
+
+func (e *encoder) encode(v reflect.Value) (*yaml.Node, error) {
+	if !v.IsValid() {
+		return nullNode(), nil
+	} else if !needsWalk(v.Type()) {
+		node := new(yaml.Node)
+		if err := node.Encode(addressable(v).Interface()); err != nil {
+			return nil, err
+		}
+		return node, nil
+	} else if usesOptions(v.Type()) {
+		return e.encodeOptionsUser(addressable(v).Interface().(optionsUser))
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		return e.encodeInterface(v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nullNode(), nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nullNode(), nil
+		}
+		return e.encodeArray(v)
+	default:
+		node := new(yaml.Node)
+		if err := node.Encode(v.Interface()); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+}
+
+// addressable returns a pointer to the value so that
+// methods with pointer receivers are available.
+func addressable(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		return v
+	} else if v.CanAddr() {
+		return v.Addr()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr
+}
+
+func (e *encoder) encodeOptionsUser(user optionsUser) (*yaml.Node, error) {
+	return marshalNode(user, e.opts)
+}
+
+// marshalNode returns the result of marshaling the optionsUser as a YAML node.
+func marshalNode(user optionsUser, opts *options) (*yaml.Node, error) {
+	marshaled, err := user.marshalWith(opts)
+	if err != nil {
+		return nil, err
+	} else if node, ok := marshaled.(*yaml.Node); ok {
+		return node, nil
+	}
+	node := new(yaml.Node)
+	if err = node.Encode(marshaled); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (e *encoder) encodeInterface(v reflect.Value) (*yaml.Node, error) {
+	if v.IsNil() {
+		return nullNode(), nil
+	}
+
+	item := v.Elem()
+	if v.NumMethod() == 0 && isPlain(item.Type()) {
+		if m, ok := item.Interface().(map[string]interface{}); ok && isPackedMap(m) {
+			return nil, errAmbiguousMap
+		}
+		return e.encode(item)
+	}
+	registry := wrapper.Lookup(v.Type(), e.opts.getRegistry())
+	typeName, err := registry.NameFor(item.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("get type name for %#v: %w", item.Interface(), err)
+	}
+	data, err := e.encode(item)
+	if err != nil {
+		return nil, fmt.Errorf("marshal packed area: %w", err)
+	}
+	return &yaml.Node{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			stringNode(typeField), stringNode(typeName),
+			stringNode(dataField), data,
+		},
+	}, nil
+}
+
+// isPlain returns true if values of the type are serialized in an empty interface
+// without the type/data envelope.
+// These are the unnamed map and slice types generated by gopkg.in/yaml.v3 for empty interfaces
+// and the predeclared boolean, numeric and string types.
+func isPlain(t reflect.Type) bool {
+	if t == anyMapType || t == anySliceType {
+		return true
+	} else if t.PkgPath() != "" {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// isPackedMap returns true if the map would be read as the type/data envelope.
+func isPackedMap(m map[string]interface{}) bool {
+	if len(m) != 2 {
+		return false
+	} else if _, found := m[dataField]; !found {
+		return false
+	}
+	_, ok := m[typeField].(string)
+	return ok
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) (*yaml.Node, error) {
+	fields, err := fieldsOf(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fields {
+		fv := fieldByIndex(v, f.index, false)
+		if !fv.IsValid() || (f.omitEmpty && isZero(fv)) {
+			continue
+		}
+		encoded, err := e.encode(fv)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		if f.flow {
+			encoded.Style |= yaml.FlowStyle
+		}
+		node.Content = append(node.Content, stringNode(f.name), encoded)
+	}
+	return node, nil
+}
+
+func (e *encoder) encodeMap(v reflect.Value) (*yaml.Node, error) {
+	if v.IsNil() {
+		return nullNode(), nil
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		keyNode := new(yaml.Node)
+		if err := keyNode.Encode(key.Interface()); err != nil {
+			return nil, fmt.Errorf("map key %v: %w", key.Interface(), err)
+		}
+		encoded, err := e.encode(v.MapIndex(key))
+		if err != nil {
+			return nil, fmt.Errorf("map key %v: %w", key.Interface(), err)
+		}
+		node.Content = append(node.Content, keyNode, encoded)
+	}
+	return node, nil
+}
+
+func (e *encoder) encodeArray(v reflect.Value) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for i := 0; i < v.Len(); i++ {
+		encoded, err := e.encode(v.Index(i))
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		node.Content = append(node.Content, encoded)
+	}
+	return node, nil
+}
+
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// decoder walks a value filling it from YAML nodes.
+type decoder struct {
+	opts *options
+}
+
+func (d *decoder) decode(node *yaml.Node, v reflect.Value) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) < 1 {
+			return nil
+		}
+		return d.decode(node.Content[0], v)
+	case yaml.AliasNode:
+		return d.decode(node.Alias, v)
+	}
+
+	if !needsWalk(v.Type()) {
+		return node.Decode(v.Addr().Interface())
+	}
+
+	if usesOptions(v.Type()) {
+		// Checked first so that these types can handle null themselves.
+		return v.Addr().Interface().(optionsUser).unmarshalWith(d.opts, node)
+	} else if isNull(node) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			return d.decodeAny(node, v)
+		}
+		return d.decodeInterface(node, v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(node, v.Elem())
+	case reflect.Struct:
+		return d.decodeStruct(node, v)
+	case reflect.Map:
+		return d.decodeMap(node, v)
+	case reflect.Slice, reflect.Array:
+		return d.decodeArray(node, v)
+	default:
+		return node.Decode(v.Addr().Interface())
+	}
+}
+
+func (d *decoder) decodeInterface(node *yaml.Node, v reflect.Value) error {
+	typeName, data, err := unpack(node)
+	if err != nil {
+		return decodeError(v, wrapper.PhaseEnvelope, "", err)
+	}
+	temp, err := wrapper.Lookup(v.Type(), d.opts.getRegistry()).Make(typeName)
+	if err != nil {
+		return decodeError(v, wrapper.PhaseInstantiate, typeName, err)
+	} else if temp == nil {
+		return decodeError(v, wrapper.PhaseInstantiate, typeName, wrapper.ErrNilInstance)
+	}
+	if isLegacyRawForm(data, temp) {
+		legacy := new(yaml.Node)
+		if err = yaml.Unmarshal([]byte(data.Value), legacy); err != nil {
+			return decodeError(v, wrapper.PhaseDecode, typeName, err)
+		}
+		data = legacy
+	}
+	// Factories may return values for types implementing interfaces by value.
+	instance := addressable(reflect.ValueOf(temp))
+	if err = d.decode(data, instance.Elem()); err != nil {
+		return decodeError(v, wrapper.PhaseDecode, typeName, err)
+	}
+	return assign(v, instance, typeName)
+}
+
+// unpack returns the type name and data node for a wrapped item
+// in either the tagged or the packed form.
+func unpack(node *yaml.Node) (string, *yaml.Node, error) {
+	if typeName, tagged := tagTypeName(node); tagged {
+		return typeName, untag(node), nil
+	} else if node.Kind != yaml.MappingNode {
+		return "", nil, wrapper.ErrNotEnvelope
+	}
+
+	var typeName string
+	data := nullNode()
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case typeField:
+			typeName = node.Content[i+1].Value
+		case dataField:
+			data = node.Content[i+1]
+		}
+	}
+	if typeName == "" {
+		return "", nil, wrapper.ErrEmptyTypeField
+	}
+	return typeName, data, nil
+}
+
+// decodeAny decodes a value for an empty interface.
+// The type/data envelope and the tagged form are decoded as for other interfaces,
+// other values are decoded as by gopkg.in/yaml.v3 with any envelopes within
+// mappings with string keys and sequences decoded as well.
+func (d *decoder) decodeAny(node *yaml.Node, v reflect.Value) error {
+	if _, tagged := tagTypeName(node); tagged || isPackedNode(node) {
+		return d.decodeInterface(node, v)
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		if !hasStringKeys(node) {
+			return node.Decode(v.Addr().Interface())
+		}
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value := reflect.New(v.Type()).Elem()
+			if err := d.decode(node.Content[i+1], value); err != nil {
+				return fmt.Errorf("map key %s: %w", node.Content[i].Value, err)
+			}
+			m[node.Content[i].Value] = value.Interface()
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	case yaml.SequenceNode:
+		items := make([]interface{}, len(node.Content))
+		for i, itemNode := range node.Content {
+			value := reflect.New(v.Type()).Elem()
+			if err := d.decode(itemNode, value); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+			items[i] = value.Interface()
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	default:
+		return node.Decode(v.Addr().Interface())
+	}
+}
+
+// isPackedNode returns true if the node is the type/data envelope.
+func isPackedNode(node *yaml.Node) bool {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 4 {
+		return false
+	}
+	var typed, data bool
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case typeField:
+			value := node.Content[i+1]
+			typed = value.Kind == yaml.ScalarNode && value.ShortTag() == "!!str"
+		case dataField:
+			data = true
+		}
+	}
+	return typed && data
+}
+
+// hasStringKeys returns true if all keys of the mapping node are strings.
+func hasStringKeys(node *yaml.Node) bool {
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i]; key.Kind != yaml.ScalarNode || key.ShortTag() != "!!str" {
+			return false
+		}
+	}
+	return true
+}
+
+// assign sets the interface value to the instance or,
+// if only the value type implements the interface, the value pointed to.
+func assign(v reflect.Value, instance reflect.Value, typeName string) error {
+	if instance.Type().AssignableTo(v.Type()) {
+		v.Set(instance)
+	} else if instance.Elem().Type().AssignableTo(v.Type()) {
+		v.Set(instance.Elem())
+	} else {
+		return decodeError(v, wrapper.PhaseTypeAssert, typeName, wrapper.ErrTypeMismatch)
+	}
+	return nil
+}
+
+// decodeError returns a wrapper.DecodeError for the specified interface value.
+func decodeError(v reflect.Value, phase wrapper.Phase, typeName string, err error) error {
+	return &wrapper.DecodeError{
+		TypeName: typeName,
+		Expected: v.Type(),
+		Phase:    phase,
+		Err:      err,
+	}
+}
+
+func (d *decoder) decodeStruct(node *yaml.Node, v reflect.Value) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("unmarshal %s: not a mapping", v.Type())
+	}
+	fields, err := fieldsOf(v.Type())
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		for _, f := range fields {
+			if f.name == name {
+				fv := fieldByIndex(v, f.index, true)
+				if !fv.IsValid() {
+					return fmt.Errorf("field %s: %w", f.name, errUnexportedInline)
+				} else if err := d.decode(node.Content[i+1], fv); err != nil {
+					return fmt.Errorf("field %s: %w", f.name, err)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(node *yaml.Node, v reflect.Value) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("unmarshal %s: not a mapping", v.Type())
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(node.Content)/2))
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := reflect.New(v.Type().Key())
+		if err := node.Content[i].Decode(key.Interface()); err != nil {
+			return fmt.Errorf("map key %s: %w", node.Content[i].Value, err)
+		}
+		value := reflect.New(v.Type().Elem()).Elem()
+		pending := d.opts.getBatch().Len()
+		if err := d.decode(node.Content[i+1], value); err != nil {
+			return fmt.Errorf("map key %s: %w", node.Content[i].Value, err)
+		}
+		v.SetMapIndex(key.Elem(), value)
+		d.afterBatch(pending, v, key.Elem(), value)
+	}
+	return nil
+}
+
+// afterBatch copies the map value into the map again after the pointer.Batch is resolved
+// if decoding the value added Pointer objects to the Batch,
+// since the map holds a copy of the value made before the Target items were set.
+func (d *decoder) afterBatch(pending int, m, key, value reflect.Value) {
+	if batch := d.opts.getBatch(); batch.Len() > pending && value.Kind() != reflect.Pointer {
+		batch.After(func() {
+			m.SetMapIndex(key, value)
+		})
+	}
+}
+
+func (d *decoder) decodeArray(node *yaml.Node, v reflect.Value) error {
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("unmarshal %s: not a sequence", v.Type())
+	}
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), len(node.Content), len(node.Content)))
+	}
+	for i := 0; i < len(node.Content) && i < v.Len(); i++ {
+		if err := d.decode(node.Content[i], v.Index(i)); err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,419 @@
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+
+	"github.com/madkins23/go-type/reg"
+
+	"github.com/madkins23/go-serial/test"
+	"github.com/madkins23/go-serial/wrapper"
+)
+
+type YamlMarshalTestSuite struct {
+	suite.Suite
+	showSerialized bool
+}
+
+func (suite *YamlMarshalTestSuite) SetupSuite() {
+	if showSerialized, found := os.LookupEnv("GO-TYPE-SHOW-SERIALIZED"); found {
+		var err error
+		suite.showSerialized, err = strconv.ParseBool(showSerialized)
+		suite.Require().NoError(err)
+	}
+	reg.Singleton().Clear()
+	suite.Require().NoError(reg.AddAlias("yaml", Bond{}), "creating yaml test alias")
+	suite.Require().NoError(test.Register())
+	suite.Require().NoError(reg.Register(Bond{}))
+	suite.Require().NoError(reg.Register(PlainBond{}))
+}
+
+func TestYamlMarshalSuite(t *testing.T) {
+	suite.Run(t, new(YamlMarshalTestSuite))
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// TestPlain tests automatic wrapping of interface fields in structs
+// that have no custom serialization code.
+func (suite *YamlMarshalTestSuite) TestPlain() {
+	portfolio := MakePlainPortfolio()
+	marshaled, err := Marshal(portfolio)
+	suite.Require().NoError(err)
+	if suite.showSerialized {
+		fmt.Println(string(marshaled))
+	}
+	suite.Assert().Contains(string(marshaled), `favorite:
+    type: '[test]Stock'
+    data:
+        market: NASDAQ`)
+	suite.Assert().Contains(string(marshaled), `type: '[yaml]PlainBond'`)
+	suite.Assert().Contains(string(marshaled), `source:
+            type: '[test]Federal'
+            data: {}`)
+	suite.Assert().Contains(string(marshaled), "nickname: Fred")
+	suite.Assert().NotContains(string(marshaled), "spare:")
+	suite.Assert().NotContains(string(marshaled), "ignored:")
+
+	newPortfolio := new(PlainPortfolio)
+	suite.Require().NoError(Unmarshal(marshaled, newPortfolio))
+	if suite.showSerialized {
+		fmt.Println("---------------------------")
+		spew.Dump(newPortfolio)
+	}
+	portfolio.Ignored = ""
+	suite.Assert().Equal(portfolio, newPortfolio)
+	suite.Assert().Equal(test.StockWalmartName, newPortfolio.Lookup[test.StockWalmartSymbol].Name())
+}
+
+// TestCustom verifies that types with custom serialization
+// (in this case the Bond type from the wrapper tests) are left alone.
+func (suite *YamlMarshalTestSuite) TestCustom() {
+	holder := &PlainPortfolio{Favorite: MakeStateBond()}
+	marshaled, err := Marshal(holder)
+	suite.Require().NoError(err)
+	newHolder := new(PlainPortfolio)
+	suite.Require().NoError(Unmarshal(marshaled, newHolder))
+	suite.Assert().Equal(holder, newHolder)
+}
+
+// TestTagged verifies that hand-written tagged items are accepted.
+func (suite *YamlMarshalTestSuite) TestTagged() {
+	portfolio := new(PlainPortfolio)
+	suite.Require().NoError(Unmarshal([]byte(`
+favorite: ![test]Stock {market: NYSE, named: Walmart, symbol: WMT, shares: 58.91, price: 122.26}
+positions:
+  - ![yaml]PlainBond
+    bonddata: {named: Roads}
+    source: ![test]State {state: Confusion}
+`), portfolio))
+	suite.Assert().Equal(test.MakeWalmart(), portfolio.Favorite)
+	suite.Require().Len(portfolio.Positions, 1)
+	suite.Assert().Equal(&PlainBond{
+		BondData: test.BondData{Named: test.BondStateName},
+		Source:   test.StateBondSource(),
+	}, portfolio.Positions[0])
+}
+
+// TestTagged_Spellings verifies that tags are read as by Wrapper however they are written.
+func (suite *YamlMarshalTestSuite) TestTagged_Spellings() {
+	for _, tag := range []string{"![test]Stock", "!%5Btest%5DStock", "!<![test]Stock>", "!<[test]Stock>"} {
+		portfolio := new(PlainPortfolio)
+		suite.Require().NoError(Unmarshal([]byte(`
+favorite: `+tag+` {market: NYSE, named: Walmart, symbol: WMT, shares: 58.91, price: 122.26}
+positions: [`+tag+` {market: NYSE, named: Walmart, symbol: WMT, shares: 58.91, price: 122.26}]
+`), portfolio), tag)
+		suite.Assert().Equal(test.MakeWalmart(), portfolio.Favorite, tag)
+		suite.Require().Len(portfolio.Positions, 1, tag)
+		suite.Assert().Equal(test.MakeWalmart(), portfolio.Positions[0], tag)
+	}
+}
+
+func (suite *YamlMarshalTestSuite) TestNil() {
+	marshaled, err := Marshal(&PlainPortfolio{})
+	suite.Require().NoError(err)
+	suite.Assert().Equal("favorite: null\npositions: null\nlookup: null\nnickname: \"\"\n", string(marshaled))
+	newPortfolio := &PlainPortfolio{Favorite: test.MakeCostco()}
+	suite.Require().NoError(Unmarshal(marshaled, newPortfolio))
+	suite.Assert().Equal(&PlainPortfolio{}, newPortfolio)
+}
+
+// TestRegistry verifies that separate registries can be used concurrently.
+func (suite *YamlMarshalTestSuite) TestRegistry() {
+	var wg sync.WaitGroup
+	for _, alias := range []string{"one", "two", "three"} {
+		registry := reg.NewRegistry()
+		suite.Require().NoError(registry.AddAlias(alias, &test.Stock{}))
+		suite.Require().NoError(registry.Register(&test.Stock{}))
+		wg.Add(1)
+		go func(alias string, registry reg.Registry) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				portfolio := &PlainPortfolio{Favorite: test.MakeCostco()}
+				marshaled, err := MarshalWith(portfolio, WithRegistry(registry))
+				if !suite.Assert().NoError(err) {
+					return
+				}
+				suite.Assert().Contains(string(marshaled), "'["+alias+"]Stock'")
+				newPortfolio := new(PlainPortfolio)
+				suite.Assert().NoError(UnmarshalWith(marshaled, newPortfolio, WithRegistry(registry)))
+				suite.Assert().Equal(portfolio, newPortfolio)
+				// The go-type/reg singleton doesn't know about the alias.
+				suite.Assert().Error(Unmarshal(marshaled, new(PlainPortfolio)))
+			}
+		}(alias, registry)
+	}
+	wg.Wait()
+}
+
+// TestRegistry_Wrapper verifies that the Registry is passed to Wrapper fields
+// unless the Wrapper has its own Registry.
+func (suite *YamlMarshalTestSuite) TestRegistry_Wrapper() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("solo", &test.Stock{}))
+	suite.Require().NoError(registry.Register(&test.Stock{}))
+	holder := &struct {
+		Item *Wrapper[test.Investment]
+	}{Item: Wrap[test.Investment](test.MakeWalmart())}
+	marshaled, err := MarshalWith(holder, WithRegistry(registry))
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "type: '[solo]Stock'")
+	suite.Require().NoError(UnmarshalWith(marshaled, holder, WithRegistry(registry)))
+	suite.Assert().Equal(test.MakeWalmart(), holder.Item.Get())
+
+	holder.Item.SetRegistry(reg.Singleton())
+	marshaled, err = MarshalWith(holder, WithRegistry(registry))
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "[test]Stock")
+}
+
+// TestOmitEmpty verifies that the omitempty field tag option
+// applies to Wrapper and Pointer objects with nil items.
+func (suite *YamlMarshalTestSuite) TestOmitEmpty() {
+	holder := &Holder{
+		Item:    Wrap[test.Investment](nil),
+		Value:   *Wrap[test.Investment](nil),
+		Pet:     Point[*test.Pet](nil),
+		Another: Wrap[test.Investment](nil),
+	}
+	marshaled, err := Marshal(holder)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("another: null\n", string(marshaled))
+
+	holder.Item.Set(test.MakeCostco())
+	holder.Pet.Set(test.Knight)
+	marshaled, err = Marshal(holder)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "item:\n    type: '[test]Stock'")
+	suite.Assert().Contains(string(marshaled), "pet:\n    group: dog\n    key: Knight")
+	suite.Assert().NotContains(string(marshaled), "value:")
+
+	newHolder := new(Holder)
+	suite.Require().NoError(Unmarshal(marshaled, newHolder))
+	suite.Assert().Equal(test.MakeCostco(), newHolder.Item.Get())
+	suite.Assert().Equal(test.Knight, newHolder.Pet.Get())
+	suite.Assert().True(newHolder.Value.IsZero())
+	suite.Assert().True(newHolder.Another.IsZero())
+}
+
+func (suite *YamlMarshalTestSuite) TestErrors() {
+	_, err := Marshal(&PlainPortfolio{Favorite: &Unregistered{}})
+	suite.Assert().Error(err)
+	suite.Assert().ErrorIs(Unmarshal([]byte("{}"), PlainPortfolio{}), errNotPointer)
+	suite.Assert().ErrorIs(Unmarshal([]byte("favorite: {data: {}}"), new(PlainPortfolio)), wrapper.ErrEmptyTypeField)
+	suite.Assert().ErrorIs(Unmarshal([]byte("favorite: [17]"), new(PlainPortfolio)), wrapper.ErrNotEnvelope)
+	err = Unmarshal([]byte("favorite: {type: '[test]Federal', data: {}}"), new(PlainPortfolio))
+	suite.Assert().ErrorIs(err, wrapper.ErrTypeMismatch)
+	var decodeErr *wrapper.DecodeError
+	suite.Require().ErrorAs(err, &decodeErr)
+	suite.Assert().Equal(wrapper.PhaseTypeAssert, decodeErr.Phase)
+	suite.Assert().Equal("[test]Federal", decodeErr.TypeName)
+	suite.Assert().Equal(reflect.TypeOf((*test.Investment)(nil)).Elem(), decodeErr.Expected)
+}
+
+// TestUnexportedEmbedded verifies that a nil pointer to an unexported inlined struct
+// is reported as an error instead of causing a panic.
+func (suite *YamlMarshalTestSuite) TestUnexportedEmbedded() {
+	err := Unmarshal([]byte(`x: 1
+i: null
+`), &UnexportedOuter{})
+	suite.Assert().ErrorIs(err, errUnexportedInline)
+
+	// An allocated embedded struct is filled.
+	outer := &UnexportedOuter{unexportedInner: &unexportedInner{}}
+	suite.Require().NoError(Unmarshal([]byte(`x: 1
+i: null
+`), outer))
+	suite.Assert().Equal(1, outer.X)
+	suite.Assert().Nil(outer.I)
+}
+
+// TestWalkable verifies that walkable caches results for types without interface values,
+// including types nested within recursive types.
+func (suite *YamlMarshalTestSuite) TestWalkable() {
+	suite.Assert().False(needsWalk(reflect.TypeOf(walkList{})))
+	for _, t := range []reflect.Type{
+		reflect.TypeOf(walkList{}), reflect.TypeOf(walkLeaf{}), reflect.TypeOf([]walkLeaf{}),
+	} {
+		cached, found := walkCache.Load(t)
+		suite.Assert().True(found, t.String())
+		suite.Assert().Equal(false, cached, t.String())
+	}
+
+	// The result for a type within a recursive type depends on the rest of the recursive type.
+	suite.Assert().True(needsWalk(reflect.TypeOf(walkTree{})))
+	suite.Assert().True(needsWalk(reflect.TypeOf(walkBranch{})))
+}
+
+// TestAny verifies that plain data in empty interfaces is serialized as by gopkg.in/yaml.v3
+// and that only other types are serialized within the type/data envelope.
+func (suite *YamlMarshalTestSuite) TestAny() {
+	plain := map[string]interface{}{
+		"a": 1, "b": true, "c": "see", "d": nil, "e": 2.5,
+		"list": []interface{}{1, "two", map[string]interface{}{"three": 3}},
+	}
+	expected, err := yaml.Marshal(plain)
+	suite.Require().NoError(err)
+	marshaled, err := Marshal(plain)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(string(expected), string(marshaled))
+	var fromYAML, fromWalker map[string]interface{}
+	suite.Require().NoError(yaml.Unmarshal(marshaled, &fromYAML))
+	suite.Require().NoError(Unmarshal(marshaled, &fromWalker))
+	suite.Assert().Equal(fromYAML, fromWalker)
+
+	// Other types within plain data are wrapped.
+	holder := &AnyHolder{
+		Item:  test.MakeCostco(),
+		Extra: map[string]interface{}{"stock": test.MakeWalmart(), "count": 2},
+		List:  []interface{}{"one", test.MakeCostco(), []interface{}{test.MakeWalmart()}},
+	}
+	marshaled, err = Marshal(holder)
+	suite.Require().NoError(err)
+	if suite.showSerialized {
+		fmt.Println(string(marshaled))
+	}
+	suite.Assert().Contains(string(marshaled), "type: '[test]Stock'")
+	suite.Assert().Contains(string(marshaled), "count: 2")
+	newHolder := new(AnyHolder)
+	suite.Require().NoError(Unmarshal(marshaled, newHolder))
+	suite.Assert().Equal(holder, newHolder)
+
+	// Tagged items are accepted within plain data.
+	newHolder = new(AnyHolder)
+	suite.Require().NoError(Unmarshal([]byte("list: [![test]Stock {symbol: COST}]\n"), newHolder))
+	suite.Assert().Equal([]interface{}{&test.Stock{Symbol: "COST"}}, newHolder.List)
+
+	// Named types must still be registered.
+	_, err = Marshal(&AnyHolder{Item: celsius(20)})
+	suite.Assert().Error(err)
+
+	// A map which would be read as the envelope is rejected.
+	_, err = Marshal(&AnyHolder{Item: map[string]interface{}{"type": "x", "data": 1}})
+	suite.Assert().ErrorIs(err, errAmbiguousMap)
+	_, err = Marshal(&AnyHolder{Item: map[string]interface{}{"type": 1, "data": 1}})
+	suite.Assert().NoError(err)
+}
+
+// TestTagOptions verifies that field tag options not supported by gopkg.in/yaml.v3
+// are errors (gopkg.in/yaml.v3 panics) instead of being ignored.
+func (suite *YamlMarshalTestSuite) TestTagOptions() {
+	_, err := Marshal(&QuotedHolder{Favorite: test.MakeCostco(), ID: 5})
+	suite.Assert().ErrorIs(err, errTagOption)
+	suite.Assert().ErrorContains(err, "'string'")
+	err = Unmarshal([]byte("id: 5\n"), new(QuotedHolder))
+	suite.Assert().ErrorIs(err, errTagOption)
+}
+
+//////////////////////////////////////////////////////////////////////////
+
+// PlainPortfolio has interface fields but no custom serialization code.
+type PlainPortfolio struct {
+	Favorite  test.Investment
+	Positions []test.Investment
+	Lookup    map[string]test.Investment
+	Nickname  string
+	Spare     test.Investment `yaml:",omitempty"`
+	Ignored   string          `yaml:"-"`
+}
+
+func MakePlainPortfolio() *PlainPortfolio {
+	portfolio := &PlainPortfolio{
+		Positions: []test.Investment{
+			test.MakeCostco(), test.MakeWalmart(),
+			&PlainBond{BondData: test.StateBondData(), Source: test.StateBondSource()},
+			&PlainBond{BondData: test.TBillData(), Source: test.TBillSource()},
+		},
+		Lookup:   make(map[string]test.Investment),
+		Nickname: "Fred",
+		Ignored:  "ignored",
+	}
+	portfolio.Favorite = portfolio.Positions[0]
+	for _, position := range portfolio.Positions {
+		if stock, ok := position.(*test.Stock); ok {
+			portfolio.Lookup[stock.Symbol] = stock
+		}
+	}
+	return portfolio
+}
+
+//------------------------------------------------------------------------
+
+var _ test.Investment = &PlainBond{}
+
+// PlainBond has an interface field but no custom serialization code.
+type PlainBond struct {
+	test.BondData
+	Source test.Borrower
+}
+
+//------------------------------------------------------------------------
+
+// Holder has Wrapper and Pointer fields with the omitempty field tag option.
+type Holder struct {
+	Item    *Wrapper[test.Investment] `yaml:",omitempty"`
+	Value   Wrapper[test.Investment]  `yaml:",omitempty"`
+	Pet     *Pointer[*test.Pet]       `yaml:",omitempty"`
+	Another *Wrapper[test.Investment]
+}
+
+//------------------------------------------------------------------------
+
+var _ test.Investment = &Unregistered{}
+
+// Unregistered is not registered with go-type/reg.
+type Unregistered struct {
+	test.BondData
+}
+
+// AnyHolder has empty interface fields.
+type AnyHolder struct {
+	Item  interface{}
+	Extra map[string]interface{}
+	List  []interface{}
+}
+
+// celsius is not registered with go-type/reg.
+type celsius float64
+
+// QuotedHolder has a field with the string field tag option of encoding/json.
+type QuotedHolder struct {
+	Favorite test.Investment
+	ID       int64 `yaml:"id,string"`
+}
+
+type UnexportedOuter struct {
+	*unexportedInner `yaml:",inline"`
+	I                test.Investment
+}
+
+type unexportedInner struct {
+	X int
+}
+
+type walkList struct {
+	Next   *walkList
+	Leaves []walkLeaf
+}
+
+type walkLeaf struct {
+	Name string
+}
+
+type walkTree struct {
+	Branch *walkBranch
+	Item   interface{}
+}
+
+type walkBranch struct {
+	Tree *walkTree
+	Leaf walkLeaf
+}
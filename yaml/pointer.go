@@ -1,22 +1,46 @@
 package yaml
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/madkins23/go-serial/internal/targets"
 	"github.com/madkins23/go-serial/pointer"
-)
-
-const (
-	tgtGroup = "group"
-	tgtKey   = "key"
+	"github.com/madkins23/go-serial/wrapper"
 )
 
 // Pointer is used to specify an object that may be found in a cache or DB.
+//
+// A Pointer with a nil Target item is serialized as YAML null
+// and YAML null is deserialized as a nil Target item.
+//
+// Target items are stored in and acquired from a pointer.Cache.
+// The Cache specified via SetCache is used if present,
+// otherwise the Cache specified via WithCache when using MarshalWith or UnmarshalWith,
+// otherwise the default pointer.Cache.
+// The context specified via WithContext when using UnmarshalWith
+// is passed to any pointer.ContextFinder used to acquire the Target item.
+// When using UnmarshalWith and WithBatch the Target item is acquired after
+// the entire document has been decoded, see WithBatch.
+//
+// When using MarshalWith and WithEmbeddedTargets the first Pointer to each Target item
+// is serialized with the Target item embedded, see WithEmbeddedTargets.
+// WithAnchors additionally serializes later Pointer objects to the Target item
+// as YAML aliases of the first, see WithAnchors.
+//
+// When using UnmarshalWith and WithDangling a Pointer to a Target item that can't be found
+// may be left unresolved or set to a placeholder instead of failing, see WithDangling.
+//
+// A lazy Pointer (see SetLazy and WithLazy) only records the group and key
+// when deserialized and acquires the Target item when first requested via Get or Resolve.
 type Pointer[T pointer.Target] struct {
-	item T
+	item  T
+	cache *pointer.Cache
+	lazy  bool
+	ref   *pointer.Reference
 }
 
 func Point[T pointer.Target](target T) *Pointer[T] {
@@ -26,62 +50,270 @@ func Point[T pointer.Target](target T) *Pointer[T] {
 }
 
 // Get the Target item from the Pointer.
+// If the Pointer was deserialized lazily the Target item is acquired as by Resolve,
+// with the zero value returned if it can't be acquired.
 func (p *Pointer[T]) Get() T {
-	return p.item
+	item, _ := p.Resolve()
+	return item
 }
 
 // Set the Target item for the Pointer.
 func (p *Pointer[T]) Set(t T) {
 	p.item = t
+	p.ref = nil
+}
+
+// Resolve returns the Target item from the Pointer.
+// If the Pointer was deserialized lazily the Target item is acquired from
+// the pointer.Cache (and if necessary its Finder) the first time it is requested.
+// Failures are returned as errors and may be retried.
+func (p *Pointer[T]) Resolve() (T, error) {
+	return p.ResolveContext(context.Background())
+}
+
+// ResolveContext returns the Target item from the Pointer as does Resolve,
+// passing the specified context to any pointer.ContextFinder.
+func (p *Pointer[T]) ResolveContext(ctx context.Context) (T, error) {
+	if p.ref == nil {
+		return p.item, nil
+	}
+	var zero T
+	target, err := p.ref.Resolve(ctx)
+	if err != nil {
+		return zero, fmt.Errorf("get target: %w", err)
+	} else if item, ok := target.(T); !ok {
+		return zero, fmt.Errorf(fmtWrongTargetType, target)
+	} else {
+		return item, nil
+	}
+}
+
+// Resolved returns false if the Pointer was deserialized lazily
+// and its Target item has not yet been acquired.
+func (p *Pointer[T]) Resolved() bool {
+	return p.ref == nil || p.ref.Resolved()
+}
+
+// Lazy returns true if the Pointer is deserialized lazily.
+func (p *Pointer[T]) Lazy() bool {
+	return p.lazy
+}
+
+// SetLazy configures whether the Pointer is deserialized lazily,
+// recording the group and key of the Target item without acquiring it.
+// See also WithLazy.
+func (p *Pointer[T]) SetLazy(lazy bool) {
+	p.lazy = lazy
+}
+
+// IsZero returns true if the Pointer is nil or has a nil Target item
+// and no lazily deserialized reference.
+// This supports the omitempty field tag option.
+func (p *Pointer[T]) IsZero() bool {
+	return p == nil || (isNil(p.item) && p.ref == nil)
+}
+
+// Cache returns the pointer.Cache specific to the Pointer, if any.
+func (p *Pointer[T]) Cache() *pointer.Cache {
+	return p.cache
+}
+
+// SetCache specifies a pointer.Cache to be used by the Pointer for storing and
+// acquiring its Target item instead of the Cache from the options or the default Cache.
+// A nil Cache reverts to the default behavior.
+func (p *Pointer[T]) SetCache(cache *pointer.Cache) {
+	p.cache = cache
+}
+
+// cacheFor returns the pointer.Cache to be used for the specified options.
+func (p *Pointer[T]) cacheFor(opts *options) *pointer.Cache {
+	if p.cache != nil {
+		return p.cache
+	}
+	return opts.getCache()
 }
 
 // -----------------------------------------------------------------------
 
 func (p *Pointer[T]) MarshalYAML() (interface{}, error) {
+	return p.marshalWith(nil)
+}
+
+func (p *Pointer[T]) marshalWith(opts *options) (interface{}, error) {
+	if p.IsZero() {
+		return nullNode(), nil
+	}
+
 	var err error
-	var group = p.item.Group()
-	var key = p.item.Key()
-	var pack = map[string]string{
-		tgtGroup: group,
-		tgtKey:   key,
+	var pack pointerPack
+	if p.ref != nil {
+		// Serialize a lazy reference without acquiring the Target item.
+		pack.Group, pack.Key = p.ref.Group(), p.ref.Key()
+	} else {
+		pack.Group, pack.Key = p.item.Group(), p.item.Key()
+	}
+
+	if p.ref == nil {
+		if alias := opts.alias(pack.Group, pack.Key); alias != nil {
+			return alias, nil
+		}
 	}
 
-	if !pointer.HasTarget(group, key) {
-		if err = pointer.SetTarget(p.item, false); err == nil {
+	if cache := p.cacheFor(opts); p.ref == nil && !cache.HasTarget(pack.Group, pack.Key) {
+		if err = cache.Remember(p.item, false); err == nil {
 		} else if !errors.Is(err, pointer.ErrTargetAlreadyExists) {
 			return nil, fmt.Errorf("setting target in cache: %w", err)
 		}
 	}
 
-	fmt.Println("===========================")
-	fmt.Printf("Packed:\n%v\n", pack)
-	fmt.Println("===========================")
+	if p.ref == nil && opts.embed(pack.Group, pack.Key) {
+		if pack.TypeName, err = wrapper.NameFor(p.item, opts.getRegistry()); err != nil {
+			return nil, fmt.Errorf("get type name for %#v: %w", p.item, err)
+		}
+		if err = pack.RawForm.Encode(p.item); err != nil {
+			return nil, fmt.Errorf("marshal embedded target: %w", err)
+		}
+	}
 
-	return &pack, nil
+	node := new(yaml.Node)
+	if err = node.Encode(&pack); err != nil {
+		return nil, fmt.Errorf("marshal packed form: %w", err)
+	}
+	if p.ref == nil {
+		opts.anchor(pack.Group, pack.Key, node)
+	}
+	return node, nil
+}
+
+// pointerPack is the serialized form of a Pointer.
+// The type name and data are only present for an embedded Target item.
+type pointerPack struct {
+	Group    string    `yaml:"group"`
+	Key      string    `yaml:"key"`
+	TypeName string    `yaml:"type,omitempty"`
+	RawForm  yaml.Node `yaml:"data,omitempty"`
 }
 
 var (
-	errEmptyGroupField = errors.New("empty group field")
-	errEmptyKeyField   = errors.New("empty key field")
-	fmtWrongTargetType = "object '%v' not Target"
+	errEmptyGroupField = targets.ErrEmptyGroupField
+	errEmptyKeyField   = targets.ErrEmptyKeyField
 )
 
+const fmtWrongTargetType = targets.FmtWrongTargetType
+
 func (p *Pointer[T]) UnmarshalYAML(node *yaml.Node) error {
-	var pack = make(map[string]string)
-	if err := node.Decode(pack); err != nil {
-		return fmt.Errorf("unmarshal packed area: %p", err)
+	return p.unmarshalWith(nil, node)
+}
+
+func (p *Pointer[T]) unmarshalWith(opts *options, node *yaml.Node) error {
+	if isNull(node) {
+		p.clear()
+		return nil
+	} else if target := opts.aliased(node); target != nil {
+		// Decoded from an alias to a node already decoded by another Pointer.
+		return p.setTarget(target)
+	}
+
+	if err := p.unmarshalNode(opts, node); err != nil {
+		return err
+	}
+	if p.ref == nil && !isNil(p.item) {
+		opts.setAliased(node, p.item)
+	}
+	return nil
+}
+
+// unmarshalNode sets the Pointer from the packed form in the specified node.
+func (p *Pointer[T]) unmarshalNode(opts *options, node *yaml.Node) error {
+
+	var pack pointerPack
+	if err := node.Decode(&pack); err != nil {
+		return fmt.Errorf("unmarshal packed area: %w", err)
 	}
 
-	var ok bool
-	if group, found := pack[tgtGroup]; !found {
+	if pack.Group == "" {
 		return errEmptyGroupField
-	} else if key, found := pack[tgtKey]; !found {
+	} else if pack.Key == "" {
 		return errEmptyKeyField
-	} else if target, err := pointer.GetTarget(group, key, nil); err != nil {
+	} else if pack.TypeName != "" {
+		return p.unmarshalEmbedded(opts, pack)
+	}
+	return p.unmarshalReference(opts, pack.Group, pack.Key)
+}
+
+// unmarshalReference sets the Pointer to the Target item with the specified group and key.
+func (p *Pointer[T]) unmarshalReference(opts *options, group, key string) error {
+	if p.lazy || opts.getLazy() {
+		p.clear()
+		p.ref = pointer.NewReference(p.cacheFor(opts), group, key)
+		return nil
+	}
+
+	cache := p.cacheFor(opts)
+	if batch := opts.getBatch(); batch != nil {
+		p.clear()
+		if opts.getDangling() != pointer.DanglingFail {
+			// Remains if the Target item can't be found.
+			p.ref = pointer.NewReference(cache, group, key)
+		}
+		batch.Add(cache, group, key, p.setTarget)
+		return nil
+	} else if target, err := cache.GetTargetContext(opts.getContext(), group, key, nil); err == nil {
+		return p.setTarget(target)
+	} else if !errors.Is(err, pointer.ErrNoSuchTarget) {
+		return fmt.Errorf("get target: %w", err)
+	} else if target, err = opts.dangle(cache, group, key); err != nil {
 		return fmt.Errorf("get target: %w", err)
-	} else if p.item, ok = target.(T); !ok {
+	} else if target != nil {
+		return p.setTarget(target)
+	}
+	// Left unresolved with the group and key so that it is marshaled as it was read.
+	p.clear()
+	p.ref = pointer.NewReference(cache, group, key)
+	return nil
+}
+
+// unmarshalEmbedded creates the Target item embedded in the packed form
+// and adds it to the pointer.Cache.
+// If the Cache already has a Target with the same group and key that Target is used instead,
+// so that all Pointer objects with the same group and key have the same Target item.
+func (p *Pointer[T]) unmarshalEmbedded(opts *options, pack pointerPack) error {
+	item, err := wrapper.Make[T](pack.TypeName, opts.getRegistry())
+	if err != nil {
+		return err
+	} else if pack.RawForm.IsZero() {
+		return wrapper.NewDecodeError[T](wrapper.PhaseEnvelope, pack.TypeName, wrapper.ErrNotEnvelope)
+	} else if err = pack.RawForm.Decode(item); err != nil {
+		return wrapper.NewDecodeError[T](wrapper.PhaseDecode, pack.TypeName, err)
+	} else if item.Group() != pack.Group {
+		return pointer.ErrBadTargetGroup
+	} else if item.Key() != pack.Key {
+		return pointer.ErrBadTargetKey
+	}
+	cache := p.cacheFor(opts)
+	if err = cache.Remember(item, false); errors.Is(err, pointer.ErrTargetAlreadyExists) {
+		if existing, err := cache.GetTarget(pack.Group, pack.Key, nil); err == nil {
+			return p.setTarget(existing)
+		}
+	} else if err != nil {
+		return fmt.Errorf("set embedded target: %w", err)
+	}
+	p.Set(item)
+	return nil
+}
+
+// clear sets the Pointer to a nil Target item.
+func (p *Pointer[T]) clear() {
+	var zero T
+	p.Set(zero)
+}
+
+// setTarget sets the Target item for the Pointer if it is of the correct type.
+func (p *Pointer[T]) setTarget(target pointer.Target) error {
+	item, ok := target.(T)
+	if !ok {
 		return fmt.Errorf(fmtWrongTargetType, target)
-	} else {
-		return nil
 	}
+	p.Set(item)
+	return nil
 }
@@ -1,17 +1,25 @@
 package yaml
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/yaml.v3"
 
+	"github.com/madkins23/go-type/reg"
+
 	"github.com/madkins23/go-serial/pointer"
 	"github.com/madkins23/go-serial/test"
+	"github.com/madkins23/go-serial/wrapper"
 )
 
 type YamlPointerTestSuite struct {
@@ -42,6 +50,11 @@ func (suite *YamlPointerTestSuite) TestPointer() {
 	suite.Assert().Equal(test.Noah, ptr.Get())
 }
 
+// Kennel is registered for TestEmbeddedTargets_Map.
+type Kennel struct {
+	Dog *Pointer[*test.Pet]
+}
+
 type animals struct {
 	Cats []*Pointer[*test.Pet]
 	Dog  *Pointer[*test.Pet]
@@ -77,3 +90,497 @@ func (suite *YamlPointerTestSuite) TestMarshalCycle() {
 
 	suite.Require().Equal(start, finish)
 }
+
+func (suite *YamlPointerTestSuite) TestPointer_Nil() {
+	var nilPointer *Pointer[*test.Pet]
+	suite.Assert().True(nilPointer.IsZero())
+	marshaled, err := yaml.Marshal(nilPointer)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("null\n", string(marshaled))
+
+	start := &animals{Cats: []*Pointer[*test.Pet]{Point[*test.Pet](nil)}}
+	suite.Assert().True(start.Cats[0].IsZero())
+	marshaled, err = yaml.Marshal(start)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("cats:\n    - null\ndog: null\n", string(marshaled))
+
+	holder := &struct {
+		Pet Pointer[*test.Pet]
+	}{}
+	holder.Pet.Set(test.Knight)
+	suite.Require().NoError(holder.Pet.UnmarshalYAML(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}))
+	suite.Assert().Nil(holder.Pet.Get())
+	suite.Assert().True(holder.Pet.IsZero())
+}
+
+// TestCache verifies that Pointer objects use the Cache from WithCache or SetCache.
+func (suite *YamlPointerTestSuite) TestCache() {
+	cache := pointer.NewCache()
+	pet := &test.Pet{Name: "Rover", Type: "dog"}
+	start := &animals{Dog: Point[*test.Pet](pet)}
+	marshaled, err := MarshalWith(start, WithCache(cache))
+	suite.Require().NoError(err)
+	suite.Assert().True(cache.HasTarget(pet.Group(), pet.Key()))
+	suite.Assert().False(pointer.HasTarget(pet.Group(), pet.Key()))
+
+	finish := new(animals)
+	suite.Assert().ErrorIs(Unmarshal(marshaled, finish), pointer.ErrNoSuchTarget)
+	suite.Assert().ErrorIs(UnmarshalWith(marshaled, finish, WithCache(pointer.NewCache())), pointer.ErrNoSuchTarget)
+	suite.Require().NoError(UnmarshalWith(marshaled, finish, WithCache(cache)))
+	suite.Assert().Same(pet, finish.Dog.Get())
+
+	holder := &struct {
+		Pet Pointer[*test.Pet]
+	}{}
+	holder.Pet.SetCache(cache)
+	suite.Assert().Same(cache, holder.Pet.Cache())
+	marshaled, err = Marshal(&struct{ Pet *Pointer[*test.Pet] }{Point[*test.Pet](pet)})
+	suite.Require().NoError(err)
+	suite.Require().NoError(UnmarshalWith(marshaled, holder, WithCache(pointer.NewCache())))
+	suite.Assert().Same(pet, holder.Pet.Get())
+	pointer.ClearTargetCache()
+	suite.Require().NoError(test.CachePets())
+}
+
+// TestContext verifies that the context from WithContext is passed to a ContextFinder.
+func (suite *YamlPointerTestSuite) TestContext() {
+	type ctxKey struct{}
+	pet := &test.Pet{Name: "Rover", Type: "dog"}
+	marshaled, err := MarshalWith(&animals{Dog: Point[*test.Pet](pet)}, WithCache(pointer.NewCache()))
+	suite.Require().NoError(err)
+
+	cache := pointer.NewCache()
+	suite.Require().NoError(cache.SetContextFinder(pet.Group(), func(ctx context.Context, key string) (pointer.Target, error) {
+		if ctx.Value(ctxKey{}) == nil {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return pet, nil
+	}, false))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = UnmarshalWith(marshaled, new(animals), WithCache(cache), WithContext(ctx))
+	suite.Assert().ErrorIs(err, context.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = UnmarshalWith(marshaled, new(animals), WithCache(cache), WithContext(ctx))
+	suite.Assert().ErrorIs(err, context.DeadlineExceeded)
+	suite.Assert().False(cache.HasTarget(pet.Group(), pet.Key()))
+
+	finish := new(animals)
+	ctx = context.WithValue(context.Background(), ctxKey{}, true)
+	suite.Require().NoError(UnmarshalWith(marshaled, finish, WithCache(cache), WithContext(ctx)))
+	suite.Assert().Same(pet, finish.Dog.Get())
+}
+
+// TestBatch verifies that WithBatch acquires all Target items in one call per group.
+func (suite *YamlPointerTestSuite) TestBatch() {
+	type pets struct {
+		Cats  []*Pointer[*test.Pet]
+		Dog   Pointer[*test.Pet]
+		Named map[string]Pointer[*test.Pet]
+	}
+	start := &pets{
+		Cats:  makeAnimals().Cats,
+		Dog:   *Point[*test.Pet](test.Knight),
+		Named: map[string]Pointer[*test.Pet]{"best": *Point[*test.Pet](test.Noah)},
+	}
+	marshaled, err := MarshalWith(start, WithCache(pointer.NewCache()))
+	suite.Require().NoError(err)
+
+	var calls [][]string
+	cache := pointer.NewCache()
+	suite.Require().NoError(cache.SetBatchFinder(test.Lacey.Group(), func(keys []string) (map[string]pointer.Target, error) {
+		calls = append(calls, keys)
+		targets := make(map[string]pointer.Target)
+		for _, pet := range []*test.Pet{test.Lacey, test.Noah, test.Orca} {
+			targets[pet.Key()] = pet
+		}
+		return targets, nil
+	}, false))
+	suite.Require().NoError(cache.SetTarget(test.Knight, false))
+
+	finish := new(pets)
+	suite.Require().NoError(UnmarshalWith(marshaled, finish, WithCache(cache), WithBatch()))
+	suite.Assert().Equal(makeAnimals().Cats, finish.Cats)
+	suite.Assert().Same(test.Knight, finish.Dog.Get())
+	best := finish.Named["best"]
+	suite.Assert().Same(test.Noah, best.Get())
+	suite.Assert().Len(calls, 1)
+	suite.Assert().ElementsMatch([]string{test.Noah.Key(), test.Lacey.Key(), test.Orca.Key()}, calls[0])
+
+	err = UnmarshalWith(marshaled, new(pets), WithCache(pointer.NewCache()), WithBatch())
+	suite.Assert().ErrorIs(err, pointer.ErrNoSuchTarget)
+}
+
+// TestLazy verifies that lazy Pointer objects acquire Target items when first requested.
+func (suite *YamlPointerTestSuite) TestLazy() {
+	pet := &test.Pet{Name: "Rover", Type: "dog"}
+	marshaled, err := MarshalWith(&animals{Dog: Point[*test.Pet](pet)}, WithCache(pointer.NewCache()))
+	suite.Require().NoError(err)
+
+	cache := pointer.NewCache()
+	finish := new(animals)
+	suite.Require().NoError(UnmarshalWith(marshaled, finish, WithCache(cache), WithLazy()))
+	suite.Assert().False(finish.Dog.Resolved())
+	suite.Assert().False(finish.Dog.IsZero())
+	_, err = finish.Dog.Resolve()
+	suite.Assert().ErrorIs(err, pointer.ErrNoSuchTarget)
+	suite.Assert().Nil(finish.Dog.Get())
+
+	// A lazy Pointer is serialized without acquiring its Target item.
+	remarshaled, err := Marshal(finish)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(string(marshaled), string(remarshaled))
+
+	// The Target item may be loaded after unmarshaling.
+	var calls int
+	suite.Require().NoError(cache.SetFinder(pet.Group(), func(key string) (pointer.Target, error) {
+		calls++
+		return pet, nil
+	}, false))
+	suite.Assert().Equal(0, calls)
+	suite.Assert().Same(pet, finish.Dog.Get())
+	suite.Assert().Same(pet, finish.Dog.Get())
+	suite.Assert().True(finish.Dog.Resolved())
+	suite.Assert().Equal(1, calls)
+
+	// Set replaces a lazy reference.
+	finish.Dog.Set(test.Knight)
+	item, err := finish.Dog.ResolveContext(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Same(test.Knight, item)
+}
+
+// TestLazy_SetLazy verifies that an individual Pointer may be unmarshaled lazily.
+func (suite *YamlPointerTestSuite) TestLazy_SetLazy() {
+	holder := &struct {
+		Pet Pointer[*test.Pet]
+	}{}
+	holder.Pet.SetLazy(true)
+	suite.Assert().True(holder.Pet.Lazy())
+	suite.Require().NoError(yaml.Unmarshal([]byte("pet:\n  group: dog\n  key: Knight\n"), holder))
+	suite.Assert().False(holder.Pet.Resolved())
+	suite.Assert().Same(test.Knight, holder.Pet.Get())
+	suite.Require().NoError(yaml.Unmarshal([]byte("pet:\n  group: dog\n  key: Rover\n"), holder))
+	_, err := holder.Pet.Resolve()
+	suite.Assert().ErrorIs(err, pointer.ErrNoSuchTarget)
+}
+
+type kennel struct {
+	Owners []*owner
+	Pets   []*test.Pet
+	Extra  *Slice[pointer.Target]
+}
+
+type owner struct {
+	Name string
+	Dog  *Pointer[*test.Pet]
+	Cat  Pointer[*test.Pet]
+}
+
+// TestForwardReferences verifies that Pointer objects may precede their Target items.
+func (suite *YamlPointerTestSuite) TestForwardReferences() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("test", &test.Pet{}))
+	suite.Require().NoError(registry.Register(&test.Pet{}))
+	document := []byte(`
+owners:
+  - name: Alice
+    dog: {group: dog, key: Rex}
+    cat: {group: cat, key: Tom}
+pets:
+  - name: Rex
+    type: dog
+extra:
+  - type: '[test]Pet'
+    data: {name: Tom, type: cat}
+`)
+
+	cache := pointer.NewCache()
+	err := UnmarshalWith(document, new(kennel), WithCache(cache), WithRegistry(registry))
+	suite.Assert().ErrorIs(err, pointer.ErrNoSuchTarget)
+
+	finish := new(kennel)
+	suite.Require().NoError(UnmarshalWith(document, finish,
+		WithCache(cache), WithRegistry(registry), WithForwardReferences()))
+	suite.Require().Len(finish.Pets, 1)
+	suite.Require().Len(finish.Extra.Get(), 1)
+	suite.Assert().Same(finish.Pets[0], finish.Owners[0].Dog.Get())
+	suite.Assert().Same(finish.Extra.Get()[0], finish.Owners[0].Cat.Get())
+	suite.Assert().True(cache.HasTarget("dog", "Rex"))
+	suite.Assert().True(cache.HasTarget("cat", "Tom"))
+
+	// Target items in the document take precedence over those in the Cache.
+	suite.Require().NoError(UnmarshalWith(document, finish,
+		WithCache(cache), WithRegistry(registry), WithForwardReferences()))
+	suite.Assert().Same(finish.Pets[0], finish.Owners[0].Dog.Get())
+
+	err = UnmarshalWith([]byte(`
+owners:
+  - name: Bob
+    dog: {group: dog, key: Fido}
+`), new(kennel), WithCache(cache), WithForwardReferences())
+	suite.Assert().ErrorIs(err, pointer.ErrNoSuchTarget)
+	suite.Assert().ErrorContains(err, "dog/Fido")
+}
+
+// TestEmbeddedTargets verifies that Target items can be exported and imported with their Pointers.
+func (suite *YamlPointerTestSuite) TestEmbeddedTargets() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("test", &test.Pet{}))
+	suite.Require().NoError(registry.Register(&test.Pet{}))
+	start := &animals{
+		Cats: []*Pointer[*test.Pet]{
+			Point[*test.Pet](test.Noah),
+			Point[*test.Pet](test.Lacey),
+			Point[*test.Pet](test.Noah),
+		},
+		Dog: Point[*test.Pet](test.Knight),
+	}
+	marshaled, err := MarshalWith(start,
+		WithCache(pointer.NewCache()), WithRegistry(registry), WithEmbeddedTargets())
+	suite.Require().NoError(err)
+	if suite.showSerialized {
+		fmt.Println(string(marshaled))
+	}
+	suite.Assert().Equal(3, strings.Count(string(marshaled), "[test]Pet"))
+
+	cache := pointer.NewCache()
+	finish := new(animals)
+	suite.Require().NoError(UnmarshalWith(marshaled, finish, WithCache(cache), WithRegistry(registry)))
+	suite.Assert().Equal(start, finish)
+	suite.Assert().NotSame(test.Noah, finish.Cats[0].Get())
+	suite.Assert().Same(finish.Cats[0].Get(), finish.Cats[2].Get())
+	suite.Assert().True(cache.HasTarget("dog", "Knight"))
+
+	// Embedded Target items don't replace those already in the Cache.
+	knight := finish.Dog.Get()
+	suite.Require().NoError(UnmarshalWith(marshaled, finish, WithCache(cache), WithRegistry(registry)))
+	suite.Assert().Same(knight, finish.Dog.Get())
+
+	// Without the Registry the embedded Target items can't be created.
+	var decodeErr *wrapper.DecodeError
+	err = UnmarshalWith(marshaled, new(animals), WithCache(pointer.NewCache()))
+	suite.Assert().ErrorAs(err, &decodeErr)
+}
+
+// TestEmbeddedTargets_Map verifies that Map items are decoded in the order they are marshaled
+// so that embedded Target items precede the references to them.
+func (suite *YamlPointerTestSuite) TestEmbeddedTargets_Map() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("test", &test.Pet{}))
+	suite.Require().NoError(registry.AddAlias("yaml", &Kennel{}))
+	suite.Require().NoError(registry.Register(&test.Pet{}))
+	suite.Require().NoError(registry.Register(&Kennel{}))
+	snoopy := &test.Pet{Name: "Snoopy", Type: "dog"}
+	kennels := make(map[string]interface{})
+	for i := 0; i < 10; i++ {
+		kennels[strconv.Itoa(i)] = &Kennel{Dog: Point[*test.Pet](snoopy)}
+	}
+	marshaled, err := MarshalWith(map[string]interface{}{"kennels": kennels},
+		WithCache(pointer.NewCache()), WithRegistry(registry), WithEmbeddedTargets())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(1, strings.Count(string(marshaled), "[test]Pet"))
+
+	// Map items are decoded without the options so the default Cache and Registry are used.
+	defer reg.SetSingleton(reg.Singleton())
+	reg.SetSingleton(registry)
+	defer func() {
+		pointer.ClearTargetCache()
+		suite.Require().NoError(test.CachePets())
+	}()
+	type boarded struct {
+		Kennels *Map[string, *Kennel]
+	}
+	for i := 0; i < 20; i++ {
+		pointer.ClearTargetCache()
+		finish := new(boarded)
+		suite.Require().NoError(Unmarshal(marshaled, finish))
+		suite.Require().Len(finish.Kennels.Get(), 10)
+		suite.Assert().Equal(snoopy, finish.Kennels.Get()["0"].Dog.Get())
+		suite.Assert().Same(finish.Kennels.Get()["0"].Dog.Get(), finish.Kennels.Get()["9"].Dog.Get())
+	}
+}
+
+// TestAnchors verifies that shared Target items are serialized once with YAML anchors and aliases.
+func (suite *YamlPointerTestSuite) TestAnchors() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("test", &test.Pet{}))
+	suite.Require().NoError(registry.Register(&test.Pet{}))
+	start := &animals{
+		Cats: []*Pointer[*test.Pet]{
+			Point[*test.Pet](test.Noah),
+			Point[*test.Pet](test.Lacey),
+			Point[*test.Pet](test.Noah),
+			Point[*test.Pet](test.Noah),
+		},
+		Dog: Point[*test.Pet](test.Knight),
+	}
+	marshaled, err := MarshalWith(start,
+		WithCache(pointer.NewCache()), WithRegistry(registry), WithAnchors())
+	suite.Require().NoError(err)
+	if suite.showSerialized {
+		fmt.Println(string(marshaled))
+	}
+	suite.Assert().Equal(3, strings.Count(string(marshaled), "[test]Pet"))
+	suite.Assert().Equal(1, strings.Count(string(marshaled), "&cat-Noah"))
+	suite.Assert().Equal(2, strings.Count(string(marshaled), "*cat-Noah"))
+	// Anchors are only added to shared Target items.
+	suite.Assert().Equal(1, strings.Count(string(marshaled), "&"))
+
+	// Aliases get the Target item from the anchored node, not the Cache,
+	// which only has room for the most recent Target item.
+	cache := pointer.NewCache(pointer.WithMaxEntries(1))
+	finish := new(animals)
+	suite.Require().NoError(UnmarshalWith(marshaled, finish, WithCache(cache), WithRegistry(registry)))
+	suite.Assert().Equal(start, finish)
+	suite.Assert().NotSame(test.Noah, finish.Cats[0].Get())
+	suite.Assert().Same(finish.Cats[0].Get(), finish.Cats[2].Get())
+	suite.Assert().Same(finish.Cats[0].Get(), finish.Cats[3].Get())
+	suite.Assert().False(cache.HasTarget("cat", "Noah"))
+}
+
+func (suite *YamlPointerTestSuite) TestAnchors_Name() {
+	opts := newOptions([]Option{WithAnchors()})
+	suite.Assert().Equal("cat-Noah", opts.anchorName("cat", "Noah"))
+	suite.Assert().Equal("cat-Noah-2", opts.anchorName("cat", "Noah"))
+	suite.Assert().Equal("cat_s-Big_Noah__", opts.anchorName("cat's", "Big Noah{}"))
+}
+
+// TestDangling verifies the handling of Pointer objects to Target items that can't be found.
+func (suite *YamlPointerTestSuite) TestDangling() {
+	garfield := &test.Pet{Name: "Garfield", Type: "cat"}
+	odie := &test.Pet{Name: "Odie", Type: "dog"}
+	start := &animals{
+		Cats: []*Pointer[*test.Pet]{
+			Point[*test.Pet](test.Noah),
+			Point[*test.Pet](garfield),
+			Point[*test.Pet](test.Lacey),
+		},
+		Dog: Point[*test.Pet](odie),
+	}
+	marshaled, err := MarshalWith(start, WithCache(pointer.NewCache()))
+	suite.Require().NoError(err)
+
+	// The Cache doesn't have Garfield or Odie.
+	cache := pointer.NewCache()
+	suite.Require().NoError(cache.SetTarget(test.Noah, false))
+	suite.Require().NoError(cache.SetTarget(test.Lacey, false))
+	for _, batch := range []bool{false, true} {
+		opts := []Option{WithCache(cache)}
+		if batch {
+			opts = append(opts, WithBatch())
+		}
+		suite.Assert().ErrorIs(UnmarshalWith(marshaled, new(animals), opts...), pointer.ErrNoSuchTarget)
+
+		// Dangling Pointer objects are kept and marshaled as they were read.
+		finish := new(animals)
+		suite.Require().NoError(UnmarshalWith(marshaled, finish, append(opts, WithDangling(pointer.DanglingKeep))...))
+		suite.Assert().Same(test.Noah, finish.Cats[0].Get())
+		suite.Assert().Nil(finish.Cats[1].Get())
+		suite.Assert().False(finish.Cats[1].Resolved())
+		suite.Assert().Same(test.Lacey, finish.Cats[2].Get())
+		suite.Assert().Nil(finish.Dog.Get())
+		remarshaled, err := MarshalWith(finish, WithCache(cache))
+		suite.Require().NoError(err)
+		suite.Assert().Equal(string(marshaled), string(remarshaled))
+		suite.Assert().False(cache.HasTarget("cat", "Garfield"))
+
+		// Dangling Pointer objects are reported after the entire document is decoded.
+		finish = new(animals)
+		err = UnmarshalWith(marshaled, finish, append(opts, WithDangling(pointer.DanglingReport))...)
+		var dangling *pointer.DanglingError
+		suite.Require().ErrorAs(err, &dangling)
+		suite.Assert().ErrorIs(err, pointer.ErrNoSuchTarget)
+		suite.Assert().Equal([]pointer.DanglingReference{
+			{Group: "cat", Key: "Garfield"},
+			{Group: "dog", Key: "Odie"},
+		}, dangling.References)
+		suite.Assert().Same(test.Lacey, finish.Cats[2].Get())
+
+		// Placeholders are used for groups that have them.
+		opts = append(opts, WithDangling(pointer.DanglingPlaceholder))
+		suite.Assert().ErrorIs(UnmarshalWith(marshaled, new(animals), opts...), pointer.ErrNoSuchTarget)
+		suite.Require().NoError(cache.SetPlaceholder("dog", func(key string) pointer.Target {
+			return &test.Pet{Name: key, Type: "dog"}
+		}, true))
+		suite.Require().NoError(cache.SetPlaceholder("cat", func(key string) pointer.Target {
+			return &test.Pet{Name: key, Type: "cat"}
+		}, true))
+		finish = new(animals)
+		suite.Require().NoError(UnmarshalWith(marshaled, finish, opts...))
+		suite.Assert().Equal(start, finish)
+		suite.Assert().NotSame(garfield, finish.Cats[1].Get())
+		suite.Assert().False(cache.HasTarget("cat", "Garfield"))
+		cache.ClearFinders()
+	}
+}
+
+// TestConcurrent marshals and unmarshals pointers concurrently.
+// Run with the -race flag to verify the absence of data races.
+func (suite *YamlPointerTestSuite) TestConcurrent() {
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if (g+i)%10 == 0 {
+					pointer.ClearTargetCache()
+				}
+				start := makeAnimals()
+				marshaled, err := yaml.Marshal(start)
+				if !suite.Assert().NoError(err) {
+					return
+				}
+				finish := new(animals)
+				if err = yaml.Unmarshal(marshaled, finish); err != nil {
+					// The cache may have been cleared between marshal and unmarshal.
+					suite.Assert().ErrorIs(err, pointer.ErrNoSuchTarget)
+					continue
+				}
+				suite.Assert().Equal(start, finish)
+			}
+		}(g)
+	}
+	wg.Wait()
+	pointer.ClearTargetCache()
+	suite.Require().NoError(test.CachePets())
+}
+
+// TestStorer verifies that marshaling and unmarshaling Pointer objects never calls a Storer.
+func (suite *YamlPointerTestSuite) TestStorer() {
+	registry := reg.NewRegistry()
+	suite.Require().NoError(registry.AddAlias("test", &test.Pet{}))
+	suite.Require().NoError(registry.Register(&test.Pet{}))
+	var stored []pointer.Target
+	storer := func(target pointer.Target) error {
+		stored = append(stored, target)
+		return errors.New("dup key")
+	}
+	cache := pointer.NewCache(pointer.WithMaxEntries(2))
+	suite.Require().NoError(cache.SetStorer("cat", storer, false))
+	suite.Require().NoError(cache.SetStorer("dog", storer, false))
+
+	marshaled, err := MarshalWith(makeAnimals(), WithCache(cache))
+	suite.Require().NoError(err)
+	embedded, err := MarshalWith(makeAnimals(), WithCache(cache), WithRegistry(registry), WithEmbeddedTargets())
+	suite.Require().NoError(err)
+	for _, batch := range []bool{false, true} {
+		opts := []Option{WithCache(cache), WithRegistry(registry)}
+		if batch {
+			opts = append(opts, WithBatch())
+		}
+		cache.ClearTargets()
+		suite.Require().NoError(UnmarshalWith(embedded, new(animals), opts...))
+		suite.Require().NoError(UnmarshalWith(marshaled, new(animals), append(opts, WithDangling(pointer.DanglingKeep))...))
+	}
+	suite.Assert().Empty(stored)
+
+	// Explicit application writes are stored.
+	suite.Assert().ErrorContains(cache.SetTarget(test.Orca, true), "dup key")
+	suite.Assert().Equal([]pointer.Target{test.Orca}, stored)
+}
@@ -2,11 +2,12 @@ package yaml
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 
-	"github.com/madkins23/go-type/reg"
+	"github.com/madkins23/go-serial/wrapper"
 )
 
 // Wrap a Wrappable item in a wrapper that can handle serialization.
@@ -17,10 +18,37 @@ func Wrap[W any](item W) *Wrapper[W] {
 	return w
 }
 
+// WrapTagged wraps an item in a wrapper that serializes in tag mode.
+func WrapTagged[W any](item W) *Wrapper[W] {
+	w := Wrap(item)
+	w.SetTagged(true)
+	return w
+}
+
 // Wrapper is used to attach a type name to an item to be serialized.
 // This supports re-creating the correct type for filling an interface field.
+//
+// By default the item is serialized within a type/data envelope.
+// In tag mode the item is serialized directly with
+// a YAML node tag derived from the type name:
+//
+//	favorite: ![test]Stock
+//	  market: NYSE
+//	  ...
+//
+// Either form is accepted during unmarshaling,
+// the form that was read is retained for subsequent marshaling.
+//
+// A Wrapper with a nil item is serialized as YAML null
+// and YAML null is deserialized as a nil item.
+//
+// Type names are provided and items created by the Registry
+// returned by wrapper.Lookup for interface type T,
+// unless a specific Registry is set for the wrapper.
 type Wrapper[T any] struct {
-	item T
+	item     T
+	tagged   bool
+	registry wrapper.Registry
 }
 
 // Get the wrapped item.
@@ -33,46 +61,192 @@ func (w *Wrapper[T]) Set(t T) {
 	w.item = t
 }
 
+// IsZero returns true if the wrapper is nil or has a nil item.
+// This supports the omitempty field tag option.
+func (w *Wrapper[T]) IsZero() bool {
+	return w == nil || isNil(w.item)
+}
+
+// Tagged returns true if the wrapper serializes in tag mode.
+func (w *Wrapper[T]) Tagged() bool {
+	return w.tagged
+}
+
+// SetTagged configures whether the wrapper serializes in tag mode.
+func (w *Wrapper[T]) SetTagged(tagged bool) {
+	w.tagged = tagged
+}
+
+// Registry returns the Registry specific to the wrapper, if any.
+func (w *Wrapper[T]) Registry() wrapper.Registry {
+	return w.registry
+}
+
+// SetRegistry specifies a Registry for the wrapper.
+// This overrides any other Registry or wrapper.Factory that would otherwise be used.
+// In order to unmarshal using a specific Registry the wrapper must exist
+// before unmarshaling (e.g. a non-nil *Wrapper field) or
+// the Registry must be provided via UnmarshalWith.
+func (w *Wrapper[T]) SetRegistry(registry wrapper.Registry) {
+	w.registry = registry
+}
+
 // -----------------------------------------------------------------------
 
+const (
+	typeField = "type"
+	dataField = "data"
+)
+
+// packed is the envelope form of a wrapped item.
+// The data field is a YAML node so that the item is serialized
+// as part of the document instead of as a separately encoded string.
 type packed struct {
-	TypeName string `yaml:"type"`
-	RawForm  string `yaml:"data"`
+	TypeName string    `yaml:"type"`
+	RawForm  yaml.Node `yaml:"data"`
 }
 
 func (w *Wrapper[T]) MarshalYAML() (interface{}, error) {
+	return w.marshalWith(nil)
+}
+
+func (w *Wrapper[T]) marshalWith(opts *options) (interface{}, error) {
+	if w.IsZero() {
+		return nullNode(), nil
+	}
+
 	var err error
 	var pack packed
-	if pack.TypeName, err = reg.NameFor(w.item); err != nil {
+	if pack.TypeName, err = wrapper.NameFor(w.item, w.registry, opts.getRegistry()); err != nil {
 		return nil, fmt.Errorf("get type name for %#v: %w", w.item, err)
 	}
 
-	build := &strings.Builder{}
-	encoder := yaml.NewEncoder(build)
-	if err = encoder.Encode(w.item); err != nil {
+	if w.tagged {
+		node := new(yaml.Node)
+		if err = node.Encode(w.item); err != nil {
+			return nil, fmt.Errorf("encode tagged item: %w", err)
+		}
+		node.Tag = tagPrefix + pack.TypeName
+		return node, nil
+	}
+
+	if err = pack.RawForm.Encode(w.item); err != nil {
 		return nil, fmt.Errorf("marshal packed area: %w", err)
 	}
-	pack.RawForm = build.String()
 	return &pack, nil
 }
 
+// UnmarshalYAML unwraps the serialized item.
+// Errors are returned as *wrapper.DecodeError.
 func (w *Wrapper[T]) UnmarshalYAML(node *yaml.Node) error {
+	return w.unmarshalWith(nil, node)
+}
+
+func (w *Wrapper[T]) unmarshalWith(opts *options, node *yaml.Node) error {
+	if isNull(node) {
+		var zero T
+		w.item = zero
+		return nil
+	} else if typeName, tagged := tagTypeName(node); tagged {
+		if err := w.unmarshalTagged(opts, typeName, node); err != nil {
+			return err
+		}
+		w.tagged = true
+		return nil
+	}
+
 	var pack packed
 	if err := node.Decode(&pack); err != nil {
-		return fmt.Errorf("unmarshal packed area: %w", err)
+		return wrapper.NewDecodeError[T](wrapper.PhaseEnvelope, "", err)
+	} else if pack.TypeName == "" {
+		return wrapper.NewDecodeError[T](wrapper.PhaseEnvelope, "", wrapper.ErrEmptyTypeField)
+	}
+	item, err := wrapper.Make[T](pack.TypeName, w.registry, opts.getRegistry())
+	if err != nil {
+		return err
+	} else if err = decodeRawForm(&pack.RawForm, item); err != nil {
+		return wrapper.NewDecodeError[T](wrapper.PhaseDecode, pack.TypeName, err)
 	}
+	w.item = item
+	w.tagged = false
+	return nil
+}
 
-	var ok bool
-	if pack.TypeName == "" {
-		return fmt.Errorf("empty type field")
-	} else if temp, err := reg.Make(pack.TypeName); err != nil {
-		return fmt.Errorf("make instance of type %s: %w", pack.TypeName, err)
-	} else if err = yaml.NewDecoder(strings.NewReader(pack.RawForm)).Decode(temp); err != nil {
-		return fmt.Errorf("decode wrapper contents: %w", err)
-	} else if w.item, ok = temp.(T); !ok {
-		// TODO(mAdkins): How to get name of T? Do we care?
-		return fmt.Errorf("type %s not generic type", pack.TypeName)
-	} else {
-		return nil
+// decodeRawForm decodes the data node of the packed form into the specified item.
+//
+// Older versions of this package encoded the data as a string containing
+// a separate YAML document. A string node is treated as this legacy form
+// unless the item itself is some kind of string.
+func decodeRawForm(node *yaml.Node, item interface{}) error {
+	if isLegacyRawForm(node, item) {
+		return yaml.NewDecoder(strings.NewReader(node.Value)).Decode(item)
+	}
+	return node.Decode(item)
+}
+
+func isLegacyRawForm(node *yaml.Node, item interface{}) bool {
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+		return false
+	}
+	itemType := reflect.TypeOf(item)
+	for itemType != nil && itemType.Kind() == reflect.Pointer {
+		itemType = itemType.Elem()
+	}
+	return itemType != nil && itemType.Kind() != reflect.String
+}
+
+// -----------------------------------------------------------------------
+
+// tagPrefix marks a local YAML tag.
+// Standard YAML tags (e.g. !!map or !!str) use a doubled prefix.
+const tagPrefix = "!"
+
+// standardTagPrefix is the namespace of standard YAML tags in verbatim form.
+const standardTagPrefix = "tag:yaml.org,2002:"
+
+// tagTypeName returns the type name specified by the tag of the node, if any.
+// The type name is the same string that is the value of the type field in the
+// packed form (and in the JSON type/data envelope) however the tag is written:
+//
+//	![test]Stock          local tag as marshaled
+//	!%5Btest%5DStock      local tag with escaped flow indicators as required by the YAML spec
+//	!<![test]Stock>       local tag in verbatim form
+//	!<[test]Stock>        verbatim tag
+//
+// Standard YAML tags (e.g. !!map or !!str) never specify a type name.
+func tagTypeName(node *yaml.Node) (string, bool) {
+	switch {
+	case node.Tag == "" || node.Tag == tagPrefix:
+		return "", false
+	case strings.HasPrefix(node.Tag, tagPrefix+tagPrefix), strings.HasPrefix(node.Tag, standardTagPrefix):
+		return "", false
+	default:
+		// The parser has already unescaped the tag and removed the verbatim brackets.
+		return strings.TrimPrefix(node.Tag, tagPrefix), true
+	}
+}
+
+// untag returns a copy of the node without its tag,
+// otherwise the decoder may try to interpret it.
+func untag(node *yaml.Node) *yaml.Node {
+	untagged := *node
+	untagged.Tag = ""
+	return &untagged
+}
+
+func (w *Wrapper[T]) unmarshalTagged(opts *options, typeName string, node *yaml.Node) error {
+	item, err := wrapper.Make[T](typeName, w.registry, opts.getRegistry())
+	if err != nil {
+		return err
+	} else if err = untag(node).Decode(item); err != nil {
+		return wrapper.NewDecodeError[T](wrapper.PhaseDecode, typeName, err)
 	}
+
+	w.item = item
+	return nil
+}
+
+// heldItems returns the wrapped item for use by collectTargets.
+func (w *Wrapper[T]) heldItems() []interface{} {
+	return []interface{}{w.item}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -13,6 +14,7 @@ import (
 	"github.com/madkins23/go-type/reg"
 
 	"github.com/madkins23/go-serial/test"
+	"github.com/madkins23/go-serial/wrapper"
 )
 
 type YamlTestSuite struct {
@@ -31,6 +33,7 @@ func (suite *YamlTestSuite) SetupSuite() {
 	suite.Require().NoError(test.Register())
 	suite.Require().NoError(reg.Register(Bond{}))
 	suite.Require().NoError(reg.Register(WrappedBond{}))
+	suite.Require().NoError(reg.Register(Label("")))
 }
 
 func TestYamlSuite(t *testing.T) {
@@ -57,9 +60,208 @@ func (suite *YamlTestSuite) TestWrapper() {
 	packed, ok := packedVersion.(*packed)
 	suite.Require().True(ok)
 	suite.Assert().Equal("[test]Stock", packed.TypeName)
-	suite.Assert().Contains(packed.RawForm, "market: "+test.MarketNASDAQ)
-	suite.Assert().Contains(packed.RawForm, "named: "+test.StockCostcoName)
-	suite.Assert().Contains(packed.RawForm, "symbol: "+test.StockCostcoSymbol)
+	suite.Assert().Equal(yaml.MappingNode, packed.RawForm.Kind)
+	rawForm, err := yaml.Marshal(&packed.RawForm)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(rawForm), "market: "+test.MarketNASDAQ)
+	suite.Assert().Contains(string(rawForm), "named: "+test.StockCostcoName)
+	suite.Assert().Contains(string(rawForm), "symbol: "+test.StockCostcoSymbol)
+}
+
+// TestWrapper_Nil verifies the serialization of nil items.
+func (suite *YamlTestSuite) TestWrapper_Nil() {
+	var nilWrapper *Wrapper[test.Investment]
+	suite.Assert().True(nilWrapper.IsZero())
+	marshaled, err := yaml.Marshal(nilWrapper)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("null\n", string(marshaled))
+
+	var nilStock *test.Stock
+	for _, wrapped := range []*Wrapper[test.Investment]{
+		Wrap[test.Investment](nil), Wrap[test.Investment](nilStock), WrapTagged[test.Investment](nil),
+	} {
+		suite.Assert().True(wrapped.IsZero())
+		marshaled, err = yaml.Marshal(wrapped)
+		suite.Require().NoError(err)
+		suite.Assert().Equal("null\n", string(marshaled))
+	}
+
+	holder := &struct {
+		Item Wrapper[test.Investment]
+	}{}
+	holder.Item.Set(test.MakeCostco())
+	suite.Require().NoError(holder.Item.UnmarshalYAML(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}))
+	suite.Assert().Nil(holder.Item.Get())
+	suite.Assert().True(holder.Item.IsZero())
+}
+
+// TestWrapper_OmitEmpty verifies that the omitempty field tag option
+// applies to wrappers with nil items.
+func (suite *YamlTestSuite) TestWrapper_OmitEmpty() {
+	holder := &struct {
+		Item  *Wrapper[test.Investment] `yaml:",omitempty"`
+		Other *Wrapper[test.Investment] `yaml:",omitempty"`
+	}{Item: Wrap[test.Investment](nil), Other: Wrap[test.Investment](test.MakeCostco())}
+	marshaled, err := yaml.Marshal(holder)
+	suite.Require().NoError(err)
+	suite.Assert().NotContains(string(marshaled), "item:")
+	suite.Assert().Contains(string(marshaled), "other:")
+}
+
+func (suite *YamlTestSuite) TestWrapper_Structured() {
+	marshaled, err := yaml.Marshal(Wrap[test.Investment](test.MakeWalmart()))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`type: '[test]Stock'
+data:
+    market: NYSE
+    named: Walmart
+    symbol: WMT
+    shares: 58.91
+    price: 122.26
+`, string(marshaled))
+}
+
+func (suite *YamlTestSuite) TestWrapper_Legacy() {
+	// Older versions encoded the data as a string containing a YAML document.
+	unwrapped := new(Wrapper[test.Investment])
+	suite.Require().NoError(yaml.Unmarshal([]byte(`type: '[test]Stock'
+data: |
+    market: NYSE
+    named: Walmart
+    symbol: WMT
+    shares: 58.91
+    price: 122.26
+`), unwrapped))
+	suite.Assert().Equal(test.MakeWalmart(), unwrapped.Get())
+}
+
+func (suite *YamlTestSuite) TestWrapper_String() {
+	label := Label("hello")
+	wrapped := Wrap[any](&label)
+	marshaled, err := yaml.Marshal(wrapped)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "data: hello")
+	unwrapped := new(Wrapper[any])
+	suite.Require().NoError(yaml.Unmarshal(marshaled, unwrapped))
+	suite.Assert().Equal(wrapped, unwrapped)
+}
+
+func (suite *YamlTestSuite) TestTagged() {
+	wrapped := WrapTagged[test.Investment](test.MakeWalmart())
+	suite.Require().NotNil(wrapped)
+	suite.Assert().True(wrapped.Tagged())
+	marshaledBytes, err := yaml.Marshal(wrapped)
+	suite.Require().NoError(err)
+	marshaled := string(marshaledBytes)
+	suite.Assert().True(strings.HasPrefix(marshaled, "![test]Stock\n"))
+	suite.Assert().Contains(marshaled, "market: "+test.MarketNYSE)
+	suite.Assert().NotContains(marshaled, "data:")
+
+	unwrapped := new(Wrapper[test.Investment])
+	suite.Require().NoError(yaml.Unmarshal(marshaledBytes, unwrapped))
+	suite.Assert().True(unwrapped.Tagged())
+	suite.Assert().Equal(wrapped, unwrapped)
+	remarshaled, err := yaml.Marshal(unwrapped)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(marshaled, string(remarshaled))
+}
+
+func (suite *YamlTestSuite) TestTagged_HandWritten() {
+	var holder struct {
+		Favorite *Wrapper[test.Investment]
+		Borrower *Wrapper[test.Borrower]
+	}
+	suite.Require().NoError(yaml.Unmarshal([]byte(`
+favorite: ![test]Stock {market: NYSE, named: Walmart, symbol: WMT, shares: 58.91, price: 122.26}
+borrower: ![test]State
+  state: Confusion
+`), &holder))
+	suite.Require().NotNil(holder.Favorite)
+	suite.Assert().Equal(test.MakeWalmart(), holder.Favorite.Get())
+	suite.Require().NotNil(holder.Borrower)
+	suite.Assert().Equal(test.StateBondSource(), holder.Borrower.Get())
+}
+
+// TestTagged_Spellings verifies that every spelling of a tag with flow indicators
+// specifies the same type name as the type field of the packed form.
+func (suite *YamlTestSuite) TestTagged_Spellings() {
+	packed := new(Wrapper[test.Investment])
+	suite.Require().NoError(yaml.Unmarshal([]byte(`{type: '[test]Stock', data: {market: NYSE, named: Walmart}}`), packed))
+	for _, tag := range []string{"![test]Stock", "!%5Btest%5DStock", "!<![test]Stock>", "!<[test]Stock>"} {
+		single := new(Wrapper[test.Investment])
+		suite.Require().NoError(yaml.Unmarshal([]byte(tag+" {market: NYSE, named: Walmart}"), single), tag)
+		suite.Assert().True(single.Tagged(), tag)
+		suite.Assert().Equal(packed.Get(), single.Get(), tag)
+
+		// Flow indicators in the tag don't end a flow sequence.
+		var flow []*Wrapper[test.Investment]
+		suite.Require().NoError(yaml.Unmarshal([]byte("["+tag+" {market: NYSE, named: Walmart}]"), &flow), tag)
+		suite.Require().Len(flow, 1, tag)
+		suite.Assert().Equal(single, flow[0], tag)
+	}
+
+	// Standard tags don't specify a type name.
+	suite.Assert().Error(yaml.Unmarshal([]byte("!!map {market: NYSE}"), new(Wrapper[test.Investment])))
+	suite.Require().NoError(yaml.Unmarshal([]byte("!!map {type: '[test]Stock', data: {market: NYSE}}"), packed))
+	suite.Assert().False(packed.Tagged())
+}
+
+func (suite *YamlTestSuite) TestTagged_UnknownType() {
+	unwrapped := new(Wrapper[test.Investment])
+	suite.Assert().Error(yaml.Unmarshal([]byte("!Unknown {market: NYSE}"), unwrapped))
+}
+
+// TestDecodeError verifies the phase and type information in decode errors.
+func (suite *YamlTestSuite) TestDecodeError() {
+	for _, tc := range []struct {
+		yaml     string
+		phase    wrapper.Phase
+		typeName string
+		is       error
+	}{
+		{yaml: `[]`, phase: wrapper.PhaseEnvelope},
+		{yaml: `{data: {}}`, phase: wrapper.PhaseEnvelope, is: wrapper.ErrEmptyTypeField},
+		{yaml: `{type: '[test]Unknown', data: {}}`, phase: wrapper.PhaseInstantiate, typeName: "[test]Unknown"},
+		{yaml: `!Unknown {market: NYSE}`, phase: wrapper.PhaseInstantiate, typeName: "Unknown"},
+		{yaml: `{type: '[test]Stock', data: []}`, phase: wrapper.PhaseDecode, typeName: "[test]Stock"},
+		{yaml: `![test]Stock []`, phase: wrapper.PhaseDecode, typeName: "[test]Stock"},
+		{yaml: `{type: '[test]Federal', data: {}}`, phase: wrapper.PhaseTypeAssert, typeName: "[test]Federal",
+			is: wrapper.ErrTypeMismatch},
+	} {
+		err := yaml.Unmarshal([]byte(tc.yaml), new(Wrapper[test.Investment]))
+		var decodeErr *wrapper.DecodeError
+		if suite.Assert().ErrorAs(err, &decodeErr, tc.yaml) {
+			suite.Assert().Equal(tc.phase, decodeErr.Phase, tc.yaml)
+			suite.Assert().Equal(tc.typeName, decodeErr.TypeName, tc.yaml)
+			suite.Assert().Equal("test.Investment", decodeErr.Expected.String(), tc.yaml)
+		}
+		if tc.is != nil {
+			suite.Assert().ErrorIs(err, tc.is, tc.yaml)
+		}
+	}
+}
+
+// TestFactory verifies that the generated test.Borrower factory is used instead of go-type/reg.
+func (suite *YamlTestSuite) TestFactory() {
+	// The factory is not registered by importing the test package.
+	suite.Require().Nil(wrapper.GetFactory[test.Borrower]())
+	wrapper.SetFactory[test.Borrower](test.BorrowerFactory())
+	defer wrapper.SetFactory[test.Borrower](nil)
+	registry := reg.Singleton()
+	defer reg.SetSingleton(registry)
+	reg.SetSingleton(reg.NewRegistry())
+
+	wrapped := Wrap[test.Borrower](test.StateBondSource())
+	marshaled, err := yaml.Marshal(wrapped)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(marshaled), "[test]State")
+	unwrapped := new(Wrapper[test.Borrower])
+	suite.Require().NoError(yaml.Unmarshal(marshaled, unwrapped))
+	suite.Assert().Equal(wrapped, unwrapped)
+
+	// The empty registry doesn't know about other types.
+	_, err = yaml.Marshal(Wrap[test.Investment](test.MakeCostco()))
+	suite.Assert().Error(err)
 }
 
 //------------------------------------------------------------------------
@@ -107,6 +309,23 @@ func (suite *YamlTestSuite) TestWrapped() {
 		})
 }
 
+//------------------------------------------------------------------------
+
+// TestWrappedTagged tests a mixture of tagged and packed wrappers.
+func (suite *YamlTestSuite) TestWrappedTagged() {
+	portfolio := MakeWrappedPortfolio()
+	for _, position := range portfolio.Positions {
+		if _, ok := position.Get().(*test.Stock); ok {
+			position.SetTagged(true)
+		}
+	}
+	MarshalCycle[WrappedPortfolio](suite, portfolio,
+		func(suite *YamlTestSuite, marshaled string) {
+			suite.Assert().Contains(marshaled, "![test]Stock")
+			suite.Assert().Contains(marshaled, "type: '[test]State'")
+		}, nil)
+}
+
 //////////////////////////////////////////////////////////////////////////
 
 // MarshalCycle has common code for testing a marshal/unmarshal cycle.
@@ -319,3 +538,8 @@ func MakeWrappedTBill() *WrappedBond {
 		Source:   Wrap[test.Borrower](test.TBillSource()),
 	}
 }
+
+//////////////////////////////////////////////////////////////////////////
+
+// Label is a string type which is serialized as a YAML string.
+type Label string